@@ -3,6 +3,9 @@ package ast
 import (
 	"basic-arithmetic-parser/token"
 	"fmt"
+	"strconv"
+	"strings"
+	"time"
 )
 
 type NodeType int
@@ -11,22 +14,56 @@ const (
 	NUMBER_NODE NodeType = iota
 	BINARY_OP_NODE
 	UNARY_OP_NODE
+	IDENT_NODE
+	ASSIGN_NODE
+	CALL_NODE
+	APPLY_NODE
+	QUANTITY_NODE
+	CONVERT_NODE
+	DATE_NODE
+	DURATION_NODE
+	PERCENT_NODE
+	BOOL_NODE
+	CONDITIONAL_NODE
+	FUNCTION_DEF_NODE
+	LAMBDA_NODE
+	LIST_NODE
+	INDEX_NODE
+	SLICE_NODE
+	ERROR_NODE
 )
 
 type Node interface {
 	Type() NodeType
 	String() string
+	// Span returns the region of the input the node was parsed from
+	Span() token.Span
 }
 
 type NumberNode struct {
 	Value float64
+	// Literal is the number as written in the input, for evaluators that
+	// work at a higher precision than float64; it may be empty for nodes
+	// that were not parsed from text
+	Literal string
+	// Imaginary marks an imaginary literal such as 2.5i; Value and Literal
+	// hold its coefficient
+	Imaginary bool
+	Loc       token.Span
 }
 
 func (n *NumberNode) Type() NodeType {
 	return NUMBER_NODE
 }
 
+func (n *NumberNode) Span() token.Span {
+	return n.Loc
+}
+
 func (n *NumberNode) String() string {
+	if n.Imaginary {
+		return fmt.Sprintf("%gi", n.Value)
+	}
 	return fmt.Sprintf("%g", n.Value)
 }
 
@@ -34,12 +71,17 @@ type BinaryOpNode struct {
 	Left  Node
 	Op    token.Token
 	Right Node
+	Loc   token.Span
 }
 
 func (n *BinaryOpNode) Type() NodeType {
 	return BINARY_OP_NODE
 }
 
+func (n *BinaryOpNode) Span() token.Span {
+	return n.Loc
+}
+
 // Display as infix notation
 func (n *BinaryOpNode) String() string {
 	return fmt.Sprintf("(%s %s %s)", n.Left.String(), n.Op.Value, n.Right.String())
@@ -49,22 +91,438 @@ func (n *BinaryOpNode) String() string {
 type UnaryOpNode struct {
 	Op   token.Token
 	Expr Node
+	Loc  token.Span
 }
 
 func (n *UnaryOpNode) Type() NodeType {
 	return UNARY_OP_NODE
 }
 
+func (n *UnaryOpNode) Span() token.Span {
+	return n.Loc
+}
+
 func (n *UnaryOpNode) String() string {
 	return fmt.Sprintf("%s%s", n.Op.Value, n.Expr.String())
 }
 
+// Variable reference
+type IdentNode struct {
+	Name string
+	Loc  token.Span
+}
+
+func (n *IdentNode) Type() NodeType {
+	return IDENT_NODE
+}
+
+func (n *IdentNode) String() string {
+	return n.Name
+}
+
+func (n *IdentNode) Span() token.Span {
+	return n.Loc
+}
+
+// Assignment statement, e.g. x = 3 * 4
+type AssignNode struct {
+	Name  *IdentNode
+	Value Node
+	Loc   token.Span
+}
+
+func (n *AssignNode) Type() NodeType {
+	return ASSIGN_NODE
+}
+
+func (n *AssignNode) String() string {
+	return fmt.Sprintf("%s = %s", n.Name.String(), n.Value.String())
+}
+
+func (n *AssignNode) Span() token.Span {
+	return n.Loc
+}
+
+// Function call, e.g. max(1, x)
+type CallNode struct {
+	Name *IdentNode
+	Args []Node
+	Loc  token.Span
+}
+
+func (n *CallNode) Type() NodeType {
+	return CALL_NODE
+}
+
+func (n *CallNode) String() string {
+	args := make([]string, len(n.Args))
+	for i, arg := range n.Args {
+		args[i] = arg.String()
+	}
+	return fmt.Sprintf("%s(%s)", n.Name.String(), strings.Join(args, ", "))
+}
+
+func (n *CallNode) Span() token.Span {
+	return n.Loc
+}
+
+// Call of a function that is not named, e.g. adder(3)(4) or (x -> x)(5)
+type ApplyNode struct {
+	Func Node
+	Args []Node
+	Loc  token.Span
+}
+
+func (n *ApplyNode) Type() NodeType {
+	return APPLY_NODE
+}
+
+func (n *ApplyNode) String() string {
+	args := make([]string, len(n.Args))
+	for i, arg := range n.Args {
+		args[i] = arg.String()
+	}
+	return fmt.Sprintf("%s(%s)", n.Func.String(), strings.Join(args, ", "))
+}
+
+func (n *ApplyNode) Span() token.Span {
+	return n.Loc
+}
+
+// List literal, e.g. [1, 2, 3]. In interval mode a list of two elements is
+// read as an interval.
+type ListNode struct {
+	Elems []Node
+	Loc   token.Span
+}
+
+func (n *ListNode) Type() NodeType {
+	return LIST_NODE
+}
+
+func (n *ListNode) String() string {
+	elems := make([]string, len(n.Elems))
+	for i, elem := range n.Elems {
+		elems[i] = elem.String()
+	}
+	return fmt.Sprintf("[%s]", strings.Join(elems, ", "))
+}
+
+func (n *ListNode) Span() token.Span {
+	return n.Loc
+}
+
+// Index into a list, e.g. v[0]
+type IndexNode struct {
+	Expr  Node
+	Index Node
+	Loc   token.Span
+}
+
+func (n *IndexNode) Type() NodeType {
+	return INDEX_NODE
+}
+
+func (n *IndexNode) String() string {
+	return fmt.Sprintf("%s[%s]", n.Expr.String(), n.Index.String())
+}
+
+func (n *IndexNode) Span() token.Span {
+	return n.Loc
+}
+
+// Slice of a list, e.g. v[1:3]. Lo and Hi are nil when left out, as in v[:2].
+type SliceNode struct {
+	Expr Node
+	Lo   Node
+	Hi   Node
+	Loc  token.Span
+}
+
+func (n *SliceNode) Type() NodeType {
+	return SLICE_NODE
+}
+
+func (n *SliceNode) String() string {
+	lo, hi := "", ""
+	if n.Lo != nil {
+		lo = n.Lo.String()
+	}
+	if n.Hi != nil {
+		hi = n.Hi.String()
+	}
+	return fmt.Sprintf("%s[%s:%s]", n.Expr.String(), lo, hi)
+}
+
+func (n *SliceNode) Span() token.Span {
+	return n.Loc
+}
+
+// UnitFactor is one unit of a compound unit raised to a power, e.g. the s^-2
+// of m/s^2
+type UnitFactor struct {
+	Name  string
+	Power int
+	Loc   token.Span
+}
+
+// Unit is a product of unit factors as written after a number or a
+// conversion operator, e.g. m/s^2 or kg*m
+type Unit struct {
+	Factors []UnitFactor
+	Loc     token.Span
+}
+
+// String writes the factors with positive powers first, joined by *, then
+// the rest divided out: kg*m/s^2
+func (u *Unit) String() string {
+	var b strings.Builder
+	for _, f := range u.Factors {
+		if f.Power > 0 {
+			if b.Len() > 0 {
+				b.WriteString("*")
+			}
+			b.WriteString(unitPower(f.Name, f.Power))
+		}
+	}
+	if b.Len() == 0 {
+		b.WriteString("1")
+	}
+	for _, f := range u.Factors {
+		if f.Power < 0 {
+			b.WriteString("/" + unitPower(f.Name, -f.Power))
+		}
+	}
+	return b.String()
+}
+
+func unitPower(name string, power int) string {
+	if power == 1 {
+		return name
+	}
+	return fmt.Sprintf("%s^%d", name, power)
+}
+
+// Quantity with a unit, e.g. 9.81 m/s^2
+type QuantityNode struct {
+	Value *NumberNode
+	Unit  *Unit
+	Loc   token.Span
+}
+
+func (n *QuantityNode) Type() NodeType {
+	return QUANTITY_NODE
+}
+
+func (n *QuantityNode) String() string {
+	return fmt.Sprintf("%s %s", n.Value.String(), n.Unit.String())
+}
+
+func (n *QuantityNode) Span() token.Span {
+	return n.Loc
+}
+
+// Unit conversion, e.g. 60 mph to km/h
+type ConvertNode struct {
+	Expr Node
+	Unit *Unit
+	Loc  token.Span
+}
+
+func (n *ConvertNode) Type() NodeType {
+	return CONVERT_NODE
+}
+
+func (n *ConvertNode) String() string {
+	return fmt.Sprintf("(%s to %s)", n.Expr.String(), n.Unit.String())
+}
+
+func (n *ConvertNode) Span() token.Span {
+	return n.Loc
+}
+
+// Date or time literal, e.g. 2026-10-17T09:00Z
+type DateNode struct {
+	Value time.Time
+	// Literal is the date as written in the input
+	Literal string
+	Loc     token.Span
+}
+
+func (n *DateNode) Type() NodeType {
+	return DATE_NODE
+}
+
+func (n *DateNode) String() string {
+	if n.Literal == "" {
+		return n.Value.Format(time.RFC3339)
+	}
+	return n.Literal
+}
+
+func (n *DateNode) Span() token.Span {
+	return n.Loc
+}
+
+// Duration literal, e.g. 4h30m
+type DurationNode struct {
+	Value time.Duration
+	// Literal is the duration as written in the input
+	Literal string
+	Loc     token.Span
+}
+
+func (n *DurationNode) Type() NodeType {
+	return DURATION_NODE
+}
+
+func (n *DurationNode) String() string {
+	if n.Literal == "" {
+		return n.Value.String()
+	}
+	return n.Literal
+}
+
+func (n *DurationNode) Span() token.Span {
+	return n.Loc
+}
+
+// Percentage, e.g. 10%
+type PercentNode struct {
+	Expr Node
+	Loc  token.Span
+}
+
+func (n *PercentNode) Type() NodeType {
+	return PERCENT_NODE
+}
+
+func (n *PercentNode) String() string {
+	return fmt.Sprintf("%s%%", n.Expr.String())
+}
+
+func (n *PercentNode) Span() token.Span {
+	return n.Loc
+}
+
+// Boolean literal, true or false
+type BoolNode struct {
+	Value bool
+	Loc   token.Span
+}
+
+func (n *BoolNode) Type() NodeType {
+	return BOOL_NODE
+}
+
+func (n *BoolNode) String() string {
+	return strconv.FormatBool(n.Value)
+}
+
+func (n *BoolNode) Span() token.Span {
+	return n.Loc
+}
+
+// Conditional expression, e.g. x != 0 ? 1/x : 0 or if x != 0 then 1/x else 0
+type ConditionalNode struct {
+	Cond Node
+	Then Node
+	Else Node
+	Loc  token.Span
+}
+
+func (n *ConditionalNode) Type() NodeType {
+	return CONDITIONAL_NODE
+}
+
+func (n *ConditionalNode) String() string {
+	return fmt.Sprintf("(%s ? %s : %s)", n.Cond.String(), n.Then.String(), n.Else.String())
+}
+
+func (n *ConditionalNode) Span() token.Span {
+	return n.Loc
+}
+
+// paramList formats the names of parameters as a comma-separated list
+func paramList(params []*IdentNode) string {
+	names := make([]string, len(params))
+	for i, param := range params {
+		names[i] = param.Name
+	}
+	return strings.Join(names, ", ")
+}
+
+// Function definition, e.g. f(x, y) = x^2 + y
+type FunctionDefNode struct {
+	Name   *IdentNode
+	Params []*IdentNode
+	Body   Node
+	Loc    token.Span
+}
+
+func (n *FunctionDefNode) Type() NodeType {
+	return FUNCTION_DEF_NODE
+}
+
+// Signature returns the name and parameters, e.g. f(x, y)
+func (n *FunctionDefNode) Signature() string {
+	return fmt.Sprintf("%s(%s)", n.Name.Name, paramList(n.Params))
+}
+
+func (n *FunctionDefNode) String() string {
+	return fmt.Sprintf("%s = %s", n.Signature(), n.Body.String())
+}
+
+func (n *FunctionDefNode) Span() token.Span {
+	return n.Loc
+}
+
+// Anonymous function, e.g. x -> x * 2 or (a, b) -> a + b
+type LambdaNode struct {
+	Params []*IdentNode
+	Body   Node
+	Loc    token.Span
+}
+
+func (n *LambdaNode) Type() NodeType {
+	return LAMBDA_NODE
+}
+
+func (n *LambdaNode) String() string {
+	if len(n.Params) == 1 {
+		return fmt.Sprintf("(%s -> %s)", n.Params[0].Name, n.Body.String())
+	}
+	return fmt.Sprintf("((%s) -> %s)", paramList(n.Params), n.Body.String())
+}
+
+func (n *LambdaNode) Span() token.Span {
+	return n.Loc
+}
+
+// ErrorNode stands in for a part of the input that could not be parsed, so
+// the parser can still return a partial AST alongside its errors
+type ErrorNode struct {
+	Loc token.Span
+}
+
+func (n *ErrorNode) Type() NodeType {
+	return ERROR_NODE
+}
+
+func (n *ErrorNode) String() string {
+	return "<error>"
+}
+
+func (n *ErrorNode) Span() token.Span {
+	return n.Loc
+}
+
 // Generate a visual representation of the AST with indentation
 // TODO have this return an error when appropriate instead of 'Unknown node type'
 func PrettyPrintAST(node Node, indent string) string {
 	switch n := node.(type) {
 	case *NumberNode:
-		return fmt.Sprintf("%sNumber(%g)\n", indent, n.Value)
+		return fmt.Sprintf("%sNumber(%s)\n", indent, n.String())
 	case *BinaryOpNode:
 		result := fmt.Sprintf("%sBinaryOp(%s)\n", indent, n.Op.Value)
 		result += fmt.Sprintf("%s  Left:\n", indent)
@@ -77,6 +535,95 @@ func PrettyPrintAST(node Node, indent string) string {
 		result += fmt.Sprintf("%s  Expr:\n", indent)
 		result += PrettyPrintAST(n.Expr, indent+"    ")
 		return result
+	case *IdentNode:
+		return fmt.Sprintf("%sIdent(%s)\n", indent, n.Name)
+	case *AssignNode:
+		result := fmt.Sprintf("%sAssign(%s)\n", indent, n.Name.Name)
+		result += fmt.Sprintf("%s  Value:\n", indent)
+		result += PrettyPrintAST(n.Value, indent+"    ")
+		return result
+	case *CallNode:
+		result := fmt.Sprintf("%sCall(%s)\n", indent, n.Name.Name)
+		for i, arg := range n.Args {
+			result += fmt.Sprintf("%s  Arg %d:\n", indent, i)
+			result += PrettyPrintAST(arg, indent+"    ")
+		}
+		return result
+	case *ApplyNode:
+		result := fmt.Sprintf("%sApply\n", indent)
+		result += fmt.Sprintf("%s  Func:\n", indent)
+		result += PrettyPrintAST(n.Func, indent+"    ")
+		for i, arg := range n.Args {
+			result += fmt.Sprintf("%s  Arg %d:\n", indent, i)
+			result += PrettyPrintAST(arg, indent+"    ")
+		}
+		return result
+	case *ListNode:
+		result := fmt.Sprintf("%sList\n", indent)
+		for i, elem := range n.Elems {
+			result += fmt.Sprintf("%s  Elem %d:\n", indent, i)
+			result += PrettyPrintAST(elem, indent+"    ")
+		}
+		return result
+	case *IndexNode:
+		result := fmt.Sprintf("%sIndex\n", indent)
+		result += fmt.Sprintf("%s  Expr:\n", indent)
+		result += PrettyPrintAST(n.Expr, indent+"    ")
+		result += fmt.Sprintf("%s  Index:\n", indent)
+		result += PrettyPrintAST(n.Index, indent+"    ")
+		return result
+	case *SliceNode:
+		result := fmt.Sprintf("%sSlice\n", indent)
+		result += fmt.Sprintf("%s  Expr:\n", indent)
+		result += PrettyPrintAST(n.Expr, indent+"    ")
+		if n.Lo != nil {
+			result += fmt.Sprintf("%s  Lo:\n", indent)
+			result += PrettyPrintAST(n.Lo, indent+"    ")
+		}
+		if n.Hi != nil {
+			result += fmt.Sprintf("%s  Hi:\n", indent)
+			result += PrettyPrintAST(n.Hi, indent+"    ")
+		}
+		return result
+	case *QuantityNode:
+		return fmt.Sprintf("%sQuantity(%s)\n", indent, n.String())
+	case *ConvertNode:
+		result := fmt.Sprintf("%sConvert(%s)\n", indent, n.Unit.String())
+		result += fmt.Sprintf("%s  Expr:\n", indent)
+		result += PrettyPrintAST(n.Expr, indent+"    ")
+		return result
+	case *DateNode:
+		return fmt.Sprintf("%sDate(%s)\n", indent, n.String())
+	case *DurationNode:
+		return fmt.Sprintf("%sDuration(%s)\n", indent, n.String())
+	case *PercentNode:
+		result := fmt.Sprintf("%sPercent\n", indent)
+		result += fmt.Sprintf("%s  Expr:\n", indent)
+		result += PrettyPrintAST(n.Expr, indent+"    ")
+		return result
+	case *BoolNode:
+		return fmt.Sprintf("%sBool(%s)\n", indent, n.String())
+	case *ConditionalNode:
+		result := fmt.Sprintf("%sConditional\n", indent)
+		result += fmt.Sprintf("%s  Cond:\n", indent)
+		result += PrettyPrintAST(n.Cond, indent+"    ")
+		result += fmt.Sprintf("%s  Then:\n", indent)
+		result += PrettyPrintAST(n.Then, indent+"    ")
+		result += fmt.Sprintf("%s  Else:\n", indent)
+		result += PrettyPrintAST(n.Else, indent+"    ")
+		return result
+	case *FunctionDefNode:
+		result := fmt.Sprintf("%sFunctionDef(%s)\n", indent, n.Signature())
+		result += fmt.Sprintf("%s  Body:\n", indent)
+		result += PrettyPrintAST(n.Body, indent+"    ")
+		return result
+	case *LambdaNode:
+		result := fmt.Sprintf("%sLambda(%s)\n", indent, paramList(n.Params))
+		result += fmt.Sprintf("%s  Body:\n", indent)
+		result += PrettyPrintAST(n.Body, indent+"    ")
+		return result
+	case *ErrorNode:
+		return fmt.Sprintf("%sError\n", indent)
 	default:
 		return fmt.Sprintf("%sUnknown node type\n", indent)
 	}
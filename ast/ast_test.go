@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"strings"
 	"testing"
+	"time"
 )
 
 func TestNodeType(t *testing.T) {
@@ -35,6 +36,14 @@ func TestNodeType(t *testing.T) {
 	if unOpNode.String() != "-5" {
 		t.Errorf("UnaryOpNode.String() failed. Expected %q, got %q", "-5", unOpNode.String())
 	}
+
+	errNode := &ErrorNode{}
+	if errNode.Type() != ERROR_NODE {
+		t.Errorf("ErrorNode.Type() failed. Expected %v, got %v", ERROR_NODE, errNode.Type())
+	}
+	if errNode.String() != "<error>" {
+		t.Errorf("ErrorNode.String() failed. Expected %q, got %q", "<error>", errNode.String())
+	}
 }
 
 func TestNodeString(t *testing.T) {
@@ -50,6 +59,10 @@ func TestNodeString(t *testing.T) {
 			&NumberNode{Value: 10}, // Test integer formatting
 			"10",
 		},
+		{
+			&NumberNode{Value: 2.5, Imaginary: true},
+			"2.5i",
+		},
 		{
 			&BinaryOpNode{
 				Left:  &NumberNode{Value: 1},
@@ -88,6 +101,169 @@ func TestNodeString(t *testing.T) {
 			},
 			"+(1 / 2)",
 		},
+		{
+			&BinaryOpNode{
+				Left: &NumberNode{Value: 2},
+				Op:   token.Token{Type: token.POWER, Value: "^"},
+				Right: &BinaryOpNode{
+					Left:  &NumberNode{Value: 3},
+					Op:    token.Token{Type: token.POWER, Value: "^"},
+					Right: &NumberNode{Value: 2},
+				},
+			},
+			"(2 ^ (3 ^ 2))",
+		},
+		{
+			&AssignNode{
+				Name: &IdentNode{Name: "x"},
+				Value: &BinaryOpNode{
+					Left:  &IdentNode{Name: "y"},
+					Op:    token.Token{Type: token.MULTIPLY, Value: "*"},
+					Right: &NumberNode{Value: 4},
+				},
+			},
+			"x = (y * 4)",
+		},
+		{
+			&CallNode{
+				Name: &IdentNode{Name: "max"},
+				Args: []Node{
+					&NumberNode{Value: 1},
+					&BinaryOpNode{
+						Left:  &IdentNode{Name: "x"},
+						Op:    token.Token{Type: token.DIVIDE, Value: "/"},
+						Right: &NumberNode{Value: 2},
+					},
+				},
+			},
+			"max(1, (x / 2))",
+		},
+		{
+			&CallNode{Name: &IdentNode{Name: "f"}},
+			"f()",
+		},
+		{
+			&ApplyNode{
+				Func: &CallNode{Name: &IdentNode{Name: "adder"}, Args: []Node{&NumberNode{Value: 3}}},
+				Args: []Node{&NumberNode{Value: 4}},
+			},
+			"adder(3)(4)",
+		},
+		{
+			&QuantityNode{
+				Value: &NumberNode{Value: 9.81},
+				Unit:  &Unit{Factors: []UnitFactor{{Name: "m", Power: 1}, {Name: "s", Power: -2}}},
+			},
+			"9.81 m/s^2",
+		},
+		{
+			&ConvertNode{
+				Expr: &IdentNode{Name: "x"},
+				Unit: &Unit{Factors: []UnitFactor{{Name: "kg", Power: 1}, {Name: "m", Power: 2}, {Name: "s", Power: -2}}},
+			},
+			"(x to kg*m^2/s^2)",
+		},
+		{
+			&ConvertNode{
+				Expr: &IdentNode{Name: "f"},
+				Unit: &Unit{Factors: []UnitFactor{{Name: "s", Power: -1}}},
+			},
+			"(f to 1/s)",
+		},
+		{
+			&DateNode{Value: time.Date(2026, 10, 17, 9, 0, 0, 0, time.UTC), Literal: "2026-10-17T09:00Z"},
+			"2026-10-17T09:00Z",
+		},
+		{
+			&DateNode{Value: time.Date(2026, 10, 17, 9, 0, 0, 0, time.UTC)},
+			"2026-10-17T09:00:00Z",
+		},
+		{
+			&DurationNode{Value: 4*time.Hour + 30*time.Minute, Literal: "4h30m"},
+			"4h30m",
+		},
+		{
+			&DurationNode{Value: 90 * time.Minute},
+			"1h30m0s",
+		},
+		{
+			&BinaryOpNode{
+				Left:  &PercentNode{Expr: &NumberNode{Value: 15}},
+				Op:    token.Token{Type: token.OF, Value: "of"},
+				Right: &NumberNode{Value: 80},
+			},
+			"(15% of 80)",
+		},
+		{
+			&BinaryOpNode{
+				Left: &BinaryOpNode{
+					Left:  &IdentNode{Name: "x"},
+					Op:    token.Token{Type: token.LT_EQ, Value: "<="},
+					Right: &NumberNode{Value: 3},
+				},
+				Op: token.Token{Type: token.OR, Value: "||"},
+				Right: &UnaryOpNode{
+					Op:   token.Token{Type: token.NOT, Value: "!"},
+					Expr: &BoolNode{Value: true},
+				},
+			},
+			"((x <= 3) || !true)",
+		},
+		{
+			&ConditionalNode{
+				Cond: &BoolNode{Value: true},
+				Then: &NumberNode{Value: 1},
+				Else: &UnaryOpNode{Op: token.Token{Type: token.MINUS, Value: "-"}, Expr: &NumberNode{Value: 1}},
+			},
+			"(true ? 1 : -1)",
+		},
+		{
+			&FunctionDefNode{
+				Name:   &IdentNode{Name: "f"},
+				Params: []*IdentNode{{Name: "x"}, {Name: "y"}},
+				Body: &BinaryOpNode{
+					Left:  &IdentNode{Name: "x"},
+					Op:    token.Token{Type: token.PLUS, Value: "+"},
+					Right: &IdentNode{Name: "y"},
+				},
+			},
+			"f(x, y) = (x + y)",
+		},
+		{
+			&LambdaNode{
+				Params: []*IdentNode{{Name: "x"}},
+				Body: &BinaryOpNode{
+					Left:  &IdentNode{Name: "x"},
+					Op:    token.Token{Type: token.MULTIPLY, Value: "*"},
+					Right: &NumberNode{Value: 2},
+				},
+			},
+			"(x -> (x * 2))",
+		},
+		{
+			&LambdaNode{Params: []*IdentNode{{Name: "a"}, {Name: "b"}}, Body: &IdentNode{Name: "a"}},
+			"((a, b) -> a)",
+		},
+		{
+			&LambdaNode{Body: &NumberNode{Value: 42}},
+			"(() -> 42)",
+		},
+		{
+			&ListNode{Elems: []Node{&NumberNode{Value: 1}, &IdentNode{Name: "x"}, &ListNode{}}},
+			"[1, x, []]",
+		},
+		{
+			&IndexNode{Expr: &IdentNode{Name: "v"}, Index: &NumberNode{Value: 0}},
+			"v[0]",
+		},
+		{
+			&SliceNode{Expr: &IdentNode{Name: "v"}, Lo: &NumberNode{Value: 1}, Hi: &NumberNode{Value: 3}},
+			"v[1:3]",
+		},
+		{
+			&SliceNode{Expr: &IdentNode{Name: "v"}, Hi: &NumberNode{Value: -1}},
+			"v[:-1]",
+		},
 	}
 
 	for i, tt := range tests {
@@ -139,6 +315,223 @@ BinaryOp(*)
 		t.Errorf("PrettyPrintAST mismatch.\nExpected:\n%s\nGot:\n%s", normalize(expectedOutput), normalize(actualOutput))
 	}
 
+	// Test with a partial AST containing a syntax error placeholder
+	partial := &BinaryOpNode{
+		Left:  &NumberNode{Value: 1},
+		Op:    token.Token{Type: token.PLUS, Value: "+"},
+		Right: &ErrorNode{},
+	}
+	expectedPartialOutput := `
+BinaryOp(+)
+  Left:
+    Number(1)
+  Right:
+    Error
+`
+	actualPartialOutput := PrettyPrintAST(partial, "")
+	if normalize(actualPartialOutput) != normalize(expectedPartialOutput) {
+		t.Errorf("PrettyPrintAST mismatch.\nExpected:\n%s\nGot:\n%s", normalize(expectedPartialOutput), normalize(actualPartialOutput))
+	}
+
+	// Test with an assignment
+	assign := &AssignNode{
+		Name: &IdentNode{Name: "x"},
+		Value: &UnaryOpNode{
+			Op:   token.Token{Type: token.MINUS, Value: "-"},
+			Expr: &IdentNode{Name: "y"},
+		},
+	}
+	expectedAssignOutput := `
+Assign(x)
+  Value:
+    UnaryOp(-)
+      Expr:
+        Ident(y)
+`
+	actualAssignOutput := PrettyPrintAST(assign, "")
+	if normalize(actualAssignOutput) != normalize(expectedAssignOutput) {
+		t.Errorf("PrettyPrintAST mismatch.\nExpected:\n%s\nGot:\n%s", normalize(expectedAssignOutput), normalize(actualAssignOutput))
+	}
+
+	// Test with a function call
+	call := &CallNode{
+		Name: &IdentNode{Name: "atan2"},
+		Args: []Node{&NumberNode{Value: 1}, &IdentNode{Name: "x"}},
+	}
+	expectedCallOutput := `
+Call(atan2)
+  Arg 0:
+    Number(1)
+  Arg 1:
+    Ident(x)
+`
+	actualCallOutput := PrettyPrintAST(call, "")
+	if normalize(actualCallOutput) != normalize(expectedCallOutput) {
+		t.Errorf("PrettyPrintAST mismatch.\nExpected:\n%s\nGot:\n%s", normalize(expectedCallOutput), normalize(actualCallOutput))
+	}
+
+	// Test with a unit conversion
+	convert := &ConvertNode{
+		Expr: &QuantityNode{
+			Value: &NumberNode{Value: 60},
+			Unit:  &Unit{Factors: []UnitFactor{{Name: "mph", Power: 1}}},
+		},
+		Unit: &Unit{Factors: []UnitFactor{{Name: "km", Power: 1}, {Name: "h", Power: -1}}},
+	}
+	expectedConvertOutput := `
+Convert(km/h)
+  Expr:
+    Quantity(60 mph)
+`
+	actualConvertOutput := PrettyPrintAST(convert, "")
+	if normalize(actualConvertOutput) != normalize(expectedConvertOutput) {
+		t.Errorf("PrettyPrintAST mismatch.\nExpected:\n%s\nGot:\n%s", normalize(expectedConvertOutput), normalize(actualConvertOutput))
+	}
+
+	// Test with date and duration literals
+	deadline := &BinaryOpNode{
+		Left:  &DateNode{Literal: "2026-10-17"},
+		Op:    token.Token{Type: token.PLUS, Value: "+"},
+		Right: &DurationNode{Literal: "3d"},
+	}
+	expectedDeadlineOutput := `
+BinaryOp(+)
+  Left:
+    Date(2026-10-17)
+  Right:
+    Duration(3d)
+`
+	actualDeadlineOutput := PrettyPrintAST(deadline, "")
+	if normalize(actualDeadlineOutput) != normalize(expectedDeadlineOutput) {
+		t.Errorf("PrettyPrintAST mismatch.\nExpected:\n%s\nGot:\n%s", normalize(expectedDeadlineOutput), normalize(actualDeadlineOutput))
+	}
+
+	// Test with a boolean literal
+	negation := &UnaryOpNode{
+		Op:   token.Token{Type: token.NOT, Value: "!"},
+		Expr: &BoolNode{Value: false},
+	}
+	expectedNegationOutput := `
+UnaryOp(!)
+  Expr:
+    Bool(false)
+`
+	actualNegationOutput := PrettyPrintAST(negation, "")
+	if normalize(actualNegationOutput) != normalize(expectedNegationOutput) {
+		t.Errorf("PrettyPrintAST mismatch.\nExpected:\n%s\nGot:\n%s", normalize(expectedNegationOutput), normalize(actualNegationOutput))
+	}
+
+	// Test with a conditional
+	conditional := &ConditionalNode{
+		Cond: &IdentNode{Name: "x"},
+		Then: &NumberNode{Value: 1},
+		Else: &NumberNode{Value: 0},
+	}
+	expectedConditionalOutput := `
+Conditional
+  Cond:
+    Ident(x)
+  Then:
+    Number(1)
+  Else:
+    Number(0)
+`
+	actualConditionalOutput := PrettyPrintAST(conditional, "")
+	if normalize(actualConditionalOutput) != normalize(expectedConditionalOutput) {
+		t.Errorf("PrettyPrintAST mismatch.\nExpected:\n%s\nGot:\n%s", normalize(expectedConditionalOutput), normalize(actualConditionalOutput))
+	}
+
+	// Test with a function definition
+	def := &FunctionDefNode{
+		Name:   &IdentNode{Name: "double"},
+		Params: []*IdentNode{{Name: "x"}},
+		Body: &BinaryOpNode{
+			Left:  &IdentNode{Name: "x"},
+			Op:    token.Token{Type: token.MULTIPLY, Value: "*"},
+			Right: &NumberNode{Value: 2},
+		},
+	}
+	expectedDefOutput := `
+FunctionDef(double(x))
+  Body:
+    BinaryOp(*)
+      Left:
+        Ident(x)
+      Right:
+        Number(2)
+`
+	actualDefOutput := PrettyPrintAST(def, "")
+	if normalize(actualDefOutput) != normalize(expectedDefOutput) {
+		t.Errorf("PrettyPrintAST mismatch.\nExpected:\n%s\nGot:\n%s", normalize(expectedDefOutput), normalize(actualDefOutput))
+	}
+
+	// Test with a lambda
+	lambda := &LambdaNode{
+		Params: []*IdentNode{{Name: "a"}, {Name: "b"}},
+		Body:   &IdentNode{Name: "b"},
+	}
+	expectedLambdaOutput := `
+Lambda(a, b)
+  Body:
+    Ident(b)
+`
+	actualLambdaOutput := PrettyPrintAST(lambda, "")
+	if normalize(actualLambdaOutput) != normalize(expectedLambdaOutput) {
+		t.Errorf("PrettyPrintAST mismatch.\nExpected:\n%s\nGot:\n%s", normalize(expectedLambdaOutput), normalize(actualLambdaOutput))
+	}
+
+	// Test with a call of an expression
+	apply := &ApplyNode{
+		Func: &IdentNode{Name: "f"},
+		Args: []Node{&NumberNode{Value: 1}},
+	}
+	expectedApplyOutput := `
+Apply
+  Func:
+    Ident(f)
+  Arg 0:
+    Number(1)
+`
+	actualApplyOutput := PrettyPrintAST(apply, "")
+	if normalize(actualApplyOutput) != normalize(expectedApplyOutput) {
+		t.Errorf("PrettyPrintAST mismatch.\nExpected:\n%s\nGot:\n%s", normalize(expectedApplyOutput), normalize(actualApplyOutput))
+	}
+
+	// Test with a list
+	list := &ListNode{Elems: []Node{&NumberNode{Value: 1}, &IdentNode{Name: "x"}}}
+	expectedListOutput := `
+List
+  Elem 0:
+    Number(1)
+  Elem 1:
+    Ident(x)
+`
+	actualListOutput := PrettyPrintAST(list, "")
+	if normalize(actualListOutput) != normalize(expectedListOutput) {
+		t.Errorf("PrettyPrintAST mismatch.\nExpected:\n%s\nGot:\n%s", normalize(expectedListOutput), normalize(actualListOutput))
+	}
+
+	// Test with an index and a slice
+	index := &IndexNode{
+		Expr:  &SliceNode{Expr: &IdentNode{Name: "v"}, Lo: &NumberNode{Value: 1}},
+		Index: &NumberNode{Value: 0},
+	}
+	expectedIndexOutput := `
+Index
+  Expr:
+    Slice
+      Expr:
+        Ident(v)
+      Lo:
+        Number(1)
+  Index:
+    Number(0)
+`
+	actualIndexOutput := PrettyPrintAST(index, "")
+	if normalize(actualIndexOutput) != normalize(expectedIndexOutput) {
+		t.Errorf("PrettyPrintAST mismatch.\nExpected:\n%s\nGot:\n%s", normalize(expectedIndexOutput), normalize(actualIndexOutput))
+	}
+
 	// Test with a simple number node
 	numNode := &NumberNode{Value: 42}
 	expectedNumOutput := "Number(42)\n"
@@ -147,3 +540,31 @@ BinaryOp(*)
 		t.Errorf("PrettyPrintAST for NumberNode mismatch.\nExpected:\n%s\nGot:\n%s", expectedNumOutput, actualNumOutput)
 	}
 }
+
+func TestNodeSpan(t *testing.T) {
+	span := token.Span{
+		Start: token.Position{Offset: 2, Line: 1, Column: 3},
+		End:   token.Position{Offset: 7, Line: 1, Column: 8},
+	}
+
+	nodes := []Node{
+		&NumberNode{Value: 1, Loc: span},
+		&BinaryOpNode{Left: &NumberNode{Value: 1}, Op: token.Token{Type: token.PLUS, Value: "+"}, Right: &NumberNode{Value: 2}, Loc: span},
+		&UnaryOpNode{Op: token.Token{Type: token.MINUS, Value: "-"}, Expr: &NumberNode{Value: 1}, Loc: span},
+		&BoolNode{Value: true, Loc: span},
+		&ConditionalNode{Cond: &BoolNode{Value: true}, Then: &NumberNode{Value: 1}, Else: &NumberNode{Value: 2}, Loc: span},
+		&FunctionDefNode{Name: &IdentNode{Name: "f"}, Body: &NumberNode{Value: 1}, Loc: span},
+		&LambdaNode{Body: &NumberNode{Value: 1}, Loc: span},
+		&ApplyNode{Func: &IdentNode{Name: "f"}, Loc: span},
+		&ListNode{Loc: span},
+		&IndexNode{Expr: &IdentNode{Name: "v"}, Index: &NumberNode{Value: 0}, Loc: span},
+		&SliceNode{Expr: &IdentNode{Name: "v"}, Loc: span},
+		&ErrorNode{Loc: span},
+	}
+
+	for _, node := range nodes {
+		if node.Span() != span {
+			t.Errorf("%T.Span() failed. Expected %+v, got %+v", node, span, node.Span())
+		}
+	}
+}
@@ -0,0 +1,26 @@
+package diagnostic
+
+import (
+	"basic-arithmetic-parser/token"
+	"fmt"
+)
+
+// Diagnostic describes a single problem found while processing the input,
+// e.g. an invalid character from the lexer, a syntax error from the parser or
+// a division by zero during evaluation. Span is the part of the input the
+// problem refers to, and Label an optional short note shown next to it when
+// rendered (e.g. "expected ')'").
+type Diagnostic struct {
+	Span    token.Span
+	Message string
+	Label   string
+}
+
+// Error lets a Diagnostic be used anywhere an error is expected. The message
+// is prefixed with "line:column: " when the position is known.
+func (d Diagnostic) Error() string {
+	if d.Span.Start.Line == 0 {
+		return d.Message
+	}
+	return fmt.Sprintf("%d:%d: %s", d.Span.Start.Line, d.Span.Start.Column, d.Message)
+}
@@ -0,0 +1,105 @@
+package diagnostic
+
+import (
+	"basic-arithmetic-parser/token"
+	"testing"
+)
+
+func span(startOffset, endOffset int) token.Span {
+	// single-line source, so columns follow the offsets
+	return token.Span{
+		Start: token.Position{Offset: startOffset, Line: 1, Column: startOffset + 1},
+		End:   token.Position{Offset: endOffset, Line: 1, Column: endOffset + 1},
+	}
+}
+
+func TestError(t *testing.T) {
+	d := Diagnostic{Span: span(4, 5), Message: "division by zero"}
+	if d.Error() != "1:5: division by zero" {
+		t.Errorf("Error() wrong. expected=%q, got=%q", "1:5: division by zero", d.Error())
+	}
+
+	// without a position only the message is shown
+	d = Diagnostic{Message: "division by zero"}
+	if d.Error() != "division by zero" {
+		t.Errorf("Error() wrong. expected=%q, got=%q", "division by zero", d.Error())
+	}
+}
+
+func TestRender(t *testing.T) {
+	tests := []struct {
+		name     string
+		renderer Renderer
+		source   string
+		diag     Diagnostic
+		expected string
+	}{
+		{
+			name:   "Single Character",
+			source: "2 + * 3",
+			diag:   Diagnostic{Span: span(4, 5), Message: "expected expression, found '*'", Label: "expected expression"},
+			expected: "error: expected expression, found '*'\n" +
+				" --> 1:5\n" +
+				"  |\n" +
+				"1 | 2 + * 3\n" +
+				"  |     ^ expected expression\n",
+		},
+		{
+			name:   "Wide Span Without Label",
+			source: "1 + 4 / 0",
+			diag:   Diagnostic{Span: span(4, 9), Message: "division by zero"},
+			expected: "error: division by zero\n" +
+				" --> 1:5\n" +
+				"  |\n" +
+				"1 | 1 + 4 / 0\n" +
+				"  |     ^~~~~\n",
+		},
+		{
+			name:   "End Of Input",
+			source: "(1 + 2",
+			diag:   Diagnostic{Span: span(6, 6), Message: "expected ')', found end of input", Label: "expected ')'"},
+			expected: "error: expected ')', found end of input\n" +
+				" --> 1:7\n" +
+				"  |\n" +
+				"1 | (1 + 2\n" +
+				"  |       ^ expected ')'\n",
+		},
+		{
+			name:     "Named Source With Line Offset",
+			renderer: Renderer{Name: "exprs.txt", LineOffset: 9},
+			source:   "\t1 $",
+			diag:     Diagnostic{Span: span(3, 4), Message: "invalid character '$'"},
+			expected: "error: invalid character '$'\n" +
+				"  --> exprs.txt:10:4\n" +
+				"   |\n" +
+				"10 | \t1 $\n" +
+				"   | \t  ^\n",
+		},
+		{
+			name:     "No Position",
+			source:   "1 + 2",
+			diag:     Diagnostic{Message: "unknown node type"},
+			expected: "error: unknown node type\n",
+		},
+		{
+			name:     "Color",
+			renderer: Renderer{Color: true},
+			source:   "1 $",
+			diag:     Diagnostic{Span: span(2, 3), Message: "invalid character '$'"},
+			expected: "\033[1;31merror\033[0m\033[1m: invalid character '$'\033[0m\n" +
+				" \033[1;34m-->\033[0m 1:3\n" +
+				"  \033[1;34m|\033[0m\n" +
+				"\033[1;34m1\033[0m \033[1;34m|\033[0m 1 $\n" +
+				"  \033[1;34m|\033[0m   \033[1;31m^\033[0m\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.renderer.Render(tt.source, tt.diag)
+			if got != tt.expected {
+				t.Errorf("Render mismatch.\nExpected:\n%s\nGot:\n%s", tt.expected, got)
+			}
+		})
+	}
+}
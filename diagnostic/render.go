@@ -0,0 +1,106 @@
+package diagnostic
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// ANSI escape sequences used when colour output is enabled
+const (
+	colorReset = "\033[0m"
+	colorBold  = "\033[1m"
+	colorRed   = "\033[1;31m"
+	colorBlue  = "\033[1;34m"
+)
+
+// Renderer formats diagnostics in the style of rustc/clang: the message,
+// its location, the offending source line and the span underlined with ^~~~.
+type Renderer struct {
+	// Name identifies the source in the location line (e.g. a file name); it
+	// may be empty
+	Name string
+	// LineOffset is added to reported line numbers, for sources that are
+	// part of a larger input (e.g. one line of a file)
+	LineOffset int
+	// Color enables ANSI colours, which should only be used on a terminal
+	Color bool
+}
+
+func (r Renderer) paint(color, s string) string {
+	if !r.Color {
+		return s
+	}
+	return color + s + colorReset
+}
+
+// Render formats d against source, the text that was lexed and parsed. The
+// result ends with a newline.
+//
+//	error: expected ')', found end of input
+//	 --> 1:7
+//	  |
+//	1 | (1 + 2
+//	  |       ^ expected ')'
+func (r Renderer) Render(source string, d Diagnostic) string {
+	var sb strings.Builder
+	sb.WriteString(r.paint(colorRed, "error"))
+	sb.WriteString(r.paint(colorBold, ": "+d.Message))
+	sb.WriteString("\n")
+
+	start := d.Span.Start
+	if start.Line == 0 {
+		// no position information, so there is nothing to point at
+		return sb.String()
+	}
+
+	lineNum := fmt.Sprint(start.Line + r.LineOffset)
+	gutter := strings.Repeat(" ", len(lineNum))
+	location := fmt.Sprintf("%s:%d", lineNum, start.Column)
+	if r.Name != "" {
+		location = r.Name + ":" + location
+	}
+	sb.WriteString(fmt.Sprintf("%s%s %s\n", gutter, r.paint(colorBlue, "-->"), location))
+	sb.WriteString(fmt.Sprintf("%s %s\n", gutter, r.paint(colorBlue, "|")))
+
+	line := sourceLine(source, start.Offset-(start.Column-1))
+	sb.WriteString(fmt.Sprintf("%s %s %s\n", r.paint(colorBlue, lineNum), r.paint(colorBlue, "|"), line))
+
+	// pad up to the start column, keeping tabs so the carets line up
+	col := min(start.Column-1, len(line))
+	padding := []rune(line[:col])
+	for i, ch := range padding {
+		if ch != '\t' {
+			padding[i] = ' '
+		}
+	}
+
+	// the underline stops at the end of the line for multi-line spans
+	width := 1
+	if d.Span.End.Line == start.Line && d.Span.End.Offset > start.Offset {
+		end := min(d.Span.End.Offset-start.Offset+col, len(line))
+		width = max(utf8.RuneCountInString(line[col:end]), 1)
+	} else if d.Span.End.Line > start.Line {
+		width = max(utf8.RuneCountInString(line[col:]), 1)
+	}
+	underline := "^" + strings.Repeat("~", width-1)
+	if d.Label != "" {
+		underline += " " + d.Label
+	}
+	sb.WriteString(fmt.Sprintf("%s %s %s%s\n", gutter, r.paint(colorBlue, "|"), string(padding), r.paint(colorRed, underline)))
+
+	return sb.String()
+}
+
+// sourceLine returns the line of source starting at byte offset lineStart,
+// without its line terminator
+func sourceLine(source string, lineStart int) string {
+	if lineStart < 0 || lineStart > len(source) {
+		return ""
+	}
+	line := source[lineStart:]
+	if i := strings.IndexByte(line, '\n'); i >= 0 {
+		line = line[:i]
+	}
+	return strings.TrimSuffix(line, "\r")
+}
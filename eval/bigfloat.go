@@ -0,0 +1,664 @@
+package eval
+
+import (
+	"basic-arithmetic-parser/ast"
+	"basic-arithmetic-parser/token"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"strings"
+)
+
+// guardBits is the extra precision the arbitrary-precision functions work
+// at, so that their results are accurate before the final rounding
+const guardBits = 64
+
+// maxResultExp bounds the binary exponent of the results of ^ and the
+// functions: printing a number of 2^maxResultExp or more in decimal would
+// take too long
+const maxResultExp = 1 << 22
+
+// bigFloatEvaluator evaluates with big.Float values of a fixed precision
+// and rounding mode
+type bigFloatEvaluator struct {
+	prec uint
+	mode big.RoundingMode
+}
+
+// EvalBigFloat evaluates the given AST node with arbitrary-precision
+// floating point numbers of prec mantissa bits. Number literals are read
+// from their text and every operation is rounded to prec bits using mode.
+// It supports + - * / ^, unary signs, the constants pi and e, and the
+// functions sqrt, abs, exp, ln, log10, log2, sin, cos, tan, asin, acos and
+// atan, which are computed at extra precision before rounding. Other
+// operators, variables and functions are reported as errors.
+func EvalBigFloat(node ast.Node, prec uint, mode big.RoundingMode) (*big.Float, error) {
+	if prec == 0 || prec > big.MaxPrec {
+		return nil, fmt.Errorf("invalid precision: %d bits", prec)
+	}
+	e := &bigFloatEvaluator{prec: prec, mode: mode}
+	return e.eval(node)
+}
+
+// DigitsPrecision returns the mantissa bits to evaluate with for a result
+// of the given number of significant digits. It includes guard bits, so
+// that the result is only rounded to digits once, by FormatBigFloat.
+func DigitsPrecision(digits uint) uint {
+	return uint(math.Ceil(float64(digits)*math.Log2(10))) + guardBits
+}
+
+// FormatBigFloat formats x with the given number of significant digits,
+// rounding its exact binary value to them in decimal using mode. Text
+// alone always rounds to nearest, so 2/3 would print as 0.66667 even when
+// rounding down.
+func FormatBigFloat(x *big.Float, digits uint, mode big.RoundingMode) string {
+	if digits == 0 || x.IsInf() || x.Sign() == 0 {
+		return x.Text('g', int(digits))
+	}
+	r, _ := x.Rat(nil)
+	num, den := r.Num(), r.Denom()
+
+	// exp is the decimal exponent of x: 10^exp <= |x| < 10^(exp+1). The
+	// binary exponent puts it within one of the estimate.
+	exp := int(math.Floor(float64(x.MantExp(nil)-1) * math.Log10(2)))
+	for scaledCmp(num, den, exp+1) >= 0 {
+		exp++
+	}
+	for scaledCmp(num, den, exp) < 0 {
+		exp--
+	}
+
+	// round x * 10^shift to an integer of digits digits; rounding up can
+	// carry into one more digit
+	shift := int(digits) - 1 - exp
+	n, d := new(big.Int).Set(num), new(big.Int).Set(den)
+	if shift >= 0 {
+		n.Mul(n, pow10(shift))
+	} else {
+		d.Mul(d, pow10(-shift))
+	}
+	coef := quoRound(n, d, mode).Text(10)
+	neg := strings.HasPrefix(coef, "-")
+	coef = strings.TrimPrefix(coef, "-")
+	// formatting the digits directly is much faster than Text for a tiny x
+	return formatDecimal(neg, strings.TrimRight(coef, "0"), len(coef)-shift, int(digits))
+}
+
+// formatDecimal formats 0.mant * 10^exp, where mant has no trailing zeros,
+// as Text formats a value with 'g' and the given number of digits
+func formatDecimal(neg bool, mant string, exp, digits int) string {
+	var b strings.Builder
+	if neg {
+		b.WriteByte('-')
+	}
+	eprec := digits
+	if eprec > len(mant) && len(mant) >= exp {
+		eprec = len(mant)
+	}
+	if exp-1 < -4 || exp-1 >= eprec {
+		b.WriteByte(mant[0])
+		if len(mant) > 1 {
+			b.WriteByte('.')
+			b.WriteString(mant[1:])
+		}
+		fmt.Fprintf(&b, "e%+03d", exp-1)
+		return b.String()
+	}
+
+	// the digit of mant at i, or a 0 beyond it
+	digit := func(i int) byte {
+		if i >= 0 && i < len(mant) {
+			return mant[i]
+		}
+		return '0'
+	}
+	if exp > 0 {
+		for i := range exp {
+			b.WriteByte(digit(i))
+		}
+	} else {
+		b.WriteByte('0')
+	}
+	prec := digits
+	if prec > exp {
+		prec = len(mant)
+	}
+	if frac := prec - exp; frac > 0 {
+		b.WriteByte('.')
+		for i := range frac {
+			b.WriteByte(digit(exp + i))
+		}
+	}
+	return b.String()
+}
+
+// quoRound returns num/den rounded to an integer using mode
+func quoRound(num, den *big.Int, mode big.RoundingMode) *big.Int {
+	q, r := new(big.Int).QuoRem(num, den, new(big.Int))
+	if r.Sign() == 0 {
+		return q
+	}
+	// the sign of the exact quotient, which q (truncated) may have lost
+	sign := int64(num.Sign() * den.Sign())
+	// compare the remainder to half the divisor
+	half := new(big.Int).Abs(r)
+	half.Lsh(half, 1)
+	cmp := half.CmpAbs(den)
+
+	var away bool
+	switch mode {
+	case big.ToNearestEven:
+		away = cmp > 0 || (cmp == 0 && q.Bit(0) == 1)
+	case big.ToNearestAway:
+		away = cmp >= 0
+	case big.ToZero:
+		away = false
+	case big.AwayFromZero:
+		away = true
+	case big.ToNegativeInf:
+		away = sign < 0
+	case big.ToPositiveInf:
+		away = sign > 0
+	}
+	if away {
+		q.Add(q, big.NewInt(sign))
+	}
+	return q
+}
+
+// pow10 returns 10^n for n >= 0
+func pow10(n int) *big.Int {
+	return new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(n)), nil)
+}
+
+// scaledCmp compares |num/den| with 10^exp
+func scaledCmp(num, den *big.Int, exp int) int {
+	n := new(big.Int).Abs(num)
+	d := new(big.Int).Abs(den)
+	if exp >= 0 {
+		d.Mul(d, pow10(exp))
+	} else {
+		n.Mul(n, pow10(-exp))
+	}
+	return n.Cmp(d)
+}
+
+// newFloat returns a zero value with the evaluator's precision and mode
+func (e *bigFloatEvaluator) newFloat() *big.Float {
+	return new(big.Float).SetPrec(e.prec).SetMode(e.mode)
+}
+
+// round rounds x, computed at a higher precision, to the evaluator's
+func (e *bigFloatEvaluator) round(x *big.Float) *big.Float {
+	return e.newFloat().Set(x)
+}
+
+func (e *bigFloatEvaluator) eval(node ast.Node) (*big.Float, error) {
+	switch n := node.(type) {
+	case *ast.NumberNode:
+		if n.Imaginary {
+			return nil, imaginaryError(n)
+		}
+		literal := literalText(n)
+		f, ok := e.newFloat().SetString(literal)
+		if !ok {
+			return nil, newError(n, fmt.Sprintf("invalid number: %s", literal))
+		}
+		return f, nil
+	case *ast.IdentNode:
+		switch n.Name {
+		case "pi":
+			return e.round(bigPi(e.prec + guardBits)), nil
+		case "e":
+			one := new(big.Float).SetInt64(1)
+			return e.round(bigExp(one, e.prec+guardBits)), nil
+		default:
+			return nil, newError(n, "variables are not supported in arbitrary-precision mode")
+		}
+	case *ast.BinaryOpNode:
+		leftVal, err := e.eval(n.Left)
+		if err != nil {
+			return nil, err
+		}
+		rightVal, err := e.eval(n.Right)
+		if err != nil {
+			return nil, err
+		}
+
+		var result *big.Float
+		switch n.Op.Type {
+		case token.PLUS:
+			result = e.newFloat().Add(leftVal, rightVal)
+		case token.MINUS:
+			result = e.newFloat().Sub(leftVal, rightVal)
+		case token.MULTIPLY:
+			result = e.newFloat().Mul(leftVal, rightVal)
+		case token.DIVIDE:
+			if rightVal.Sign() == 0 {
+				return nil, newError(n, "division by zero")
+			}
+			result = e.newFloat().Quo(leftVal, rightVal)
+		case token.POWER:
+			result, err = e.pow(leftVal, rightVal)
+			if err != nil {
+				return nil, newError(n, err.Error())
+			}
+		default:
+			return nil, newError(n, fmt.Sprintf("operator %s is not supported in arbitrary-precision mode", n.Op.Value))
+		}
+		if result.IsInf() {
+			return nil, newError(n, "result is too large")
+		}
+		return result, nil
+	case *ast.UnaryOpNode:
+		exprVal, err := e.eval(n.Expr)
+		if err != nil {
+			return nil, err
+		}
+
+		switch n.Op.Type {
+		case token.PLUS: // Unary plus (identity)
+			return exprVal, nil
+		case token.MINUS: // Unary minus (negation)
+			return e.newFloat().Neg(exprVal), nil
+		default:
+			return nil, newError(n, fmt.Sprintf("unknown unary operator: %s", n.Op.Value))
+		}
+	case *ast.CallNode:
+		f, ok := bigFloatFunctions[n.Name.Name]
+		if !ok {
+			return nil, newError(n.Name, fmt.Sprintf("function %s is not supported in arbitrary-precision mode", n.Name.Name))
+		}
+		if err := checkArity(n.Name.Name, 1, 1, len(n.Args)); err != nil {
+			return nil, newError(n, err.Error())
+		}
+		arg, err := e.eval(n.Args[0])
+		if err != nil {
+			return nil, err
+		}
+		result, err := f(arg, e.prec+guardBits)
+		if err != nil {
+			return nil, newError(n, fmt.Sprintf("%s: %v", n.Name.Name, err))
+		}
+		if result, err = e.limit(result); err != nil {
+			return nil, newError(n, err.Error())
+		}
+		return e.round(result), nil
+	case *ast.AssignNode:
+		return nil, newError(n, "variables are not supported in arbitrary-precision mode")
+	case *ast.ListNode, *ast.IndexNode, *ast.SliceNode:
+		return nil, listError(n)
+	case *ast.QuantityNode, *ast.ConvertNode:
+		return nil, unitsError(n)
+	case *ast.DateNode, *ast.DurationNode:
+		return nil, dateError(n)
+	case *ast.PercentNode:
+		return nil, percentError(n)
+	case *ast.BoolNode, *ast.ConditionalNode:
+		return nil, boolError(n)
+	case *ast.FunctionDefNode, *ast.LambdaNode, *ast.ApplyNode:
+		return nil, functionDefError(n)
+	case *ast.ErrorNode:
+		return nil, newError(n, "cannot evaluate an expression with syntax errors")
+	default:
+		return nil, fmt.Errorf("unknown node type: %T", node)
+	}
+}
+
+// pow returns base^exponent: exactly rounded by repeated squaring for
+// integer exponents, and as exp(exponent * ln(base)) otherwise
+func (e *bigFloatEvaluator) pow(base, exponent *big.Float) (*big.Float, error) {
+	if n, acc := exponent.Int64(); exponent.IsInt() && acc == big.Exact {
+		if base.Sign() == 0 && n < 0 {
+			return nil, errors.New("division by zero")
+		}
+		return e.limit(bigPowInt(base, n, e.prec+guardBits))
+	}
+
+	switch base.Sign() {
+	case 0:
+		if exponent.Sign() < 0 {
+			return nil, errors.New("division by zero")
+		}
+		return e.newFloat(), nil
+	case -1:
+		return nil, errors.New("fractional power of a negative number")
+	}
+	// the larger the exponent, the more ln(base) is magnified
+	wp := e.prec + guardBits + uint(max(exponent.MantExp(nil), 0))
+	lnBase, _ := bigLn(base, wp)
+	product := new(big.Float).SetPrec(wp).Mul(exponent, lnBase)
+	if product.MantExp(nil) > 32 {
+		return nil, errors.New("result is too large")
+	}
+	return e.limit(bigExp(product, wp))
+}
+
+// limit rounds x, reporting it as too large if its binary exponent is
+// beyond maxResultExp, and flushing it to zero if it is that far below
+func (e *bigFloatEvaluator) limit(x *big.Float) (*big.Float, error) {
+	switch exp := x.MantExp(nil); {
+	case x.IsInf() || exp > maxResultExp:
+		return nil, errors.New("result is too large")
+	case exp < -maxResultExp:
+		return e.newFloat(), nil
+	}
+	return e.round(x), nil
+}
+
+// bigFloatFunctions are the functions available in arbitrary-precision
+// mode. Each computes its result at the given working precision.
+var bigFloatFunctions = map[string]func(x *big.Float, prec uint) (*big.Float, error){
+	"sqrt": func(x *big.Float, prec uint) (*big.Float, error) {
+		if x.Sign() < 0 {
+			return nil, errNegativeSqrt
+		}
+		return new(big.Float).SetPrec(prec).Sqrt(x), nil
+	},
+	"abs": func(x *big.Float, prec uint) (*big.Float, error) {
+		return new(big.Float).SetPrec(prec).Abs(x), nil
+	},
+	"exp": func(x *big.Float, prec uint) (*big.Float, error) {
+		if x.MantExp(nil) > 32 {
+			if x.Sign() < 0 {
+				return new(big.Float).SetPrec(prec), nil
+			}
+			return nil, errors.New("argument is too large")
+		}
+		return bigExp(x, prec), nil
+	},
+	"ln": bigLn,
+	"log10": func(x *big.Float, prec uint) (*big.Float, error) {
+		return bigLogBase(x, 10, prec)
+	},
+	"log2": func(x *big.Float, prec uint) (*big.Float, error) {
+		return bigLogBase(x, 2, prec)
+	},
+	"sin": func(x *big.Float, prec uint) (*big.Float, error) {
+		sin, _ := bigSinCos(x, prec)
+		return sin, nil
+	},
+	"cos": func(x *big.Float, prec uint) (*big.Float, error) {
+		_, cos := bigSinCos(x, prec)
+		return cos, nil
+	},
+	"tan": func(x *big.Float, prec uint) (*big.Float, error) {
+		sin, cos := bigSinCos(x, prec)
+		if cos.Sign() == 0 {
+			return nil, errors.New("tangent is undefined")
+		}
+		return new(big.Float).SetPrec(prec).Quo(sin, cos), nil
+	},
+	"asin": bigAsin,
+	"acos": func(x *big.Float, prec uint) (*big.Float, error) {
+		asin, err := bigAsin(x, prec)
+		if err != nil {
+			return nil, err
+		}
+		halfPi := new(big.Float).SetPrec(prec).SetMantExp(bigPi(prec), -1)
+		return halfPi.Sub(halfPi, asin), nil
+	},
+	"atan": func(x *big.Float, prec uint) (*big.Float, error) {
+		return bigAtan(x, prec), nil
+	},
+}
+
+// bigPowInt returns x^n by repeated squaring
+func bigPowInt(x *big.Float, n int64, prec uint) *big.Float {
+	result := new(big.Float).SetPrec(prec).SetInt64(1)
+	base := new(big.Float).SetPrec(prec).Set(x)
+	negative := n < 0
+	if negative {
+		n = -n
+	}
+	for n > 0 {
+		if n&1 == 1 {
+			result.Mul(result, base)
+		}
+		base.Mul(base, base)
+		n >>= 1
+	}
+	if negative {
+		result.Quo(new(big.Float).SetPrec(prec).SetInt64(1), result)
+	}
+	return result
+}
+
+// negligible reports whether term no longer affects a sum of magnitude
+// around 2^sumExp at prec bits
+func negligible(term *big.Float, sumExp int, prec uint) bool {
+	return term.Sign() == 0 || term.MantExp(nil) < sumExp-int(prec)
+}
+
+// bigExp returns e^x. The argument is halved until it is small, the Taylor
+// series is summed, and the result squared back up. |x| must be below 2^32.
+func bigExp(x *big.Float, prec uint) *big.Float {
+	if x.Sign() == 0 {
+		return new(big.Float).SetPrec(prec).SetInt64(1)
+	}
+	// each squaring doubles the relative error, so work with a bit more
+	// precision per halving
+	halvings := max(x.MantExp(nil)+8, 0)
+	wp := prec + uint(halvings) + 8
+	r := new(big.Float).SetPrec(wp).SetMantExp(x, -halvings)
+
+	sum := new(big.Float).SetPrec(wp).SetInt64(1)
+	term := new(big.Float).SetPrec(wp).SetInt64(1)
+	divisor := new(big.Float).SetPrec(wp)
+	for i := int64(1); ; i++ {
+		term.Mul(term, r)
+		term.Quo(term, divisor.SetInt64(i))
+		if negligible(term, 0, wp) {
+			break
+		}
+		sum.Add(sum, term)
+	}
+	for range halvings {
+		sum.Mul(sum, sum)
+	}
+	return new(big.Float).SetPrec(prec).Set(sum)
+}
+
+// bigLn returns the natural logarithm of x > 0. Writing x = m * 2^k with m
+// near 1, ln(x) = ln(m) + k*ln(2), and ln(m) is found by Halley's method on
+// exp(y) = m starting from the float64 estimate.
+func bigLn(x *big.Float, prec uint) (*big.Float, error) {
+	if x.Sign() <= 0 {
+		return nil, errNonPositiveLog
+	}
+	m := new(big.Float)
+	k := x.MantExp(m)
+	if m.Cmp(big.NewFloat(math.Sqrt2/2)) < 0 {
+		m.SetMantExp(m, 1)
+		k--
+	}
+
+	lnM := lnNearOne(m, prec)
+	if k == 0 {
+		return lnM, nil
+	}
+	wp := prec + 32
+	ln2 := lnNearOne(new(big.Float).SetInt64(2), wp)
+	result := new(big.Float).SetPrec(wp).SetInt64(int64(k))
+	result.Mul(result, ln2)
+	result.Add(result, lnM)
+	return new(big.Float).SetPrec(prec).Set(result), nil
+}
+
+// lnNearOne returns ln(m) for m roughly between 1/2 and 2
+func lnNearOne(m *big.Float, prec uint) *big.Float {
+	// ln(m) is about m-1 for m close to 1, so its relative accuracy needs
+	// as many extra bits as m-1 has leading zeros
+	wp := prec + 16
+	one := new(big.Float).SetInt64(1)
+	if diff := new(big.Float).SetPrec(wp).Sub(m, one); diff.Sign() != 0 {
+		wp += uint(max(-diff.MantExp(nil), 0))
+	} else {
+		return new(big.Float).SetPrec(prec)
+	}
+
+	mf, _ := m.Float64()
+	y := new(big.Float).SetPrec(wp).SetFloat64(math.Log(mf))
+	num := new(big.Float).SetPrec(wp)
+	den := new(big.Float).SetPrec(wp)
+	for range 64 {
+		// y += 2 * (m - e^y) / (m + e^y)
+		ey := bigExp(y, wp)
+		num.Sub(m, ey)
+		den.Add(m, ey)
+		num.Quo(num, den)
+		num.SetMantExp(num, 1)
+		y.Add(y, num)
+		if negligible(num, y.MantExp(nil), wp-8) {
+			break
+		}
+	}
+	return new(big.Float).SetPrec(prec).Set(y)
+}
+
+// bigLogBase returns the logarithm of x in the given integer base
+func bigLogBase(x *big.Float, base int64, prec uint) (*big.Float, error) {
+	wp := prec + 16
+	lnX, err := bigLn(x, wp)
+	if err != nil {
+		return nil, err
+	}
+	lnBase, _ := bigLn(new(big.Float).SetInt64(base), wp)
+	return new(big.Float).SetPrec(prec).Quo(lnX, lnBase), nil
+}
+
+// bigPi returns pi using Machin's formula pi = 16 atan(1/5) - 4 atan(1/239)
+func bigPi(prec uint) *big.Float {
+	wp := prec + 16
+	fifth := new(big.Float).SetPrec(wp).Quo(big.NewFloat(1), big.NewFloat(5))
+	inv239 := new(big.Float).SetPrec(wp).Quo(big.NewFloat(1), big.NewFloat(239))
+	a := atanSeries(fifth, wp)
+	a.Mul(a, big.NewFloat(16))
+	b := atanSeries(inv239, wp)
+	b.Mul(b, big.NewFloat(4))
+	return new(big.Float).SetPrec(prec).Sub(a, b)
+}
+
+// atanSeries sums the Taylor series x - x^3/3 + x^5/5 - ..., which
+// converges quickly for small |x|
+func atanSeries(x *big.Float, prec uint) *big.Float {
+	sum := new(big.Float).SetPrec(prec).Set(x)
+	power := new(big.Float).SetPrec(prec).Set(x)
+	xSquared := new(big.Float).SetPrec(prec).Mul(x, x)
+	term := new(big.Float).SetPrec(prec)
+	divisor := new(big.Float).SetPrec(prec)
+	sumExp := x.MantExp(nil)
+	for i := int64(1); ; i++ {
+		power.Mul(power, xSquared)
+		power.Neg(power)
+		term.Quo(power, divisor.SetInt64(2*i+1))
+		if negligible(term, sumExp, prec) {
+			break
+		}
+		sum.Add(sum, term)
+	}
+	return sum
+}
+
+// bigAtan returns atan(x). Arguments above 1 use atan(x) = pi/2 - atan(1/x);
+// the rest are halved with atan(x) = 2 atan(x / (1 + sqrt(1 + x^2))) until
+// the series converges quickly.
+func bigAtan(x *big.Float, prec uint) *big.Float {
+	if x.Sign() == 0 {
+		return new(big.Float).SetPrec(prec)
+	}
+	const halvings = 8
+	wp := prec + 16 + halvings
+	a := new(big.Float).SetPrec(wp).Abs(x)
+	one := new(big.Float).SetPrec(wp).SetInt64(1)
+	inverted := a.Cmp(one) > 0
+	if inverted {
+		a.Quo(one, a)
+	}
+
+	tmp := new(big.Float).SetPrec(wp)
+	for range halvings {
+		tmp.Mul(a, a)
+		tmp.Add(tmp, one)
+		tmp.Sqrt(tmp)
+		tmp.Add(tmp, one)
+		a.Quo(a, tmp)
+	}
+	result := atanSeries(a, wp)
+	result.SetMantExp(result, halvings)
+
+	if inverted {
+		halfPi := new(big.Float).SetPrec(wp).SetMantExp(bigPi(wp), -1)
+		result.Sub(halfPi, result)
+	}
+	if x.Sign() < 0 {
+		result.Neg(result)
+	}
+	return new(big.Float).SetPrec(prec).Set(result)
+}
+
+// bigAsin returns asin(x) = atan(x / sqrt(1 - x^2)) for |x| <= 1
+func bigAsin(x *big.Float, prec uint) (*big.Float, error) {
+	one := new(big.Float).SetInt64(1)
+	abs := new(big.Float).Abs(x)
+	switch abs.Cmp(one) {
+	case 1:
+		return nil, errInverseTrigDomain
+	case 0:
+		halfPi := new(big.Float).SetPrec(prec).SetMantExp(bigPi(prec), -1)
+		if x.Sign() < 0 {
+			halfPi.Neg(halfPi)
+		}
+		return halfPi, nil
+	}
+	wp := prec + 16
+	denom := new(big.Float).SetPrec(wp).Mul(x, x)
+	denom.Sub(one, denom)
+	denom.Sqrt(denom)
+	ratio := new(big.Float).SetPrec(wp).Quo(x, denom)
+	return new(big.Float).SetPrec(prec).Set(bigAtan(ratio, wp)), nil
+}
+
+// bigSinCos returns sin(x) and cos(x). The argument is reduced to [-pi, pi]
+// with a multiple of 2pi computed to enough precision for its magnitude,
+// then both Taylor series are summed.
+func bigSinCos(x *big.Float, prec uint) (*big.Float, *big.Float) {
+	wp := prec + 16 + uint(max(x.MantExp(nil), 0))
+	twoPi := new(big.Float).SetPrec(wp).SetMantExp(bigPi(wp), 1)
+
+	// r = x - 2pi * round(x / 2pi)
+	q := new(big.Float).SetPrec(wp).Quo(x, twoPi)
+	q.Add(q, big.NewFloat(math.Copysign(0.5, float64(q.Sign()))))
+	k, _ := q.Int(nil)
+	r := new(big.Float).SetPrec(wp).SetInt(k)
+	r.Mul(r, twoPi)
+	r.Sub(x, r)
+
+	rSquared := new(big.Float).SetPrec(wp).Mul(r, r)
+	divisor := new(big.Float).SetPrec(wp)
+
+	sin := new(big.Float).SetPrec(wp).Set(r)
+	term := new(big.Float).SetPrec(wp).Set(r)
+	for i := int64(1); ; i++ {
+		term.Mul(term, rSquared)
+		term.Quo(term, divisor.SetInt64(-(2*i)*(2*i+1)))
+		if negligible(term, 0, wp) {
+			break
+		}
+		sin.Add(sin, term)
+	}
+
+	cos := new(big.Float).SetPrec(wp).SetInt64(1)
+	term.SetInt64(1)
+	for i := int64(1); ; i++ {
+		term.Mul(term, rSquared)
+		term.Quo(term, divisor.SetInt64(-(2*i-1)*(2*i)))
+		if negligible(term, 0, wp) {
+			break
+		}
+		cos.Add(cos, term)
+	}
+
+	return new(big.Float).SetPrec(prec).Set(sin), new(big.Float).SetPrec(prec).Set(cos)
+}
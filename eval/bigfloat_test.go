@@ -0,0 +1,180 @@
+package eval
+
+import (
+	"basic-arithmetic-parser/ast"
+	"basic-arithmetic-parser/diagnostic"
+	"errors"
+	"math/big"
+	"testing"
+)
+
+func TestEvalBigFloat(t *testing.T) {
+	// 200 bits is a little over 60 significant digits; results are compared
+	// to 50 digits
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"1 / 3", "0.33333333333333333333333333333333333333333333333333"},
+		{"0.1 + 0.2", "0.3"},
+		{"2 ^ 100", "1.2676506002282294014967032053760000000000000000000e+30"},
+		{"2 ^ -3", "0.125"},
+		{"2 ^ 0.5", "1.4142135623730950488016887242096980785696718753769"},
+		{"sqrt(2)", "1.4142135623730950488016887242096980785696718753769"},
+		{"pi", "3.1415926535897932384626433832795028841971693993751"},
+		{"e", "2.7182818284590452353602874713526624977572470937000"},
+		{"exp(1)", "2.7182818284590452353602874713526624977572470937000"},
+		{"ln(2)", "0.69314718055994530941723212145817656807550013436026"},
+		{"ln(10)", "2.3025850929940456840179914546843642076011014886288"},
+		{"log10(1000)", "3"},
+		{"log2(8)", "3"},
+		{"sin(1)", "0.84147098480789650665250232163029899962256306079837"},
+		{"cos(1)", "0.54030230586813971740093660744297660373231042061792"},
+		{"tan(1)", "1.5574077246549022305069748074583601730872507723815"},
+		{"atan(1) * 4", "3.1415926535897932384626433832795028841971693993751"},
+		{"asin(1)", "1.5707963267948966192313216916397514420985846996876"},
+		{"acos(0.5)", "1.0471975511965977461542144610931676280657231331250"},
+		{"abs(-2.5)", "2.5"},
+		// too small to print in decimal, so flushed to zero
+		{"2 ^ -1000000000", "0"},
+		{"exp(-100000000)", "0"},
+		{"-sqrt(4)", "-2"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			program := mustParse(t, tt.input)
+
+			result, err := EvalBigFloat(program, 200, big.ToNearestEven)
+			if err != nil {
+				t.Fatalf("EvalBigFloat() returned an error: %v", err)
+			}
+			expected, _, _ := big.ParseFloat(tt.expected, 10, 200, big.ToNearestEven)
+			if got := result.Text('g', 50); got != expected.Text('g', 50) {
+				t.Errorf("Expected %s, but got %s", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestEvalBigFloatRounding(t *testing.T) {
+	// with 4 bits of mantissa 1/3 lies between 0.3125 and 0.34375
+	tests := []struct {
+		mode     big.RoundingMode
+		input    string
+		expected string
+	}{
+		{big.ToNearestEven, "1 / 3", "0.34375"},
+		{big.ToZero, "1 / 3", "0.3125"},
+		{big.ToNegativeInf, "-1 / 3", "-0.34375"},
+		{big.ToPositiveInf, "-1 / 3", "-0.3125"},
+		{big.AwayFromZero, "1 / 3", "0.34375"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.mode.String(), func(t *testing.T) {
+			program := mustParse(t, tt.input)
+
+			result, err := EvalBigFloat(program, 4, tt.mode)
+			if err != nil {
+				t.Fatalf("EvalBigFloat() returned an error: %v", err)
+			}
+			if got := result.Text('g', 10); got != tt.expected {
+				t.Errorf("Expected %s, but got %s", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestEvalBigFloatErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"1 / (0.5 - 0.5)", "division by zero"},
+		{"0 ^ -1", "division by zero"},
+		{"(-8) ^ 0.5", "fractional power of a negative number"},
+		{"sqrt(-1)", "sqrt: square root of a negative number"},
+		{"ln(0)", "ln: logarithm of a non-positive number"},
+		{"asin(2)", "asin: argument must be between -1 and 1"},
+		{"exp(2 ^ 40)", "exp: argument is too large"},
+		{"2 ^ 1000000000", "result is too large"},
+		{"2.5 ^ 100000000.5", "result is too large"},
+		{"exp(100000000)", "result is too large"},
+		{"sqrt(1, 2)", "sqrt expects 1 argument, got 2"},
+		{"floor(2.5)", "function floor is not supported in arbitrary-precision mode"},
+		{"7 % 2", "operator % is not supported in arbitrary-precision mode"},
+		{"x + 1", "variables are not supported in arbitrary-precision mode"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			program := mustParse(t, tt.input)
+
+			_, err := EvalBigFloat(program, 100, big.ToNearestEven)
+			var d diagnostic.Diagnostic
+			if !errors.As(err, &d) {
+				t.Fatalf("Expected a diagnostic.Diagnostic, got %T (%v)", err, err)
+			}
+			if d.Message != tt.expected {
+				t.Errorf("Expected error %q, but got %q", tt.expected, d.Message)
+			}
+		})
+	}
+}
+
+func TestEvalBigFloatInvalidPrecision(t *testing.T) {
+	if _, err := EvalBigFloat(&ast.NumberNode{Value: 1}, 0, big.ToNearestEven); err == nil {
+		t.Error("Expected an error for a precision of 0 bits")
+	}
+}
+
+func TestDigitsPrecision(t *testing.T) {
+	// rounding ln(2) to the bits that just hold 50 digits, and then to 50
+	// digits, would end in ...13436025
+	program := mustParse(t, "ln(2)")
+	result, err := EvalBigFloat(program, DigitsPrecision(50), big.ToNearestEven)
+	if err != nil {
+		t.Fatalf("EvalBigFloat() returned an error: %v", err)
+	}
+	expected := "0.69314718055994530941723212145817656807550013436026"
+	if got := result.Text('g', 50); got != expected {
+		t.Errorf("Expected %s, but got %s", expected, got)
+	}
+}
+
+func TestFormatBigFloat(t *testing.T) {
+	tests := []struct {
+		mode     big.RoundingMode
+		input    string
+		digits   uint
+		expected string
+	}{
+		{big.ToNearestEven, "2 / 3", 5, "0.66667"},
+		{big.ToZero, "2 / 3", 5, "0.66666"},
+		{big.AwayFromZero, "2 / 3", 5, "0.66667"},
+		{big.ToNegativeInf, "-2 / 3", 5, "-0.66667"},
+		{big.ToPositiveInf, "-2 / 3", 5, "-0.66666"},
+		{big.ToNearestAway, "0.125", 2, "0.13"},
+		{big.ToNearestEven, "0.125", 2, "0.12"},
+		// rounding up can carry into another digit
+		{big.AwayFromZero, "9.99", 2, "10"},
+		{big.ToZero, "2 ^ 100", 5, "1.2676e+30"},
+		{big.AwayFromZero, "1 / 3000", 3, "0.000334"},
+		{big.ToZero, "0", 5, "0"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.mode.String()+" "+tt.input, func(t *testing.T) {
+			program := mustParse(t, tt.input)
+
+			result, err := EvalBigFloat(program, DigitsPrecision(tt.digits), tt.mode)
+			if err != nil {
+				t.Fatalf("EvalBigFloat() returned an error: %v", err)
+			}
+			if got := FormatBigFloat(result, tt.digits, tt.mode); got != tt.expected {
+				t.Errorf("Expected %s, but got %s", tt.expected, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,95 @@
+package eval
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+var errNegativeSqrt = errors.New("square root of a negative number")
+var errNonPositiveLog = errors.New("logarithm of a non-positive number")
+var errInverseTrigDomain = errors.New("argument must be between -1 and 1")
+
+// checked wraps a one-argument math function that is only defined where
+// valid(x) holds, returning domainErr elsewhere
+func checked(f func(float64) float64, valid func(float64) bool, domainErr error) func(float64) (float64, error) {
+	return func(x float64) (float64, error) {
+		if !valid(x) {
+			return 0, domainErr
+		}
+		return f(x), nil
+	}
+}
+
+// fold wraps a variadic function by folding f over its arguments
+func fold(f func(float64, float64) float64) func(...float64) (float64, error) {
+	return func(args ...float64) (float64, error) {
+		result := args[0]
+		for _, arg := range args[1:] {
+			result = f(result, arg)
+		}
+		return result, nil
+	}
+}
+
+func nonNegative(x float64) bool { return x >= 0 }
+func positive(x float64) bool    { return x > 0 }
+func unitRange(x float64) bool   { return x >= -1 && x <= 1 }
+
+// registerBuiltins adds the built-in math functions to r
+func registerBuiltins(r *Registry) {
+	functions := map[string]any{
+		"sqrt":  checked(math.Sqrt, nonNegative, errNegativeSqrt),
+		"abs":   math.Abs,
+		"sin":   math.Sin,
+		"cos":   math.Cos,
+		"tan":   math.Tan,
+		"asin":  checked(math.Asin, unitRange, errInverseTrigDomain),
+		"acos":  checked(math.Acos, unitRange, errInverseTrigDomain),
+		"atan":  math.Atan,
+		"atan2": math.Atan2,
+		"exp":   math.Exp,
+		"ln":    checked(math.Log, positive, errNonPositiveLog),
+		"log10": checked(math.Log10, positive, errNonPositiveLog),
+		"log2":  checked(math.Log2, positive, errNonPositiveLog),
+		"floor": math.Floor,
+		"ceil":  math.Ceil,
+		"round": math.Round,
+		"hypot": math.Hypot,
+	}
+	for name, fn := range functions {
+		if err := r.RegisterFunc(name, fn); err != nil {
+			panic(err)
+		}
+	}
+	for name, fn := range map[string]func(...float64) (float64, error){
+		"min": fold(math.Min),
+		"max": fold(math.Max),
+	} {
+		if err := r.RegisterVariadic(name, 1, -1, fn); err != nil {
+			panic(err)
+		}
+	}
+}
+
+// checkArity returns an error if a function taking between min and max
+// arguments (max -1 for no limit) is called with count of them
+func checkArity(name string, min, max, count int) error {
+	if count >= min && (max < 0 || count <= max) {
+		return nil
+	}
+	var expected string
+	switch {
+	case min == max:
+		expected = fmt.Sprintf("%d", min)
+	case max < 0:
+		expected = fmt.Sprintf("at least %d", min)
+	default:
+		expected = fmt.Sprintf("%d to %d", min, max)
+	}
+	plural := "s"
+	if max == 1 || max < 0 && min == 1 {
+		plural = ""
+	}
+	return fmt.Errorf("%s expects %s argument%s, got %d", name, expected, plural, count)
+}
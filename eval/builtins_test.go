@@ -0,0 +1,108 @@
+package eval
+
+import (
+	"basic-arithmetic-parser/diagnostic"
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestBuiltins(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected float64
+	}{
+		{"sqrt(16)", 4},
+		{"abs(-3.5)", 3.5},
+		{"sin(0)", 0},
+		{"cos(0)", 1},
+		{"tan(0)", 0},
+		{"asin(1)", math.Pi / 2},
+		{"acos(1)", 0},
+		{"atan(1)", math.Pi / 4},
+		{"atan2(1, -1)", 3 * math.Pi / 4},
+		{"exp(0)", 1},
+		{"ln(exp(2))", 2},
+		{"log10(1000)", 3},
+		{"log2(8)", 3},
+		{"floor(-2.5)", -3},
+		{"ceil(-2.5)", -2},
+		{"round(2.5)", 3},
+		{"min(3, 1, 2)", 1},
+		{"max(3)", 3},
+		{"max(3, 1 + 4, 2)", 5},
+		{"hypot(3, 4)", 5},
+		{"2 * sqrt(abs(-9)) ^ 2", 18},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			program := mustParse(t, tt.input)
+
+			result, err := Eval(program)
+			if err != nil {
+				t.Fatalf("Eval() returned an error: %v", err)
+			}
+			if result.Kind != NumberKind || math.Abs(result.Number-tt.expected) > 1e-12 {
+				t.Errorf("Expected %g, but got %v", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestBuiltinErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"sqrt(-1)", "sqrt: square root of a negative number"},
+		{"ln(0)", "ln: logarithm of a non-positive number"},
+		{"log10(-5)", "log10: logarithm of a non-positive number"},
+		{"log2(0)", "log2: logarithm of a non-positive number"},
+		{"asin(2)", "asin: argument must be between -1 and 1"},
+		{"acos(-1.5)", "acos: argument must be between -1 and 1"},
+		{"sqrt()", "sqrt expects 1 argument, got 0"},
+		{"atan2(1)", "atan2 expects 2 arguments, got 1"},
+		{"min()", "min expects at least 1 argument, got 0"},
+		{"nope(1)", "unknown function: nope"},
+		{"sqrt(1 / 0)", "division by zero"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			program := mustParse(t, tt.input)
+
+			_, err := Eval(program)
+			var d diagnostic.Diagnostic
+			if !errors.As(err, &d) {
+				t.Fatalf("Expected a diagnostic.Diagnostic, got %T (%v)", err, err)
+			}
+			if d.Message != tt.expected {
+				t.Errorf("Expected error %q, but got %q", tt.expected, d.Message)
+			}
+		})
+	}
+}
+
+func TestCheckArity(t *testing.T) {
+	tests := []struct {
+		min, max, count int
+		expected        string
+	}{
+		{1, 1, 1, ""},
+		{2, 2, 3, "f expects 2 arguments, got 3"},
+		{1, 3, 4, "f expects 1 to 3 arguments, got 4"},
+		{1, -1, 10, ""},
+	}
+
+	for _, tt := range tests {
+		err := checkArity("f", tt.min, tt.max, tt.count)
+		got := ""
+		if err != nil {
+			got = err.Error()
+		}
+		if got != tt.expected {
+			t.Errorf("checkArity(%d, %d, %d) wrong. expected=%q, got=%q", tt.min, tt.max, tt.count, tt.expected, got)
+		}
+	}
+}
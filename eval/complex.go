@@ -0,0 +1,169 @@
+package eval
+
+import (
+	"basic-arithmetic-parser/ast"
+	"basic-arithmetic-parser/token"
+	"errors"
+	"fmt"
+	"math"
+	"math/cmplx"
+)
+
+// complexFunctions are the functions available in complex mode
+var complexFunctions = map[string]func(z complex128) (complex128, error){
+	"re":   func(z complex128) (complex128, error) { return complex(real(z), 0), nil },
+	"im":   func(z complex128) (complex128, error) { return complex(imag(z), 0), nil },
+	"conj": func(z complex128) (complex128, error) { return cmplx.Conj(z), nil },
+	"arg":  func(z complex128) (complex128, error) { return complex(cmplx.Phase(z), 0), nil },
+	"abs":  func(z complex128) (complex128, error) { return complex(cmplx.Abs(z), 0), nil },
+	"sqrt": func(z complex128) (complex128, error) { return cmplx.Sqrt(z), nil },
+	"exp":  func(z complex128) (complex128, error) { return cmplx.Exp(z), nil },
+	"ln": func(z complex128) (complex128, error) {
+		if z == 0 {
+			return 0, errors.New("logarithm of zero")
+		}
+		return cmplx.Log(z), nil
+	},
+	"sin": func(z complex128) (complex128, error) { return cmplx.Sin(z), nil },
+	"cos": func(z complex128) (complex128, error) { return cmplx.Cos(z), nil },
+	"tan": func(z complex128) (complex128, error) { return cmplx.Tan(z), nil },
+}
+
+// EvalComplex evaluates the given AST node with complex128 arithmetic, so
+// imaginary literals such as 2i can be used and sqrt(-4) is 2i. It supports
+// + - * / ^, unary signs, the constants pi and e, and the functions re, im,
+// conj, arg, abs, sqrt, exp, ln, sin, cos and tan. Other operators,
+// variables and functions are reported as errors.
+func EvalComplex(node ast.Node) (complex128, error) {
+	switch n := node.(type) {
+	case *ast.NumberNode:
+		if n.Imaginary {
+			return complex(0, n.Value), nil
+		}
+		return complex(n.Value, 0), nil
+	case *ast.IdentNode:
+		switch n.Name {
+		case "pi":
+			return complex(math.Pi, 0), nil
+		case "e":
+			return complex(math.E, 0), nil
+		default:
+			return 0, newError(n, "variables are not supported in complex mode")
+		}
+	case *ast.BinaryOpNode:
+		leftVal, err := EvalComplex(n.Left)
+		if err != nil {
+			return 0, err
+		}
+		rightVal, err := EvalComplex(n.Right)
+		if err != nil {
+			return 0, err
+		}
+
+		switch n.Op.Type {
+		case token.PLUS:
+			return leftVal + rightVal, nil
+		case token.MINUS:
+			return leftVal - rightVal, nil
+		case token.MULTIPLY:
+			return leftVal * rightVal, nil
+		case token.DIVIDE:
+			if rightVal == 0 {
+				return 0, newError(n, "division by zero")
+			}
+			return leftVal / rightVal, nil
+		case token.POWER:
+			if leftVal == 0 && real(rightVal) < 0 {
+				return 0, newError(n, "division by zero")
+			}
+			return complexPow(leftVal, rightVal), nil
+		default:
+			return 0, newError(n, fmt.Sprintf("operator %s is not supported in complex mode", n.Op.Value))
+		}
+	case *ast.UnaryOpNode:
+		exprVal, err := EvalComplex(n.Expr)
+		if err != nil {
+			return 0, err
+		}
+
+		switch n.Op.Type {
+		case token.PLUS: // Unary plus (identity)
+			return exprVal, nil
+		case token.MINUS: // Unary minus (negation)
+			// subtracting from zero rather than negating keeps a zero
+			// imaginary part positive, so sqrt(-4) is 2i rather than -2i
+			return 0 - exprVal, nil
+		default:
+			return 0, newError(n, fmt.Sprintf("unknown unary operator: %s", n.Op.Value))
+		}
+	case *ast.CallNode:
+		f, ok := complexFunctions[n.Name.Name]
+		if !ok {
+			return 0, newError(n.Name, fmt.Sprintf("function %s is not supported in complex mode", n.Name.Name))
+		}
+		if err := checkArity(n.Name.Name, 1, 1, len(n.Args)); err != nil {
+			return 0, newError(n, err.Error())
+		}
+		arg, err := EvalComplex(n.Args[0])
+		if err != nil {
+			return 0, err
+		}
+		result, err := f(arg)
+		if err != nil {
+			return 0, newError(n, fmt.Sprintf("%s: %v", n.Name.Name, err))
+		}
+		return result, nil
+	case *ast.AssignNode:
+		return 0, newError(n, "variables are not supported in complex mode")
+	case *ast.ListNode, *ast.IndexNode, *ast.SliceNode:
+		return 0, listError(n)
+	case *ast.QuantityNode, *ast.ConvertNode:
+		return 0, unitsError(n)
+	case *ast.DateNode, *ast.DurationNode:
+		return 0, dateError(n)
+	case *ast.PercentNode:
+		return 0, percentError(n)
+	case *ast.BoolNode, *ast.ConditionalNode:
+		return 0, boolError(n)
+	case *ast.FunctionDefNode, *ast.LambdaNode, *ast.ApplyNode:
+		return 0, functionDefError(n)
+	case *ast.ErrorNode:
+		return 0, newError(n, "cannot evaluate an expression with syntax errors")
+	default:
+		return 0, fmt.Errorf("unknown node type: %T", node)
+	}
+}
+
+// complexPow returns base^exponent. Small integer exponents are computed by
+// repeated multiplication, which is exact where cmplx.Pow's polar form is
+// not: (1+1i)^2 is 2i rather than 1.2e-16+2i.
+func complexPow(base, exponent complex128) complex128 {
+	n := real(exponent)
+	if imag(exponent) != 0 || n != math.Trunc(n) || math.Abs(n) > 64 {
+		return cmplx.Pow(base, exponent)
+	}
+	result := complex(1, 0)
+	for range int(math.Abs(n)) {
+		result *= base
+	}
+	if n < 0 {
+		return 1 / result
+	}
+	return result
+}
+
+// FormatComplex formats z as a+bi, leaving out a part that is zero: 3-4i,
+// 2.5 or 1i. The imaginary unit is written 1i so that the result can be read
+// back as input.
+func FormatComplex(z complex128) string {
+	re, im := real(z), imag(z)
+	switch {
+	case im == 0:
+		return fmt.Sprintf("%g", re)
+	case re == 0:
+		return fmt.Sprintf("%gi", im)
+	default:
+		// %+g always writes the sign of the imaginary part
+		return fmt.Sprintf("%g%+gi", re, im)
+	}
+}
@@ -0,0 +1,99 @@
+package eval
+
+import (
+	"basic-arithmetic-parser/diagnostic"
+	"errors"
+	"testing"
+)
+
+func TestEvalComplex(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"3 + 4i", "3+4i"},
+		{"3 - 4j", "3-4i"},
+		{"2.5i", "2.5i"},
+		{"1i * 1i", "-1"},
+		{"(1 + 2i) * (3 - 1i)", "5+5i"},
+		{"(1 + 2i) / (1 - 1i)", "-0.5+1.5i"},
+		{"(1 + 1i) ^ 2", "2i"},
+		{"1i ^ -1", "-1i"},
+		{"(-1) ^ 0.5", "6.123233995736757e-17+1i"},
+		{"-(2 - 3i)", "-2+3i"},
+		{"sqrt(-4)", "2i"},
+		{"abs(3 + 4i)", "5"},
+		{"re(3 + 4i)", "3"},
+		{"im(3 + 4i)", "4"},
+		{"conj(3 + 4i)", "3-4i"},
+		{"arg(1i)", "1.5707963267948966"},
+		{"arg(-1)", "3.141592653589793"},
+		{"exp(1i * pi) + 1", "1.2246467991473515e-16i"},
+		{"ln(-1)", "3.141592653589793i"},
+		{"7", "7"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			program := mustParse(t, tt.input)
+
+			result, err := EvalComplex(program)
+			if err != nil {
+				t.Fatalf("EvalComplex() returned an error: %v", err)
+			}
+			if got := FormatComplex(result); got != tt.expected {
+				t.Errorf("Expected %s, but got %s", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestEvalComplexErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"1 / (1i - 1i)", "division by zero"},
+		{"0 ^ -1", "division by zero"},
+		{"ln(0)", "ln: logarithm of zero"},
+		{"floor(1i)", "function floor is not supported in complex mode"},
+		{"re(1, 2)", "re expects 1 argument, got 2"},
+		{"7 % 2", "operator % is not supported in complex mode"},
+		{"10%", "percentages are only supported in float, rational and decimal modes"},
+		{"z + 1", "variables are not supported in complex mode"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			program := mustParse(t, tt.input)
+
+			_, err := EvalComplex(program)
+			var d diagnostic.Diagnostic
+			if !errors.As(err, &d) {
+				t.Fatalf("Expected a diagnostic.Diagnostic, got %T (%v)", err, err)
+			}
+			if d.Message != tt.expected {
+				t.Errorf("Expected error %q, but got %q", tt.expected, d.Message)
+			}
+		})
+	}
+}
+
+func TestFormatComplex(t *testing.T) {
+	tests := []struct {
+		value    complex128
+		expected string
+	}{
+		{complex(1, 1), "1+1i"},
+		{complex(1, -1), "1-1i"},
+		{complex(0, -1), "-1i"},
+		{complex(-2.5, 0), "-2.5"},
+		{0, "0"},
+	}
+
+	for _, tt := range tests {
+		if got := FormatComplex(tt.value); got != tt.expected {
+			t.Errorf("FormatComplex(%v) wrong. expected=%q, got=%q", tt.value, tt.expected, got)
+		}
+	}
+}
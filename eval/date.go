@@ -0,0 +1,443 @@
+package eval
+
+import (
+	"basic-arithmetic-parser/ast"
+	"basic-arithmetic-parser/token"
+	"cmp"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DateKind says which field of a DateResult holds its value
+type DateKind int
+
+const (
+	DateNumber DateKind = iota
+	DateTime
+	DateDuration
+	DateBool
+)
+
+func (k DateKind) String() string {
+	switch k {
+	case DateTime:
+		return "date"
+	case DateDuration:
+		return "duration"
+	case DateBool:
+		return "bool"
+	default:
+		return "number"
+	}
+}
+
+// DateResult is the result of EvalDate: a number, an instant, a duration or
+// a bool
+type DateResult struct {
+	Kind     DateKind
+	Number   float64
+	Time     time.Time
+	Duration time.Duration
+	Bool     bool
+}
+
+// String formats r the way it would be written in an expression:
+// 2026-10-17, 2026-10-17T09:00Z, 4h30m, true or 42
+func (r DateResult) String() string {
+	switch r.Kind {
+	case DateTime:
+		return formatDateTime(r.Time)
+	case DateDuration:
+		return formatDuration(r.Duration)
+	case DateBool:
+		return strconv.FormatBool(r.Bool)
+	default:
+		return fmt.Sprintf("%g", r.Number)
+	}
+}
+
+// formatDateTime leaves out the time of day at midnight UTC, and the
+// seconds when they are zero
+func formatDateTime(t time.Time) string {
+	_, offset := t.Zone()
+	switch {
+	case t.Hour() == 0 && t.Minute() == 0 && t.Second() == 0 && offset == 0:
+		return t.Format("2006-01-02")
+	case t.Second() == 0:
+		return t.Format("2006-01-02T15:04Z07:00")
+	default:
+		return t.Format("2006-01-02T15:04:05Z07:00")
+	}
+}
+
+// formatDuration writes d in days, hours, minutes and seconds, leaving out
+// the parts that are zero: 1d4h30m, -3d or 1.5s
+func formatDuration(d time.Duration) string {
+	if d == 0 {
+		return "0s"
+	}
+	var b strings.Builder
+	if d < 0 {
+		b.WriteString("-")
+		d = -d
+	}
+	for _, part := range []struct {
+		unit   time.Duration
+		suffix string
+	}{{24 * time.Hour, "d"}, {time.Hour, "h"}, {time.Minute, "m"}} {
+		if n := d / part.unit; n > 0 {
+			fmt.Fprintf(&b, "%d%s", n, part.suffix)
+			d -= n * part.unit
+		}
+	}
+	if d > 0 {
+		fmt.Fprintf(&b, "%gs", d.Seconds())
+	}
+	return b.String()
+}
+
+// dateEvaluator evaluates with dates and durations, reading the current time
+// from clock
+type dateEvaluator struct {
+	clock func() time.Time
+}
+
+// dateFunction is a function available in date mode
+type dateFunction struct {
+	// params are the kinds of the arguments
+	params []DateKind
+	fn     func(e *dateEvaluator, args []DateResult) DateResult
+}
+
+// dateNumber returns x as a DateResult
+func dateNumber(x float64) DateResult {
+	return DateResult{Kind: DateNumber, Number: x}
+}
+
+// dateBool returns b as a DateResult
+func dateBool(b bool) DateResult {
+	return DateResult{Kind: DateBool, Bool: b}
+}
+
+// compareFunction returns a function of two dates that is true where
+// less(a, b) holds
+func compareFunction(less func(a, b time.Time) bool) dateFunction {
+	return dateFunction{
+		params: []DateKind{DateTime, DateTime},
+		fn: func(e *dateEvaluator, args []DateResult) DateResult {
+			return dateBool(less(args[0].Time, args[1].Time))
+		},
+	}
+}
+
+// dateFunctions are the functions available in date mode
+var dateFunctions = map[string]dateFunction{
+	"now": {fn: func(e *dateEvaluator, args []DateResult) DateResult {
+		return DateResult{Kind: DateTime, Time: e.clock()}
+	}},
+	"today": {fn: func(e *dateEvaluator, args []DateResult) DateResult {
+		return DateResult{Kind: DateTime, Time: e.clock().UTC().Truncate(24 * time.Hour)}
+	}},
+	// the ISO 8601 day of the week, from 1 for Monday to 7 for Sunday
+	"weekday": {params: []DateKind{DateTime}, fn: func(e *dateEvaluator, args []DateResult) DateResult {
+		day := args[0].Time.Weekday()
+		if day == time.Sunday {
+			return dateNumber(7)
+		}
+		return dateNumber(float64(day))
+	}},
+	"year": {params: []DateKind{DateTime}, fn: func(e *dateEvaluator, args []DateResult) DateResult {
+		return dateNumber(float64(args[0].Time.Year()))
+	}},
+	"month": {params: []DateKind{DateTime}, fn: func(e *dateEvaluator, args []DateResult) DateResult {
+		return dateNumber(float64(args[0].Time.Month()))
+	}},
+	"day": {params: []DateKind{DateTime}, fn: func(e *dateEvaluator, args []DateResult) DateResult {
+		return dateNumber(float64(args[0].Time.Day()))
+	}},
+	"days": {params: []DateKind{DateDuration}, fn: func(e *dateEvaluator, args []DateResult) DateResult {
+		return dateNumber(args[0].Duration.Hours() / 24)
+	}},
+	"hours": {params: []DateKind{DateDuration}, fn: func(e *dateEvaluator, args []DateResult) DateResult {
+		return dateNumber(args[0].Duration.Hours())
+	}},
+	"minutes": {params: []DateKind{DateDuration}, fn: func(e *dateEvaluator, args []DateResult) DateResult {
+		return dateNumber(args[0].Duration.Minutes())
+	}},
+	"seconds": {params: []DateKind{DateDuration}, fn: func(e *dateEvaluator, args []DateResult) DateResult {
+		return dateNumber(args[0].Duration.Seconds())
+	}},
+	"before": compareFunction(time.Time.Before),
+	"after":  compareFunction(time.Time.After),
+}
+
+// EvalDate evaluates the given AST node with dates and durations, so that
+// deadlines can be computed as 2026-10-17T09:00Z + 4h30m. A date minus a
+// date is a duration, durations can be added to and subtracted from dates
+// and each other, and multiplied or divided by numbers; a duration divided
+// by a duration is a number. Two dates, two durations or two numbers can be
+// compared with == != < <= > >=, giving a bool, and bools combine with
+// && || ! and conditionals as in float mode. Times without a UTC offset are
+// in UTC, and a day is always 24 hours. The functions are now() and
+// today(), which read clock (time.Now if it is nil); weekday, year, month
+// and day of a date; days, hours, minutes and seconds in a duration; and
+// before(a, b) and after(a, b), which compare two dates. Variables are
+// reported as errors.
+func EvalDate(node ast.Node, clock func() time.Time) (DateResult, error) {
+	if clock == nil {
+		clock = time.Now
+	}
+	e := &dateEvaluator{clock: clock}
+	return e.eval(node)
+}
+
+func (e *dateEvaluator) eval(node ast.Node) (DateResult, error) {
+	switch n := node.(type) {
+	case *ast.NumberNode:
+		if n.Imaginary {
+			return DateResult{}, imaginaryError(n)
+		}
+		return dateNumber(n.Value), nil
+	case *ast.DateNode:
+		return DateResult{Kind: DateTime, Time: n.Value}, nil
+	case *ast.DurationNode:
+		return DateResult{Kind: DateDuration, Duration: n.Value}, nil
+	case *ast.BoolNode:
+		return dateBool(n.Value), nil
+	case *ast.ConditionalNode:
+		condVal, err := e.eval(n.Cond)
+		if err != nil {
+			return DateResult{}, err
+		}
+		if condVal.Kind != DateBool {
+			return DateResult{}, newError(n.Cond, fmt.Sprintf("condition must be a bool, got a %s", condVal.Kind))
+		}
+		// only the branch that is taken is evaluated
+		if condVal.Bool {
+			return e.eval(n.Then)
+		}
+		return e.eval(n.Else)
+	case *ast.IdentNode:
+		if value, ok := defaultRegistry.Constant(n.Name); ok {
+			return dateNumber(value), nil
+		}
+		return DateResult{}, newError(n, "variables are not supported in date mode")
+	case *ast.BinaryOpNode:
+		leftVal, err := e.eval(n.Left)
+		if err != nil {
+			return DateResult{}, err
+		}
+		if n.Op.Type == token.AND || n.Op.Type == token.OR {
+			return e.evalLogical(n, leftVal)
+		}
+		rightVal, err := e.eval(n.Right)
+		if err != nil {
+			return DateResult{}, err
+		}
+		return dateBinaryOp(n, leftVal, rightVal)
+	case *ast.UnaryOpNode:
+		exprVal, err := e.eval(n.Expr)
+		if err != nil {
+			return DateResult{}, err
+		}
+
+		switch n.Op.Type {
+		case token.NOT:
+			if exprVal.Kind != DateBool {
+				return DateResult{}, newError(n, fmt.Sprintf("operator ! expects a bool, got a %s", exprVal.Kind))
+			}
+			return dateBool(!exprVal.Bool), nil
+		case token.PLUS: // Unary plus (identity)
+			if exprVal.Kind == DateBool {
+				return DateResult{}, newError(n, "operator + expects a number, got a bool")
+			}
+			return exprVal, nil
+		case token.MINUS: // Unary minus (negation)
+			switch exprVal.Kind {
+			case DateNumber:
+				return dateNumber(-exprVal.Number), nil
+			case DateDuration:
+				return DateResult{Kind: DateDuration, Duration: -exprVal.Duration}, nil
+			default:
+				return DateResult{}, newError(n, fmt.Sprintf("cannot negate a %s", exprVal.Kind))
+			}
+		default:
+			return DateResult{}, newError(n, fmt.Sprintf("unknown unary operator: %s", n.Op.Value))
+		}
+	case *ast.CallNode:
+		f, ok := dateFunctions[n.Name.Name]
+		if !ok {
+			return DateResult{}, newError(n.Name, fmt.Sprintf("function %s is not supported in date mode", n.Name.Name))
+		}
+		if err := checkArity(n.Name.Name, len(f.params), len(f.params), len(n.Args)); err != nil {
+			return DateResult{}, newError(n, err.Error())
+		}
+		args := make([]DateResult, len(n.Args))
+		for i, arg := range n.Args {
+			val, err := e.eval(arg)
+			if err != nil {
+				return DateResult{}, err
+			}
+			if val.Kind != f.params[i] {
+				return DateResult{}, newError(arg, fmt.Sprintf("%s expects a %s, got a %s", n.Name.Name, f.params[i], val.Kind))
+			}
+			args[i] = val
+		}
+		return f.fn(e, args), nil
+	case *ast.AssignNode:
+		return DateResult{}, newError(n, "variables are not supported in date mode")
+	case *ast.ListNode, *ast.IndexNode, *ast.SliceNode:
+		return DateResult{}, listError(n)
+	case *ast.QuantityNode, *ast.ConvertNode:
+		return DateResult{}, unitsError(n)
+	case *ast.PercentNode:
+		return DateResult{}, percentError(n)
+	case *ast.FunctionDefNode, *ast.LambdaNode, *ast.ApplyNode:
+		return DateResult{}, functionDefError(n)
+	case *ast.ErrorNode:
+		return DateResult{}, newError(n, "cannot evaluate an expression with syntax errors")
+	default:
+		return DateResult{}, fmt.Errorf("unknown node type: %T", node)
+	}
+}
+
+// evalLogical evaluates && and ||, whose left operand is leftVal, only
+// evaluating the right operand when it decides the result
+func (e *dateEvaluator) evalLogical(n *ast.BinaryOpNode, leftVal DateResult) (DateResult, error) {
+	if leftVal.Kind != DateBool {
+		return DateResult{}, newError(n.Left, fmt.Sprintf("operator %s expects a bool, got a %s", n.Op.Value, leftVal.Kind))
+	}
+	if leftVal.Bool == (n.Op.Type == token.OR) {
+		return leftVal, nil
+	}
+	rightVal, err := e.eval(n.Right)
+	if err != nil {
+		return DateResult{}, err
+	}
+	if rightVal.Kind != DateBool {
+		return DateResult{}, newError(n.Right, fmt.Sprintf("operator %s expects a bool, got a %s", n.Op.Value, rightVal.Kind))
+	}
+	return rightVal, nil
+}
+
+// dateBinaryOp applies a binary operator to numbers, dates and durations
+func dateBinaryOp(n *ast.BinaryOpNode, leftVal, rightVal DateResult) (DateResult, error) {
+	switch n.Op.Type {
+	case token.EQ, token.NOT_EQ, token.LT, token.LT_EQ, token.GT, token.GT_EQ:
+		return dateComparison(n, leftVal, rightVal)
+	}
+	if leftVal.Kind == DateNumber && rightVal.Kind == DateNumber {
+		value, err := floatBinaryOp(n, leftVal.Number, rightVal.Number)
+		if err != nil {
+			return DateResult{}, err
+		}
+		return dateNumber(value), nil
+	}
+
+	// the kinds of the operands, as a pair that can be switched on
+	type kinds struct{ left, right DateKind }
+	switch op, k := n.Op.Type, (kinds{leftVal.Kind, rightVal.Kind}); {
+	case op == token.PLUS && k == kinds{DateTime, DateDuration}:
+		return DateResult{Kind: DateTime, Time: leftVal.Time.Add(rightVal.Duration)}, nil
+	case op == token.PLUS && k == kinds{DateDuration, DateTime}:
+		return DateResult{Kind: DateTime, Time: rightVal.Time.Add(leftVal.Duration)}, nil
+	case op == token.PLUS && k == kinds{DateDuration, DateDuration}:
+		return addDurations(n, leftVal.Duration, rightVal.Duration)
+	case op == token.MINUS && k == kinds{DateTime, DateDuration}:
+		return DateResult{Kind: DateTime, Time: leftVal.Time.Add(-rightVal.Duration)}, nil
+	case op == token.MINUS && k == kinds{DateTime, DateTime}:
+		return DateResult{Kind: DateDuration, Duration: leftVal.Time.Sub(rightVal.Time)}, nil
+	case op == token.MINUS && k == kinds{DateDuration, DateDuration}:
+		return addDurations(n, leftVal.Duration, -rightVal.Duration)
+	case op == token.MULTIPLY && k == kinds{DateDuration, DateNumber}:
+		return scaledDuration(n, float64(leftVal.Duration)*rightVal.Number)
+	case op == token.MULTIPLY && k == kinds{DateNumber, DateDuration}:
+		return scaledDuration(n, leftVal.Number*float64(rightVal.Duration))
+	case op == token.DIVIDE && k == kinds{DateDuration, DateNumber}:
+		if rightVal.Number == 0 {
+			return DateResult{}, newError(n, "division by zero")
+		}
+		return scaledDuration(n, float64(leftVal.Duration)/rightVal.Number)
+	case op == token.DIVIDE && k == kinds{DateDuration, DateDuration}:
+		if rightVal.Duration == 0 {
+			return DateResult{}, newError(n, "division by zero")
+		}
+		return dateNumber(float64(leftVal.Duration) / float64(rightVal.Duration)), nil
+	}
+
+	verb, ok := operatorVerbs[n.Op.Type]
+	if !ok {
+		return DateResult{}, newError(n, fmt.Sprintf("operator %s is not supported for dates and durations", n.Op.Value))
+	}
+	return DateResult{}, newError(n, fmt.Sprintf("cannot %s %s and %s", verb, leftVal.Kind, rightVal.Kind))
+}
+
+// dateComparison compares two values of the same kind with the operator
+// of n. Dates, durations and numbers are ordered; bools can only be tested
+// for equality.
+func dateComparison(n *ast.BinaryOpNode, leftVal, rightVal DateResult) (DateResult, error) {
+	if leftVal.Kind != rightVal.Kind {
+		return DateResult{}, newError(n, fmt.Sprintf("cannot compare %s and %s", leftVal.Kind, rightVal.Kind))
+	}
+	switch leftVal.Kind {
+	case DateTime:
+		// dates at the same instant are equal whatever their offsets
+		return dateBool(compare(n.Op.Type, leftVal.Time.Compare(rightVal.Time), 0)), nil
+	case DateDuration:
+		return dateBool(compare(n.Op.Type, leftVal.Duration, rightVal.Duration)), nil
+	case DateBool:
+		if n.Op.Type != token.EQ && n.Op.Type != token.NOT_EQ {
+			return DateResult{}, newError(n, fmt.Sprintf("operator %s expects numbers, dates or durations, got bool and bool", n.Op.Value))
+		}
+		return dateBool(compare(n.Op.Type, boolRank(leftVal.Bool), boolRank(rightVal.Bool))), nil
+	default:
+		return dateBool(compare(n.Op.Type, leftVal.Number, rightVal.Number)), nil
+	}
+}
+
+// compare applies the comparison operator op to a and b
+func compare[T cmp.Ordered](op token.TokenType, a, b T) bool {
+	switch op {
+	case token.EQ:
+		return a == b
+	case token.NOT_EQ:
+		return a != b
+	case token.LT:
+		return a < b
+	case token.LT_EQ:
+		return a <= b
+	case token.GT:
+		return a > b
+	default:
+		return a >= b
+	}
+}
+
+// boolRank makes a bool something compare can take
+func boolRank(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// addDurations returns a+b, reporting an overflow as an error at n
+func addDurations(n *ast.BinaryOpNode, a, b time.Duration) (DateResult, error) {
+	sum := a + b
+	if (a > 0 && b > 0 && sum < 0) || (a < 0 && b < 0 && sum >= 0) {
+		return DateResult{}, newError(n, "duration out of range")
+	}
+	return DateResult{Kind: DateDuration, Duration: sum}, nil
+}
+
+// scaledDuration returns the duration of nanoseconds, which an arithmetic
+// operation at n produced as a float64, rounded to the nearest nanosecond
+func scaledDuration(n *ast.BinaryOpNode, nanoseconds float64) (DateResult, error) {
+	if math.IsNaN(nanoseconds) || math.Abs(nanoseconds) >= math.MaxInt64 {
+		return DateResult{}, newError(n, "duration out of range")
+	}
+	return DateResult{Kind: DateDuration, Duration: time.Duration(math.Round(nanoseconds))}, nil
+}
@@ -0,0 +1,150 @@
+package eval
+
+import (
+	"basic-arithmetic-parser/ast"
+	"basic-arithmetic-parser/diagnostic"
+	"basic-arithmetic-parser/lexer"
+	"basic-arithmetic-parser/parser"
+	"errors"
+	"testing"
+	"time"
+)
+
+// fixedClock is a clock for EvalDate that always reads 2026-10-17T15:04:05Z,
+// a Saturday
+func fixedClock() time.Time {
+	return time.Date(2026, 10, 17, 15, 4, 5, 0, time.UTC)
+}
+
+// mustParseDates parses input with dates and durations enabled, as in date
+// mode, failing the test if it has syntax errors
+func mustParseDates(t *testing.T, input string) ast.Node {
+	t.Helper()
+	l := lexer.New(input)
+	l.EnableDates()
+	program, err := parser.New(l).Parse()
+	if err != nil {
+		t.Fatalf("Parse(%q) returned an error: %v", input, err)
+	}
+	return program
+}
+
+func TestEvalDate(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"2026-10-17", "2026-10-17"},
+		{"2026-10-17T09:00Z + 4h30m", "2026-10-17T13:30Z"},
+		{"4h30m + 2026-10-17T09:00Z", "2026-10-17T13:30Z"},
+		{"2026-10-17T09:00+02:00 + 1h", "2026-10-17T10:00+02:00"},
+		{"2026-10-17T09:00 + 30s", "2026-10-17T09:00:30Z"},
+		{"2026-10-17 + 3d", "2026-10-20"},
+		{"2026-10-17 - 1w", "2026-10-10"},
+		{"2026-10-17 - 1s", "2026-10-16T23:59:59Z"},
+		{"2026-12-25 - 2026-10-17", "69d"},
+		{"2026-10-17T09:00Z - 2026-10-17T07:00+02:00", "4h"},
+		{"4h30m * 2", "9h"},
+		{"3 * 1d", "3d"},
+		{"1d / 4h", "6"},
+		{"1h / 8", "7m30s"},
+		{"1d - 36h", "-12h"},
+		{"-3d", "-3d"},
+		{"0s", "0s"},
+		{"1.5s", "1.5s"},
+		{"now()", "2026-10-17T15:04:05Z"},
+		{"today()", "2026-10-17"},
+		{"now() - 2026-10-17T09:00Z", "6h4m5s"},
+		{"weekday(2026-10-17)", "6"},
+		{"weekday(2026-10-18)", "7"},
+		{"weekday(today() + 2d)", "1"},
+		{"year(2026-10-17) + month(2026-10-17) + day(2026-10-17)", "2053"},
+		{"days(2026-12-25 - 2026-10-17)", "69"},
+		{"hours(4h30m)", "4.5"},
+		{"minutes(1h)", "60"},
+		{"seconds(1m)", "60"},
+		{"before(2026-10-17, now())", "true"},
+		{"after(2026-10-17, now())", "false"},
+		{"2026-10-17 < 2026-10-18", "true"},
+		{"2026-10-17 == 2026-10-17", "true"},
+		{"2026-10-17T02:00+02:00 == 2026-10-17", "true"},
+		{"2026-10-17 != today()", "false"},
+		{"now() >= 2026-10-17T15:00Z", "true"},
+		{"2026-10-17 + 1d > 2026-10-18", "false"},
+		{"4h30m <= 1d", "true"},
+		{"1w == 7d", "true"},
+		{"2 > 3", "false"},
+		{"true != false", "true"},
+		{"2026-10-17 < now() && !(1d > 2d)", "true"},
+		{"1d > 2d || 2026-10-17 > now()", "false"},
+		// the right operand is not evaluated when the left one decides
+		{"false && 1d / 0 > 1", "false"},
+		{"now() - 2026-10-17 < 16h ? 2026-10-17 + 1d : 2026-10-17", "2026-10-18"},
+		{"if 1d > 2d then 1 / 0 else 3h", "3h"},
+		{"2 * (3 + 4)", "14"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			program := mustParseDates(t, tt.input)
+
+			result, err := EvalDate(program, fixedClock)
+			if err != nil {
+				t.Fatalf("EvalDate() returned an error: %v", err)
+			}
+			if got := result.String(); got != tt.expected {
+				t.Errorf("Expected %s, but got %s", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestEvalDateErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"2026-10-17 + 2026-10-17", "cannot add date and date"},
+		{"1d - 2026-10-17", "cannot subtract duration and date"},
+		{"2026-10-17 + 1", "cannot add date and number"},
+		{"1d * 1d", "cannot multiply duration and duration"},
+		{"2 / 1d", "cannot divide number and duration"},
+		{"3d % 2", "operator % is not supported for dates and durations"},
+		{"-2026-10-17", "cannot negate a date"},
+		{"1d / 0", "division by zero"},
+		{"1d / 0s", "division by zero"},
+		{"1w * 100000", "duration out of range"},
+		{"weekday(3d)", "weekday expects a date, got a duration"},
+		{"hours(2026-10-17)", "hours expects a duration, got a date"},
+		{"now(1)", "now expects 0 arguments, got 1"},
+		{"sqrt(1d)", "function sqrt is not supported in date mode"},
+		{"deadline + 1d", "variables are not supported in date mode"},
+		{"1d to h", "units are only supported in units mode"},
+		{"2026-10-17 < 1d", "cannot compare date and duration"},
+		{"1d == 86400", "cannot compare duration and number"},
+		{"1 == true", "cannot compare number and bool"},
+		{"true < false", "operator < expects numbers, dates or durations, got bool and bool"},
+		{"true + 1d", "cannot add bool and duration"},
+		{"!2026-10-17", "operator ! expects a bool, got a date"},
+		{"-true", "cannot negate a bool"},
+		{"1d && true", "operator && expects a bool, got a duration"},
+		{"false || 0", "operator || expects a bool, got a number"},
+		{"1d ? 1 : 2", "condition must be a bool, got a duration"},
+		{"weekday(true)", "weekday expects a date, got a bool"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			program := mustParseDates(t, tt.input)
+
+			_, err := EvalDate(program, fixedClock)
+			var d diagnostic.Diagnostic
+			if !errors.As(err, &d) {
+				t.Fatalf("Expected a diagnostic.Diagnostic, got %T (%v)", err, err)
+			}
+			if d.Message != tt.expected {
+				t.Errorf("Expected error %q, but got %q", tt.expected, d.Message)
+			}
+		})
+	}
+}
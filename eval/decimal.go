@@ -0,0 +1,267 @@
+package eval
+
+import (
+	"basic-arithmetic-parser/ast"
+	"basic-arithmetic-parser/token"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// RoundingMode says how EvalDecimal rounds a result that has more decimal
+// places than the chosen scale
+type RoundingMode int
+
+const (
+	RoundHalfEven RoundingMode = iota // to nearest, ties to even (banker's rounding)
+	RoundHalfUp                       // to nearest, ties away from zero
+	RoundDown                         // towards zero
+	RoundUp                           // away from zero
+	RoundFloor                        // towards negative infinity
+	RoundCeiling                      // towards positive infinity
+)
+
+// bigRoundingModes maps the rounding modes to the big.Float ones that round
+// the same way
+var bigRoundingModes = map[RoundingMode]big.RoundingMode{
+	RoundHalfEven: big.ToNearestEven,
+	RoundHalfUp:   big.ToNearestAway,
+	RoundDown:     big.ToZero,
+	RoundUp:       big.AwayFromZero,
+	RoundFloor:    big.ToNegativeInf,
+	RoundCeiling:  big.ToPositiveInf,
+}
+
+// Decimal is the exact base-10 number Coef × 10^-Scale, so 19.99 is
+// {1999, 2}
+type Decimal struct {
+	Coef  *big.Int
+	Scale int
+}
+
+// String formats d with exactly Scale decimal places
+func (d Decimal) String() string {
+	digits := new(big.Int).Abs(d.Coef).String()
+	sign := ""
+	if d.Coef.Sign() < 0 {
+		sign = "-"
+	}
+	if d.Scale <= 0 {
+		return sign + digits + strings.Repeat("0", -d.Scale)
+	}
+	if len(digits) <= d.Scale {
+		digits = strings.Repeat("0", d.Scale-len(digits)+1) + digits
+	}
+	point := len(digits) - d.Scale
+	return sign + digits[:point] + "." + digits[point:]
+}
+
+// decimalEvaluator evaluates with Decimal values, dividing and rounding to
+// a fixed number of decimal places
+type decimalEvaluator struct {
+	scale int
+	mode  RoundingMode
+}
+
+// EvalDecimal evaluates the given AST node with decimal arithmetic, reading
+// number literals from their text so that 19.99 * 3 is exactly 59.97.
+// Addition, subtraction, multiplication, percentages and non-negative integer
+// powers are exact; division, negative powers and the final result are rounded to
+// scale decimal places using mode. Other operators, variables and function
+// calls are reported as errors.
+func EvalDecimal(node ast.Node, scale int, mode RoundingMode) (Decimal, error) {
+	if scale < 0 {
+		return Decimal{}, fmt.Errorf("invalid scale: %d", scale)
+	}
+	e := &decimalEvaluator{scale: scale, mode: mode}
+	result, err := e.eval(node)
+	if err != nil {
+		return Decimal{}, err
+	}
+	return e.round(result), nil
+}
+
+func (e *decimalEvaluator) eval(node ast.Node) (Decimal, error) {
+	switch n := node.(type) {
+	case *ast.NumberNode:
+		if n.Imaginary {
+			return Decimal{}, imaginaryError(n)
+		}
+		literal := literalText(n)
+		d, ok := parseDecimal(literal)
+		if !ok {
+			return Decimal{}, newError(n, fmt.Sprintf("invalid number: %s", literal))
+		}
+		return d, nil
+	case *ast.BinaryOpNode:
+		leftVal, err := e.eval(n.Left)
+		if err != nil {
+			return Decimal{}, err
+		}
+		rightVal, err := e.eval(n.Right)
+		if err != nil {
+			return Decimal{}, err
+		}
+		if isRelativePercent(n) {
+			rightVal = mulDecimal(rightVal, leftVal)
+		}
+
+		switch n.Op.Type {
+		case token.PLUS:
+			a, b := alignDecimals(leftVal, rightVal)
+			return Decimal{Coef: new(big.Int).Add(a.Coef, b.Coef), Scale: a.Scale}, nil
+		case token.MINUS:
+			a, b := alignDecimals(leftVal, rightVal)
+			return Decimal{Coef: new(big.Int).Sub(a.Coef, b.Coef), Scale: a.Scale}, nil
+		case token.MULTIPLY, token.OF:
+			return mulDecimal(leftVal, rightVal), nil
+		case token.DIVIDE:
+			if rightVal.Coef.Sign() == 0 {
+				return Decimal{}, newError(n, "division by zero")
+			}
+			return e.quo(leftVal, rightVal), nil
+		case token.POWER:
+			result, err := e.pow(leftVal, rightVal)
+			if err != nil {
+				return Decimal{}, newError(n, err.Error())
+			}
+			return result, nil
+		default:
+			return Decimal{}, newError(n, fmt.Sprintf("operator %s is not supported in decimal mode", n.Op.Value))
+		}
+	case *ast.UnaryOpNode:
+		exprVal, err := e.eval(n.Expr)
+		if err != nil {
+			return Decimal{}, err
+		}
+
+		switch n.Op.Type {
+		case token.PLUS: // Unary plus (identity)
+			return exprVal, nil
+		case token.MINUS: // Unary minus (negation)
+			return Decimal{Coef: new(big.Int).Neg(exprVal.Coef), Scale: exprVal.Scale}, nil
+		default:
+			return Decimal{}, newError(n, fmt.Sprintf("unknown unary operator: %s", n.Op.Value))
+		}
+	case *ast.PercentNode:
+		exprVal, err := e.eval(n.Expr)
+		if err != nil {
+			return Decimal{}, err
+		}
+		// dividing by 100 only moves the decimal point
+		return Decimal{Coef: exprVal.Coef, Scale: exprVal.Scale + 2}, nil
+	case *ast.IdentNode:
+		return Decimal{}, newError(n, "variables are not supported in decimal mode")
+	case *ast.AssignNode:
+		return Decimal{}, newError(n, "variables are not supported in decimal mode")
+	case *ast.CallNode:
+		return Decimal{}, newError(n, "function calls are not supported in decimal mode")
+	case *ast.ListNode, *ast.IndexNode, *ast.SliceNode:
+		return Decimal{}, listError(n)
+	case *ast.QuantityNode, *ast.ConvertNode:
+		return Decimal{}, unitsError(n)
+	case *ast.DateNode, *ast.DurationNode:
+		return Decimal{}, dateError(n)
+	case *ast.BoolNode, *ast.ConditionalNode:
+		return Decimal{}, boolError(n)
+	case *ast.FunctionDefNode, *ast.LambdaNode, *ast.ApplyNode:
+		return Decimal{}, functionDefError(n)
+	case *ast.ErrorNode:
+		return Decimal{}, newError(n, "cannot evaluate an expression with syntax errors")
+	default:
+		return Decimal{}, fmt.Errorf("unknown node type: %T", node)
+	}
+}
+
+// parseDecimal reads a literal made of digits and at most one decimal point
+func parseDecimal(literal string) (Decimal, bool) {
+	whole, fraction, _ := strings.Cut(literal, ".")
+	coef, ok := new(big.Int).SetString(whole+fraction, 10)
+	if !ok {
+		return Decimal{}, false
+	}
+	return Decimal{Coef: coef, Scale: len(fraction)}, true
+}
+
+// rescale returns d with a larger scale and the same value
+func rescale(d Decimal, scale int) Decimal {
+	if scale <= d.Scale {
+		return d
+	}
+	return Decimal{Coef: new(big.Int).Mul(d.Coef, pow10(scale-d.Scale)), Scale: scale}
+}
+
+// alignDecimals returns a and b rescaled to the larger of their scales
+func alignDecimals(a, b Decimal) (Decimal, Decimal) {
+	scale := max(a.Scale, b.Scale)
+	return rescale(a, scale), rescale(b, scale)
+}
+
+// mulDecimal returns the exact product a*b
+func mulDecimal(a, b Decimal) Decimal {
+	return Decimal{Coef: new(big.Int).Mul(a.Coef, b.Coef), Scale: a.Scale + b.Scale}
+}
+
+// round returns d rounded to the evaluator's scale
+func (e *decimalEvaluator) round(d Decimal) Decimal {
+	if d.Scale <= e.scale {
+		return rescale(d, e.scale)
+	}
+	return Decimal{Coef: divRound(d.Coef, pow10(d.Scale-e.scale), e.mode), Scale: e.scale}
+}
+
+// quo returns a/b rounded to the evaluator's scale
+func (e *decimalEvaluator) quo(a, b Decimal) Decimal {
+	// a/b × 10^scale = (a.Coef × 10^(scale + b.Scale - a.Scale)) / b.Coef
+	num := new(big.Int).Set(a.Coef)
+	den := new(big.Int).Set(b.Coef)
+	if shift := e.scale + b.Scale - a.Scale; shift >= 0 {
+		num.Mul(num, pow10(shift))
+	} else {
+		den.Mul(den, pow10(-shift))
+	}
+	return Decimal{Coef: divRound(num, den, e.mode), Scale: e.scale}
+}
+
+// pow returns base^exponent for an integer exponent: exactly when it is not
+// negative, and rounded to the evaluator's scale otherwise
+func (e *decimalEvaluator) pow(base, exponent Decimal) (Decimal, error) {
+	if !integral(exponent) {
+		return Decimal{}, errors.New("exponent must be an integer in decimal mode")
+	}
+	n := new(big.Int).Quo(exponent.Coef, pow10(exponent.Scale))
+	if n.CmpAbs(big.NewInt(maxRationalExponent)) > 0 {
+		return Decimal{}, errors.New("exponent is too large")
+	}
+	k := n.Int64()
+	if base.Coef.Sign() == 0 && k < 0 {
+		return Decimal{}, errors.New("division by zero")
+	}
+
+	abs := k
+	if abs < 0 {
+		abs = -abs
+	}
+	result := Decimal{
+		Coef:  new(big.Int).Exp(base.Coef, big.NewInt(abs), nil),
+		Scale: base.Scale * int(abs),
+	}
+	if k < 0 {
+		return e.quo(Decimal{Coef: big.NewInt(1)}, result), nil
+	}
+	return result, nil
+}
+
+// integral reports whether d is a whole number
+func integral(d Decimal) bool {
+	if d.Scale <= 0 {
+		return true
+	}
+	return new(big.Int).Rem(d.Coef, pow10(d.Scale)).Sign() == 0
+}
+
+// divRound returns num/den rounded to an integer using mode
+func divRound(num, den *big.Int, mode RoundingMode) *big.Int {
+	return quoRound(num, den, bigRoundingModes[mode])
+}
@@ -0,0 +1,129 @@
+package eval
+
+import (
+	"basic-arithmetic-parser/ast"
+	"basic-arithmetic-parser/diagnostic"
+	"errors"
+	"testing"
+)
+
+func TestEvalDecimal(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"19.99 * 3", "59.97"},
+		{"0.1 + 0.2", "0.30"},
+		{"100", "100.00"},
+		{"10 / 3", "3.33"},
+		{"10 / 3 * 3", "9.99"},
+		{"-2 / 3", "-0.67"},
+		{"1.005 * 1", "1.00"},
+		{"1.015 * 1", "1.02"},
+		{"1.1 ^ 2", "1.21"},
+		{"2 ^ -2", "0.25"},
+		{"-(0.05 - 0.1)", "0.05"},
+		{"0.001", "0.00"},
+		{"19.99 + 8.25%", "21.64"},
+		{"80 - 15%", "68.00"},
+		{"0.5% of 1000", "5.00"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			program := mustParse(t, tt.input)
+
+			result, err := EvalDecimal(program, 2, RoundHalfEven)
+			if err != nil {
+				t.Fatalf("EvalDecimal() returned an error: %v", err)
+			}
+			if got := result.String(); got != tt.expected {
+				t.Errorf("Expected %s, but got %s", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestEvalDecimalRounding(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected map[RoundingMode]string
+	}{
+		{"2.5 * 1", map[RoundingMode]string{
+			RoundHalfEven: "2", RoundHalfUp: "3", RoundDown: "2",
+			RoundUp: "3", RoundFloor: "2", RoundCeiling: "3",
+		}},
+		{"-2.5 * 1", map[RoundingMode]string{
+			RoundHalfEven: "-2", RoundHalfUp: "-3", RoundDown: "-2",
+			RoundUp: "-3", RoundFloor: "-3", RoundCeiling: "-2",
+		}},
+		{"3.5 * 1", map[RoundingMode]string{
+			RoundHalfEven: "4", RoundHalfUp: "4", RoundDown: "3",
+			RoundUp: "4", RoundFloor: "3", RoundCeiling: "4",
+		}},
+		{"7 / 3", map[RoundingMode]string{
+			RoundHalfEven: "2", RoundHalfUp: "2", RoundDown: "2",
+			RoundUp: "3", RoundFloor: "2", RoundCeiling: "3",
+		}},
+		{"-7 / 3", map[RoundingMode]string{
+			RoundHalfEven: "-2", RoundHalfUp: "-2", RoundDown: "-2",
+			RoundUp: "-3", RoundFloor: "-3", RoundCeiling: "-2",
+		}},
+	}
+
+	for _, tt := range tests {
+		program := mustParse(t, tt.input)
+
+		for mode, expected := range tt.expected {
+			result, err := EvalDecimal(program, 0, mode)
+			if err != nil {
+				t.Fatalf("EvalDecimal(%q) returned an error: %v", tt.input, err)
+			}
+			if got := result.String(); got != expected {
+				t.Errorf("EvalDecimal(%q) with mode %d wrong. expected=%s, got=%s", tt.input, mode, expected, got)
+			}
+		}
+	}
+}
+
+func TestEvalDecimalErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"1 / (0.5 - 0.50)", "division by zero"},
+		{"0 ^ -1", "division by zero"},
+		{"2 ^ 0.5", "exponent must be an integer in decimal mode"},
+		{"2 ^ 100000", "exponent is too large"},
+		{"7 % 2", "operator % is not supported in decimal mode"},
+		{"x + 1", "variables are not supported in decimal mode"},
+		{"round(2)", "function calls are not supported in decimal mode"},
+		{"2i", "imaginary numbers are only supported in complex mode"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			program := mustParse(t, tt.input)
+
+			_, err := EvalDecimal(program, 2, RoundHalfEven)
+			var d diagnostic.Diagnostic
+			if !errors.As(err, &d) {
+				t.Fatalf("Expected a diagnostic.Diagnostic, got %T (%v)", err, err)
+			}
+			if d.Message != tt.expected {
+				t.Errorf("Expected error %q, but got %q", tt.expected, d.Message)
+			}
+		})
+	}
+}
+
+func TestEvalDecimalWithoutLiteral(t *testing.T) {
+	// nodes built by hand have no literal text, so the float64 value is used
+	result, err := EvalDecimal(&ast.NumberNode{Value: 0.1}, 3, RoundHalfEven)
+	if err != nil {
+		t.Fatalf("EvalDecimal() returned an error: %v", err)
+	}
+	if got := result.String(); got != "0.100" {
+		t.Errorf("Expected 0.100, but got %s", got)
+	}
+}
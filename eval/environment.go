@@ -0,0 +1,46 @@
+package eval
+
+// Environment holds the variable bindings used during evaluation.
+// Embedders can implement it to expose their own values to expressions.
+type Environment interface {
+	// Get returns the value bound to name, and whether there is one
+	Get(name string) (Value, bool)
+	// Set binds name to value, replacing any existing binding
+	Set(name string, value Value)
+}
+
+// MapEnvironment is the default, map-backed Environment
+type MapEnvironment map[string]Value
+
+// NewEnvironment returns an empty MapEnvironment
+func NewEnvironment() MapEnvironment {
+	return MapEnvironment{}
+}
+
+func (e MapEnvironment) Get(name string) (Value, bool) {
+	value, ok := e[name]
+	return value, ok
+}
+
+func (e MapEnvironment) Set(name string, value Value) {
+	e[name] = value
+}
+
+// scope is the Environment of a call to a user-defined function: its
+// arguments, bound to the parameter names, in front of the environment the
+// function was defined in
+type scope struct {
+	vars   MapEnvironment
+	parent Environment
+}
+
+func (s *scope) Get(name string) (Value, bool) {
+	if value, ok := s.vars[name]; ok {
+		return value, true
+	}
+	return s.parent.Get(name)
+}
+
+func (s *scope) Set(name string, value Value) {
+	s.vars[name] = value
+}
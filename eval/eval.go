@@ -2,56 +2,457 @@ package eval
 
 import (
 	"basic-arithmetic-parser/ast"
+	"basic-arithmetic-parser/diagnostic"
 	"basic-arithmetic-parser/token"
+	"errors"
 	"fmt"
+	"math"
+	"strconv"
 )
 
-// Eval evaluates the given AST node and returns the result as a float64.
-// It returns an error for invalid operations like division by zero.
-func Eval(node ast.Node) (float64, error) {
+// Evaluator evaluates ASTs, looking up variables in an Environment and
+// functions and constants in a Registry.
+type Evaluator struct {
+	env      Environment
+	registry *Registry
+	maxDepth int
+	// depth is the number of user-defined function calls being evaluated
+	depth int
+}
+
+// DefaultMaxDepth is how deeply user-defined functions can call each other
+// unless SetMaxDepth says otherwise
+const DefaultMaxDepth = 1000
+
+// NewEvaluator returns an Evaluator using env for variables and registry for
+// functions and constants. A nil env starts out empty, and a nil registry
+// provides the built-ins (see DefaultRegistry).
+func NewEvaluator(env Environment, registry *Registry) *Evaluator {
+	if env == nil {
+		env = NewEnvironment()
+	}
+	if registry == nil {
+		registry = defaultRegistry
+	}
+	return &Evaluator{env: env, registry: registry, maxDepth: DefaultMaxDepth}
+}
+
+// SetMaxDepth limits how deeply user-defined functions can call each other.
+// A call beyond the limit is reported as an error, so runaway recursion does
+// not overflow the stack.
+func (e *Evaluator) SetMaxDepth(depth int) {
+	e.maxDepth = depth
+}
+
+// Eval evaluates the given AST node and returns the result, a number or a
+// bool. It returns an error for invalid operations like division by zero or
+// true + 1; such errors are diagnostic.Diagnostic values spanning the
+// failing sub-expression. No variables are defined.
+func Eval(node ast.Node) (Value, error) {
+	return NewEvaluator(nil, nil).Eval(node)
+}
+
+// EvalWithEnv is like Eval, but looks variables up in env, and assignments
+// update it.
+func EvalWithEnv(node ast.Node, env Environment) (Value, error) {
+	return NewEvaluator(env, nil).Eval(node)
+}
+
+// IsFunction reports whether name can be called: a built-in or a
+// user-defined function. It lets the evaluator act as a parser.Resolver, so
+// unknown functions are caught while parsing.
+func (e *Evaluator) IsFunction(name string) bool {
+	if e.registry.IsFunction(name) || valueFunctions[name] != nil {
+		return true
+	}
+	value, ok := e.env.Get(name)
+	return ok && value.Kind == FunctionKind
+}
+
+// Eval evaluates node; see the package-level Eval for details
+func (e *Evaluator) Eval(node ast.Node) (Value, error) {
 	switch n := node.(type) {
 	case *ast.NumberNode:
-		return n.Value, nil
+		if n.Imaginary {
+			return Value{}, imaginaryError(n)
+		}
+		return Number(n.Value), nil
+	case *ast.BoolNode:
+		return Bool(n.Value), nil
+	case *ast.IdentNode:
+		if value, ok := e.registry.Constant(n.Name); ok {
+			return Number(value), nil
+		}
+		value, ok := e.env.Get(n.Name)
+		if !ok {
+			return Value{}, newError(n, fmt.Sprintf("undefined variable: %s", n.Name))
+		}
+		return value, nil
+	case *ast.AssignNode:
+		if _, ok := e.registry.Constant(n.Name.Name); ok {
+			return Value{}, newError(n.Name, fmt.Sprintf("cannot assign to constant %s", n.Name.Name))
+		}
+		value, err := e.Eval(n.Value)
+		if err != nil {
+			return Value{}, err
+		}
+		e.env.Set(n.Name.Name, value)
+		return value, nil
+	case *ast.FunctionDefNode:
+		name := n.Name.Name
+		if _, ok := e.registry.Constant(name); ok {
+			return Value{}, newError(n.Name, fmt.Sprintf("cannot assign to constant %s", name))
+		}
+		if e.registry.IsFunction(name) || valueFunctions[name] != nil {
+			return Value{}, newError(n.Name, fmt.Sprintf("cannot redefine built-in function %s", name))
+		}
+		if err := e.checkParams(n.Params); err != nil {
+			return Value{}, err
+		}
+		value := Value{Kind: FunctionKind, Func: newFunction(n, e.env)}
+		e.env.Set(name, value)
+		return value, nil
+	case *ast.LambdaNode:
+		if err := e.checkParams(n.Params); err != nil {
+			return Value{}, err
+		}
+		return Value{Kind: FunctionKind, Func: newFunction(n, e.env)}, nil
+	case *ast.ApplyNode:
+		return e.apply(n)
+	case *ast.CallNode:
+		f, ok := e.registry.functions[n.Name.Name]
+		if !ok {
+			if fn := valueFunctions[n.Name.Name]; fn != nil {
+				return e.callValueFunction(n, fn)
+			}
+			return e.callUser(n)
+		}
+		if err := checkArity(n.Name.Name, f.minArgs, f.maxArgs, len(n.Args)); err != nil {
+			return Value{}, newError(n, err.Error())
+		}
+		args := make([]float64, len(n.Args))
+		for i, arg := range n.Args {
+			val, err := e.Eval(arg)
+			if err != nil {
+				return Value{}, err
+			}
+			if val.Kind != NumberKind {
+				return Value{}, newError(arg, fmt.Sprintf("%s expects a number, got a %s", n.Name.Name, val.Kind))
+			}
+			args[i] = val.Number
+		}
+		result, err := f.fn(args)
+		if err != nil {
+			return Value{}, newError(n, fmt.Sprintf("%s: %v", n.Name.Name, err))
+		}
+		return Number(result), nil
 	case *ast.BinaryOpNode:
-		leftVal, err := Eval(n.Left)
+		leftVal, err := e.Eval(n.Left)
 		if err != nil {
-			return 0, err
+			return Value{}, err
+		}
+		if n.Op.Type == token.AND || n.Op.Type == token.OR {
+			return e.evalLogical(n, leftVal)
 		}
-		rightVal, err := Eval(n.Right)
+		rightVal, err := e.Eval(n.Right)
 		if err != nil {
-			return 0, err
+			return Value{}, err
 		}
-
-		switch n.Op.Type {
-		case token.PLUS:
-			return leftVal + rightVal, nil
-		case token.MINUS:
-			return leftVal - rightVal, nil
-		case token.MULTIPLY:
-			return leftVal * rightVal, nil
-		case token.DIVIDE:
-			if rightVal == 0 {
-				return 0, fmt.Errorf("division by zero")
-			}
-			return leftVal / rightVal, nil
-		default:
-			return 0, fmt.Errorf("unknown binary operator: %s", n.Op.Value)
+		if isRelativePercent(n) && rightVal.Kind == NumberKind &&
+			(leftVal.Kind == NumberKind || leftVal.Kind == ListKind) {
+			rightVal = percentOf(leftVal, rightVal.Number)
+		}
+		return valueBinaryOp(n, leftVal, rightVal)
+	case *ast.ConditionalNode:
+		condVal, err := e.Eval(n.Cond)
+		if err != nil {
+			return Value{}, err
+		}
+		if condVal.Kind != BoolKind {
+			return Value{}, newError(n.Cond, fmt.Sprintf("condition must be a bool, got a %s", condVal.Kind))
+		}
+		// only the branch that is taken is evaluated
+		if condVal.Bool {
+			return e.Eval(n.Then)
+		}
+		return e.Eval(n.Else)
+	case *ast.PercentNode:
+		exprVal, err := e.Eval(n.Expr)
+		if err != nil {
+			return Value{}, err
+		}
+		if exprVal.Kind != NumberKind {
+			return Value{}, newError(n, fmt.Sprintf("a percentage must be a number, not a %s", exprVal.Kind))
 		}
+		return Number(exprVal.Number / 100), nil
+	case *ast.ListNode:
+		return e.evalList(n)
+	case *ast.IndexNode:
+		return e.evalIndex(n)
+	case *ast.SliceNode:
+		return e.evalSlice(n)
+	case *ast.QuantityNode, *ast.ConvertNode:
+		return Value{}, unitsError(n)
+	case *ast.DateNode, *ast.DurationNode:
+		return Value{}, dateError(n)
 	case *ast.UnaryOpNode:
-		exprVal, err := Eval(n.Expr)
+		exprVal, err := e.Eval(n.Expr)
+		if err != nil {
+			return Value{}, err
+		}
+
+		return valueUnaryOp(n, exprVal)
+	case *ast.ErrorNode:
+		return Value{}, newError(n, "cannot evaluate an expression with syntax errors")
+	default:
+		return Value{}, fmt.Errorf("unknown node type: %T", node)
+	}
+}
+
+// checkParams reports parameters that would be hidden by a constant
+func (e *Evaluator) checkParams(params []*ast.IdentNode) error {
+	for _, param := range params {
+		if _, ok := e.registry.Constant(param.Name); ok {
+			return newError(param, fmt.Sprintf("cannot use constant %s as a parameter", param.Name))
+		}
+	}
+	return nil
+}
+
+// callUser calls the user-defined function or lambda bound to the name
+// called by n
+func (e *Evaluator) callUser(n *ast.CallNode) (Value, error) {
+	value, ok := e.env.Get(n.Name.Name)
+	if !ok {
+		return Value{}, newError(n.Name, fmt.Sprintf("unknown function: %s", n.Name.Name))
+	}
+	if value.Kind != FunctionKind {
+		return Value{}, newError(n.Name, fmt.Sprintf("%s is a %s, not a function", n.Name.Name, value.Kind))
+	}
+	params := len(value.Func.params)
+	if err := checkArity(n.Name.Name, params, params, len(n.Args)); err != nil {
+		return Value{}, newError(n, err.Error())
+	}
+
+	args := make([]Value, len(n.Args))
+	for i, arg := range n.Args {
+		val, err := e.Eval(arg)
 		if err != nil {
-			return 0, err
+			return Value{}, err
 		}
+		args[i] = val
+	}
+	return e.callFunction(n, n.Name.Name, value.Func, args)
+}
 
-		switch n.Op.Type {
-		case token.PLUS: // Unary plus (identity)
-			return exprVal, nil
-		case token.MINUS: // Unary minus (negation)
-			return -exprVal, nil
-		default:
-			return 0, fmt.Errorf("unknown unary operator: %s", n.Op.Value)
+// apply calls the function an expression evaluates to, as in adder(3)(4)
+func (e *Evaluator) apply(n *ast.ApplyNode) (Value, error) {
+	value, err := e.Eval(n.Func)
+	if err != nil {
+		return Value{}, err
+	}
+	if value.Kind != FunctionKind {
+		return Value{}, newError(n.Func, fmt.Sprintf("cannot call a %s", value.Kind))
+	}
+	args := make([]Value, len(n.Args))
+	for i, arg := range n.Args {
+		val, err := e.Eval(arg)
+		if err != nil {
+			return Value{}, err
+		}
+		args[i] = val
+	}
+	return e.callFunction(n, value.Func.Name(), value.Func, args)
+}
+
+// callFunction calls f, under the given name, with args on behalf of site.
+// The body is evaluated in a new scope holding the arguments, whose parent
+// is the environment the function was defined in.
+func (e *Evaluator) callFunction(site ast.Node, name string, f *Function, args []Value) (Value, error) {
+	if err := checkArity(name, len(f.params), len(f.params), len(args)); err != nil {
+		return Value{}, newError(site, err.Error())
+	}
+	if e.depth >= e.maxDepth {
+		return Value{}, newError(site, fmt.Sprintf("maximum recursion depth of %d exceeded", e.maxDepth))
+	}
+
+	vars := MapEnvironment{}
+	for i, arg := range args {
+		vars[f.params[i].Name] = arg
+	}
+	callee := &Evaluator{
+		env:      &scope{vars: vars, parent: f.env},
+		registry: e.registry,
+		maxDepth: e.maxDepth,
+		depth:    e.depth + 1,
+	}
+	result, err := callee.Eval(f.body)
+	var d diagnostic.Diagnostic
+	if e.depth == 0 && errors.As(err, &d) {
+		// the body may have been parsed from another input, such as the line
+		// that defined the function, so its spans need not point into the
+		// one being evaluated
+		d.Span = site.Span()
+		d.Message = fmt.Sprintf("in %s: %s", name, d.Message)
+		return Value{}, d
+	}
+	return result, err
+}
+
+// evalLogical evaluates && and ||, whose left operand is leftVal. The right
+// operand is only evaluated when it decides the result, so false && 1/0 is
+// false rather than an error.
+func (e *Evaluator) evalLogical(n *ast.BinaryOpNode, leftVal Value) (Value, error) {
+	left, err := expectBool(n.Left, n.Op.Value, leftVal)
+	if err != nil {
+		return Value{}, err
+	}
+	if left == (n.Op.Type == token.OR) {
+		return Bool(left), nil
+	}
+	rightVal, err := e.Eval(n.Right)
+	if err != nil {
+		return Value{}, err
+	}
+	right, err := expectBool(n.Right, n.Op.Value, rightVal)
+	if err != nil {
+		return Value{}, err
+	}
+	return Bool(right), nil
+}
+
+// operatorVerbs describe the operators in errors about operands of the
+// wrong kind, e.g. cannot add bool and number
+var operatorVerbs = map[token.TokenType]string{
+	token.PLUS:     "add",
+	token.MINUS:    "subtract",
+	token.MULTIPLY: "multiply",
+	token.DIVIDE:   "divide",
+}
+
+// floatBinaryOp applies the operator of n to two float64 operands
+func floatBinaryOp(n *ast.BinaryOpNode, leftVal, rightVal float64) (float64, error) {
+	switch n.Op.Type {
+	case token.PLUS:
+		return leftVal + rightVal, nil
+	case token.MINUS:
+		return leftVal - rightVal, nil
+	case token.MULTIPLY, token.OF:
+		return leftVal * rightVal, nil
+	case token.DIVIDE:
+		if rightVal == 0 {
+			return 0, newError(n, "division by zero")
+		}
+		return leftVal / rightVal, nil
+	case token.MODULO:
+		if rightVal == 0 {
+			return 0, newError(n, "division by zero")
+		}
+		// floored: the result takes the sign of the divisor
+		r := math.Mod(leftVal, rightVal)
+		if r != 0 && (r < 0) != (rightVal < 0) {
+			r += rightVal
+		}
+		return r, nil
+	case token.FLOOR_DIVIDE:
+		if rightVal == 0 {
+			return 0, newError(n, "division by zero")
 		}
+		return math.Floor(leftVal / rightVal), nil
+	case token.REM:
+		if rightVal == 0 {
+			return 0, newError(n, "division by zero")
+		}
+		// truncated: the result takes the sign of the dividend
+		return math.Mod(leftVal, rightVal), nil
+	case token.POWER:
+		if leftVal == 0 && rightVal < 0 {
+			return 0, newError(n, "division by zero")
+		}
+		if leftVal < 0 && rightVal != math.Trunc(rightVal) {
+			return 0, newError(n, "fractional power of a negative number")
+		}
+		return math.Pow(leftVal, rightVal), nil
+	case token.PLUSMINUS:
+		return 0, newError(n, "± is only supported in interval and uncertainty modes")
 	default:
-		return 0, fmt.Errorf("unknown node type: %T", node)
+		return 0, newError(n, fmt.Sprintf("unknown binary operator: %s", n.Op.Value))
+	}
+}
+
+// isRelativePercent reports whether n adds a percentage to its left operand
+// or subtracts one from it, which makes the percentage relative to that
+// operand: 200 + 10% is 200 + 10% of 200
+func isRelativePercent(n *ast.BinaryOpNode) bool {
+	_, ok := n.Right.(*ast.PercentNode)
+	return ok && (n.Op.Type == token.PLUS || n.Op.Type == token.MINUS)
+}
+
+// percentOf returns pct of base, element by element if base is a list, so
+// [100, 200] + 10% adds 10 and 20
+func percentOf(base Value, pct float64) Value {
+	if base.Kind == ListKind {
+		elems := make([]Value, len(base.List))
+		for i, elem := range base.List {
+			elems[i] = percentOf(elem, pct)
+		}
+		return List(elems...)
+	}
+	return Number(base.Number * pct)
+}
+
+// imaginaryError reports an imaginary literal outside of complex mode
+func imaginaryError(n *ast.NumberNode) error {
+	return newError(n, "imaginary numbers are only supported in complex mode")
+}
+
+// unitsError reports a quantity or unit conversion outside units mode
+func unitsError(n ast.Node) error {
+	return newError(n, "units are only supported in units mode")
+}
+
+// percentError reports a percentage in a mode that does not support them
+func percentError(n ast.Node) error {
+	return newError(n, "percentages are only supported in float, rational and decimal modes")
+}
+
+// boolError reports a boolean or a conditional outside float and date
+// modes
+func boolError(n ast.Node) error {
+	return newError(n, "booleans are only supported in float and date modes")
+}
+
+// functionDefError reports a function definition or lambda outside float
+// mode
+func functionDefError(n ast.Node) error {
+	return newError(n, "user-defined functions are only supported in float mode")
+}
+
+// listError reports a list, index or slice outside float mode. Intervals
+// are written as two-element lists, so those point to interval mode too.
+func listError(n ast.Node) error {
+	if list, ok := n.(*ast.ListNode); ok && len(list.Elems) == 2 {
+		return newError(n, "lists are only supported in float mode, and intervals in interval mode")
+	}
+	return newError(n, "lists are only supported in float mode")
+}
+
+// dateError reports a date or duration outside date mode
+func dateError(n ast.Node) error {
+	return newError(n, "dates and durations are only supported in date mode")
+}
+
+// newError returns an evaluation error located at node
+func newError(node ast.Node, msg string) error {
+	return diagnostic.Diagnostic{Span: node.Span(), Message: msg}
+}
+
+// literalText returns the text of a number literal. A node that was not
+// parsed from text only has its float64 value, which is written as the
+// shortest decimal that reads back as it.
+func literalText(n *ast.NumberNode) string {
+	if n.Literal == "" {
+		return strconv.FormatFloat(n.Value, 'f', -1, 64)
 	}
+	return n.Literal
 }
@@ -2,10 +2,11 @@ package eval
 
 import (
 	"basic-arithmetic-parser/ast"
+	"basic-arithmetic-parser/diagnostic"
 	"basic-arithmetic-parser/lexer"
 	"basic-arithmetic-parser/parser"
 	"basic-arithmetic-parser/token"
-	"fmt"
+	"errors"
 	"testing"
 )
 
@@ -127,6 +128,17 @@ func TestEvalOperatorPrecedence(t *testing.T) {
 			expected: 7,
 			hasError: false,
 		},
+		{
+			name: "Syntax error placeholder",
+			// 1 + <error>
+			node: &ast.BinaryOpNode{
+				Left:  &ast.NumberNode{Value: 1},
+				Op:    token.Token{Type: token.PLUS, Value: "+"},
+				Right: &ast.ErrorNode{},
+			},
+			expected: 0,
+			hasError: true,
+		},
 		{
 			name: "Division by zero",
 			// 5 / 0
@@ -152,8 +164,8 @@ func TestEvalOperatorPrecedence(t *testing.T) {
 				if err != nil {
 					t.Errorf("Did not expect an error, but got: %v", err)
 				}
-				if result != tt.expected {
-					t.Errorf("Expected %g, but got %g", tt.expected, result)
+				if !result.Equal(Number(tt.expected)) {
+					t.Errorf("Expected %g, but got %v", tt.expected, result)
 				}
 			}
 		})
@@ -162,11 +174,11 @@ func TestEvalOperatorPrecedence(t *testing.T) {
 
 func TestEvalWithParser(t *testing.T) {
 	tests := []struct {
-		name            string
-		input           string
-		expected        float64
-		expectPanic     bool
-		expectEvalError bool
+		name             string
+		input            string
+		expected         float64
+		expectParseError bool
+		expectEvalError  bool
 	}{
 		{"Simple Addition", "2 + 3", 5, false, false},
 		{"Simple Subtraction", "10 - 4", 6, false, false},
@@ -182,48 +194,73 @@ func TestEvalWithParser(t *testing.T) {
 		{"Unary Plus", "+3 + 5", 8, false, false},
 		{"Complex Expression", "-(2 + 3) * 4 / 10 - 1", -3, false, false},
 		{"Division by Zero", "10 / 0", 0, false, true},  // Parse OK, Eval Error
-		{"Invalid Syntax 1", "2 + * 3", 0, true, false}, // Expect Parser Panic
-		{"Invalid Syntax 2", "1 + 2 )", 0, true, false}, // Expect Parser Panic (unmatched parenthesis)
-		{"Invalid Syntax 3", "( 1 + 2", 0, true, false}, // Expect Parser Panic (missing closing parenthesis)
-		{"Empty Input", "", 0, true, false},             // Expect Parser Panic
-		{"Just Operator", "+", 0, true, false},          // Expect Parser Panic
+		{"Invalid Syntax 1", "2 + * 3", 0, true, false}, // Expect Parse Error
+		{"Invalid Syntax 2", "1 + 2 )", 0, true, false}, // Expect Parse Error (unmatched parenthesis)
+		{"Invalid Syntax 3", "( 1 + 2", 0, true, false}, // Expect Parse Error (missing closing parenthesis)
+		{"Empty Input", "", 0, true, false},             // Expect Parse Error
+		{"Just Operator", "+", 0, true, false},          // Expect Parse Error
+		{"Invalid Character", "2 $ 3", 0, true, false},  // Expect Parse Error (lexical)
+		{"Power", "2 ^ 10", 1024, false, false},
+		{"Power Alias", "2 ** 3", 8, false, false},
+		{"Power Right Associative", "2 ^ 3 ^ 2", 512, false, false},
+		{"Power Binds Tighter Than Unary Minus", "-2 ^ 2", -4, false, false},
+		{"Negative Exponent", "2 ^ -1", 0.5, false, false},
+		{"Power Of Negative Base", "(-2) ^ 3", -8, false, false},
+		{"Zero To Negative Power", "0 ^ -1", 0, false, true},
+		{"Fractional Power Of Negative", "(-8) ^ 0.5", 0, false, true},
+		{"Modulo", "7 % 3", 1, false, false},
+		{"Modulo Negative Dividend", "-7 % 3", 2, false, false},
+		{"Modulo Negative Divisor", "7 % -3", -2, false, false},
+		{"Modulo Both Negative", "-7 % -3", -1, false, false},
+		{"Modulo Signed Divisor", "10 % +3", 1, false, false},
+		{"Modulo Fractional", "5.5 % 2", 1.5, false, false},
+		{"Modulo Wrap Around", "(22 + 5) % 24", 3, false, false},
+		{"Modulo By Zero", "7 % 0", 0, false, true},
+		{"Floor Division", "7 // 2", 3, false, false},
+		{"Floor Division Negative", "-7 // 2", -4, false, false},
+		{"Floor Division By Zero", "7 // 0", 0, false, true},
+		{"Remainder", "7 rem 3", 1, false, false},
+		{"Remainder Negative Dividend", "-7 rem 3", -1, false, false},
+		{"Remainder Negative Divisor", "7 rem -3", 1, false, false},
+		{"Remainder By Zero", "7 rem 0", 0, false, true},
+		{"Imaginary Outside Complex Mode", "1 + 2i", 0, false, true},
+		{"Mismatched List Lengths", "[1, 2] + [1, 2, 3]", 0, false, true},
+		{"Plus Minus Outside Interval Mode", "2 ± 0.1", 0, false, true},
+		{"Quantity Outside Units Mode", "3 m + 2", 0, true, false},
+		{"Conversion Outside Units Mode", "3 to km", 0, false, true},
+		{"Date Outside Date Mode", "2026-10-17", 1999, false, false},
+		{"Percentage", "10%", 0.1, false, false},
+		{"Add Percentage", "200 + 10%", 220, false, false},
+		{"Subtract Percentage", "200 - 10%", 180, false, false},
+		{"Multiply By Percentage", "50% * 80", 40, false, false},
+		{"Percentage Of", "15% of 80", 12, false, false},
+		{"Percentage Of Sum", "(150 + 50)% of 2", 4, false, false},
+		{"Add Parenthesised Percentage", "100 + (20%)", 120, false, false},
+		{"Add Percentage To Sum", "(200 + 10%) - 5", 215, false, false},
+		{"Percentage Plus Number", "(10%) + 1", 1.1, false, false},
+		{"Percentage Minus Number", "(50%) - 10", -9.5, false, false},
 		{"Number Only", "42", 42, false, false},
 		{"Unary Only", "-10", -10, false, false},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			var panicked bool
-			var panicValue interface{}
-			var program ast.Node
-
-			func() {
-				defer func() {
-					if r := recover(); r != nil {
-						panicked = true
-						panicValue = r
-						fmt.Printf("Caught panic: %v\n", r)
-					}
-				}()
-
-				// Attempt to parse inside the function with the defer/recover
-				l := lexer.New(tt.input)
-				p := parser.New(l)
-				program = p.Parse() // This might panic (when parsing fails).
-			}()
-
-			if panicked != tt.expectPanic {
-				t.Fatalf("Expected panic: %v, but got panicked=%v. Panic value: %v", tt.expectPanic, panicked, panicValue)
+			l := lexer.New(tt.input)
+			p := parser.New(l)
+			program, parseErr := p.Parse()
+
+			hasParseError := parseErr != nil
+			if hasParseError != tt.expectParseError {
+				t.Fatalf("Expected parse error: %v, but got: %v. Error: %v", tt.expectParseError, hasParseError, parseErr)
 			}
 
-			if tt.expectPanic {
+			if tt.expectParseError {
 				return
 			}
 
-			// Ensure the parsed program/node is not nil if no panic occurred
+			// Ensure the parsed program/node is not nil if parsing succeeded
 			if program == nil {
-				// This case might indicate an issue where Parse returns nil without panicking
-				t.Fatalf("Parsing did not panic but returned a nil AST node for input: %s", tt.input)
+				t.Fatalf("Parsing did not fail but returned a nil AST node for input: %s", tt.input)
 			}
 
 			result, evalErr := Eval(program)
@@ -232,25 +269,293 @@ func TestEvalWithParser(t *testing.T) {
 				t.Fatalf("Expected eval error: %v, got: %v. Error: %v", tt.expectEvalError, hasEvalError, evalErr)
 			}
 
-			// Stop if evaluation failed and we expected it to succeed
-			if !tt.expectEvalError && hasEvalError {
-				t.Fatalf("Did not expect eval error, but got: %v", evalErr)
-			}
-
-			// Stop if evaluation succeeded but we expected it to fail
-			if tt.expectEvalError && !hasEvalError {
-				t.Fatalf("Expected eval error, but got none for input: %s", tt.input)
-			}
-
 			// If evaluation was expected to fail, we don't compare the result
 			if tt.expectEvalError {
 				return
 			}
 
 			// Compare the result if no errors were expected
-			if result != tt.expected {
-				t.Errorf("Expected result %g, but got %g for input: %s", tt.expected, result, tt.input)
+			if !result.Equal(Number(tt.expected)) {
+				t.Errorf("Expected result %g, but got %v for input: %s", tt.expected, result, tt.input)
 			}
 		})
 	}
 }
+
+func TestEvalErrorSpan(t *testing.T) {
+	input := "1 + 4 / (2 - 2) * 3"
+	l := lexer.New(input)
+	p := parser.New(l)
+	program, err := p.Parse()
+	if err != nil {
+		t.Fatalf("Parse() returned an error: %v", err)
+	}
+
+	_, evalErr := Eval(program)
+	var d diagnostic.Diagnostic
+	if !errors.As(evalErr, &d) {
+		t.Fatalf("Expected a diagnostic.Diagnostic, got %T (%v)", evalErr, evalErr)
+	}
+	if d.Message != "division by zero" {
+		t.Errorf("Expected message %q, got %q", "division by zero", d.Message)
+	}
+	got := input[d.Span.Start.Offset:d.Span.End.Offset]
+	if got != "4 / (2 - 2)" {
+		t.Errorf("Expected error span %q, got %q", "4 / (2 - 2)", got)
+	}
+}
+
+func TestEvalWithEnv(t *testing.T) {
+	env := NewEnvironment()
+	env.Set("base", Number(10))
+
+	tests := []struct {
+		input    string
+		expected float64
+	}{
+		{"x = 3 * 4", 12},
+		{"x + base", 22},
+		{"x = x + 1", 13},
+		{"y = -x % 5", 2},
+		{"x * y", 26},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := parser.New(l)
+		program, err := p.Parse()
+		if err != nil {
+			t.Fatalf("Parse(%q) returned an error: %v", tt.input, err)
+		}
+
+		result, err := EvalWithEnv(program, env)
+		if err != nil {
+			t.Fatalf("EvalWithEnv(%q) returned an error: %v", tt.input, err)
+		}
+		if !result.Equal(Number(tt.expected)) {
+			t.Errorf("EvalWithEnv(%q) wrong. expected=%g, got=%v", tt.input, tt.expected, result)
+		}
+	}
+
+	if value, ok := env.Get("y"); !ok || !value.Equal(Number(2)) {
+		t.Errorf("Expected y to be bound to 2, got %v (bound: %v)", value, ok)
+	}
+}
+
+func TestEvalUndefinedVariable(t *testing.T) {
+	input := "1 + rate"
+	l := lexer.New(input)
+	p := parser.New(l)
+	program, err := p.Parse()
+	if err != nil {
+		t.Fatalf("Parse() returned an error: %v", err)
+	}
+
+	_, evalErr := Eval(program)
+	var d diagnostic.Diagnostic
+	if !errors.As(evalErr, &d) {
+		t.Fatalf("Expected a diagnostic.Diagnostic, got %T (%v)", evalErr, evalErr)
+	}
+	if d.Message != "undefined variable: rate" {
+		t.Errorf("Expected message %q, got %q", "undefined variable: rate", d.Message)
+	}
+	if got := input[d.Span.Start.Offset:d.Span.End.Offset]; got != "rate" {
+		t.Errorf("Expected error span %q, got %q", "rate", got)
+	}
+}
+
+func TestEvalConditionalWithEnv(t *testing.T) {
+	// a piecewise price: 10 per unit, 8 per unit from 100 units
+	env := NewEnvironment()
+	tests := []struct {
+		units    float64
+		expected float64
+	}{
+		{0, 0},
+		{50, 500},
+		{100, 800},
+		{150, 1200},
+	}
+
+	program := mustParse(t, "units < 100 ? units * 10 : units * 8")
+	for _, tt := range tests {
+		env.Set("units", Number(tt.units))
+		result, err := EvalWithEnv(program, env)
+		if err != nil {
+			t.Fatalf("EvalWithEnv() returned an error for units=%g: %v", tt.units, err)
+		}
+		if !result.Equal(Number(tt.expected)) {
+			t.Errorf("units=%g: expected %g, got %v", tt.units, tt.expected, result)
+		}
+	}
+
+	// an unset variable in the branch not taken is never looked up
+	program = mustParse(t, "x != 0 ? 1 / x : fallback")
+	env.Set("x", Number(4))
+	result, err := EvalWithEnv(program, env)
+	if err != nil || !result.Equal(Number(0.25)) {
+		t.Errorf("Expected 0.25, got %v (error: %v)", result, err)
+	}
+}
+
+// mustParse parses input, failing the test if it has syntax errors
+func mustParse(t *testing.T, input string) ast.Node {
+	t.Helper()
+	program, err := parser.New(lexer.New(input)).Parse()
+	if err != nil {
+		t.Fatalf("Parse(%q) returned an error: %v", input, err)
+	}
+	return program
+}
+
+// evalLines evaluates each line in turn with evaluator, as the REPL does,
+// and returns the result of the last one
+func evalLines(t *testing.T, evaluator *Evaluator, lines ...string) (Value, error) {
+	t.Helper()
+	var result Value
+	for _, line := range lines {
+		var err error
+		if result, err = evaluator.Eval(mustParse(t, line)); err != nil {
+			return Value{}, err
+		}
+	}
+	return result, nil
+}
+
+func TestUserFunctions(t *testing.T) {
+	tests := []struct {
+		name     string
+		lines    []string
+		expected Value
+	}{
+		{"Simple", []string{"f(x, y) = x^2 + y", "f(3, 1)"}, Number(10)},
+		{"No Parameters", []string{"answer() = 42", "answer() + 1"}, Number(43)},
+		{"Calls Builtin", []string{"hyp(a, b) = sqrt(a^2 + b^2)", "hyp(3, 4)"}, Number(5)},
+		{"Calls Another", []string{"sq(x) = x * x", "f(x) = sq(x) + 1", "f(3)"}, Number(10)},
+		{"Recursion", []string{"fact(n) = n <= 1 ? 1 : n * fact(n - 1)", "fact(10)"}, Number(3628800)},
+		{"Mutual Recursion", []string{
+			"even(n) = n == 0 ? true : odd(n - 1)",
+			"odd(n) = n == 0 ? false : even(n - 1)",
+			"even(10)",
+		}, Bool(true)},
+		{"Returns Bool", []string{"positive(x) = x > 0", "positive(-1)"}, Bool(false)},
+		{"Sees Globals At Call Time", []string{"a = 5", "k(x) = x + a", "a = 7", "k(1)"}, Number(8)},
+		{"Parameter Shadows Global", []string{"x = 100", "f(x) = x + 1", "f(1)"}, Number(2)},
+		{"Parameters Do Not Leak", []string{"x = 100", "f(x) = x", "f(1)", "x"}, Number(100)},
+		// lexical scoping: f sees the global a, not g's parameter a
+		{"Lexical Scope", []string{"a = 1", "f(y) = a + y", "g(a) = f(10)", "g(5)"}, Number(11)},
+		{"Redefinition", []string{"f(x) = x", "f(x) = 2 * x", "f(4)"}, Number(8)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := evalLines(t, NewEvaluator(nil, nil), tt.lines...)
+			if err != nil {
+				t.Fatalf("Eval() returned an error: %v", err)
+			}
+			if !result.Equal(tt.expected) {
+				t.Errorf("Expected %v, but got %v", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestUserFunctionErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		lines    []string
+		expected string
+	}{
+		{"Unknown Function", []string{"nope(1)"}, "unknown function: nope"},
+		{"Not A Function", []string{"x = 3", "x(1)"}, "x is a number, not a function"},
+		{"Too Few Arguments", []string{"f(x, y) = x + y", "f(1)"}, "f expects 2 arguments, got 1"},
+		{"Too Many Arguments", []string{"f(x) = x", "f(1, 2)"}, "f expects 1 argument, got 2"},
+		{"Redefine Builtin", []string{"sqrt(x) = x"}, "cannot redefine built-in function sqrt"},
+		{"Define Constant", []string{"pi(x) = x"}, "cannot assign to constant pi"},
+		{"Constant Parameter", []string{"f(e) = e"}, "cannot use constant e as a parameter"},
+		{"Undefined In Body", []string{"f(x) = x + y", "f(1)"}, "in f: undefined variable: y"},
+		{"Error In Nested Call", []string{"inv(x) = 1 / x", "f(x) = inv(x) + 1", "f(0)"}, "in f: division by zero"},
+		{"Runaway Recursion", []string{"loop(n) = loop(n + 1)", "loop(0)"}, "in loop: maximum recursion depth of 1000 exceeded"},
+		{"Function Arithmetic", []string{"f(x) = x", "f + 1"}, "cannot add function and number"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := evalLines(t, NewEvaluator(nil, nil), tt.lines...)
+			var d diagnostic.Diagnostic
+			if !errors.As(err, &d) {
+				t.Fatalf("Expected a diagnostic.Diagnostic, got %T (%v)", err, err)
+			}
+			if d.Message != tt.expected {
+				t.Errorf("Expected error %q, but got %q", tt.expected, d.Message)
+			}
+		})
+	}
+}
+
+func TestUserFunctionErrorSpan(t *testing.T) {
+	evaluator := NewEvaluator(nil, nil)
+	if _, err := evalLines(t, evaluator, "inv(x) = 1 / x"); err != nil {
+		t.Fatalf("Eval() returned an error: %v", err)
+	}
+
+	// the error is in the body, which is not part of this input, so it is
+	// reported at the call
+	input := "2 + inv(0)"
+	_, err := evalLines(t, evaluator, input)
+	var d diagnostic.Diagnostic
+	if !errors.As(err, &d) {
+		t.Fatalf("Expected a diagnostic.Diagnostic, got %T (%v)", err, err)
+	}
+	if got := input[d.Span.Start.Offset:d.Span.End.Offset]; got != "inv(0)" {
+		t.Errorf("Expected error span %q, got %q", "inv(0)", got)
+	}
+}
+
+func TestMaxDepth(t *testing.T) {
+	evaluator := NewEvaluator(nil, nil)
+	evaluator.SetMaxDepth(10)
+	total := "total(n) = n == 0 ? 0 : n + total(n - 1)"
+
+	// total(9) makes 10 nested calls
+	result, err := evalLines(t, evaluator, total, "total(9)")
+	if err != nil || !result.Equal(Number(45)) {
+		t.Errorf("Expected 45, got %v (error: %v)", result, err)
+	}
+
+	_, err = evalLines(t, evaluator, "total(10)")
+	var d diagnostic.Diagnostic
+	if !errors.As(err, &d) || d.Message != "in total: maximum recursion depth of 10 exceeded" {
+		t.Errorf("Expected a recursion depth error, got %v", err)
+	}
+}
+
+func TestEvaluatorIsFunction(t *testing.T) {
+	evaluator := NewEvaluator(nil, nil)
+	if _, err := evalLines(t, evaluator, "f(x) = x", "y = 2"); err != nil {
+		t.Fatalf("Eval() returned an error: %v", err)
+	}
+	for name, expected := range map[string]bool{"sqrt": true, "f": true, "y": false, "g": false} {
+		if got := evaluator.IsFunction(name); got != expected {
+			t.Errorf("IsFunction(%q) = %v, expected %v", name, got, expected)
+		}
+	}
+}
+
+func TestLiteralText(t *testing.T) {
+	tests := []struct {
+		node     *ast.NumberNode
+		expected string
+	}{
+		{&ast.NumberNode{Value: 0.1, Literal: "0.10"}, "0.10"},
+		{&ast.NumberNode{Value: 0.1}, "0.1"},
+		{&ast.NumberNode{Value: 1e21}, "1000000000000000000000"},
+		{&ast.NumberNode{Value: -2.5}, "-2.5"},
+	}
+
+	for _, tt := range tests {
+		if got := literalText(tt.node); got != tt.expected {
+			t.Errorf("literalText(%v) = %q, expected %q", tt.node.Value, got, tt.expected)
+		}
+	}
+}
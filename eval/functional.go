@@ -0,0 +1,187 @@
+package eval
+
+import (
+	"basic-arithmetic-parser/ast"
+	"fmt"
+	"math"
+)
+
+// maxSeriesTerms bounds the number of terms sum and product add up, so a
+// typo in a bound does not hang the evaluator
+const maxSeriesTerms = 1_000_000
+
+// maxExactInteger is 2^53, the largest float64 below which every integer
+// can be held exactly
+const maxExactInteger = 1 << 53
+
+// valueFunction is a built-in that takes values other than numbers, such
+// as functions and lists, as arguments. Its arguments are evaluated before
+// fn is called.
+type valueFunction struct {
+	minArgs, maxArgs int
+	fn               func(e *Evaluator, n *ast.CallNode, args []Value) (Value, error)
+}
+
+// valueFunctions are the built-ins that take functions or lists, such as
+// map(x -> x * 2, 1, 2, 3) and len([1, 2]). They are set up in init, as
+// some call back into the evaluator.
+var valueFunctions map[string]*valueFunction
+
+func init() {
+	valueFunctions = map[string]*valueFunction{
+		// map(f, x1, x2, ...) is the list [f(x1), f(x2), ...]
+		"map": {1, -1, func(e *Evaluator, n *ast.CallNode, args []Value) (Value, error) {
+			f, err := expectFunction(n, "map", args[0])
+			if err != nil {
+				return Value{}, err
+			}
+			xs := sequence(args[1:])
+			result := make([]Value, len(xs))
+			for i, arg := range xs {
+				if result[i], err = e.callFunction(n, f.Name(), f, []Value{arg}); err != nil {
+					return Value{}, err
+				}
+			}
+			return List(result...), nil
+		}},
+		// filter(f, x1, x2, ...) is the list of the xs for which f is true
+		"filter": {1, -1, func(e *Evaluator, n *ast.CallNode, args []Value) (Value, error) {
+			f, err := expectFunction(n, "filter", args[0])
+			if err != nil {
+				return Value{}, err
+			}
+			result := []Value{}
+			for _, arg := range sequence(args[1:]) {
+				keep, err := e.callFunction(n, f.Name(), f, []Value{arg})
+				if err != nil {
+					return Value{}, err
+				}
+				if keep.Kind != BoolKind {
+					return Value{}, newError(n, fmt.Sprintf("filter expects its function to return a bool, got a %s", keep.Kind))
+				}
+				if keep.Bool {
+					result = append(result, arg)
+				}
+			}
+			return List(result...), nil
+		}},
+		// reduce(f, x1, x2, x3, ...) is f(f(x1, x2), x3) and so on, and
+		// reduce(f, xs, init) folds the list xs starting from init
+		"reduce": {2, -1, func(e *Evaluator, n *ast.CallNode, args []Value) (Value, error) {
+			f, err := expectFunction(n, "reduce", args[0])
+			if err != nil {
+				return Value{}, err
+			}
+			xs := sequence(args[1:])
+			if args[1].Kind == ListKind && len(args) > 2 {
+				if len(args) > 3 {
+					return Value{}, newError(n, "reduce expects a list and an initial value, or separate values")
+				}
+				xs = append([]Value{args[2]}, args[1].List...)
+			}
+			if len(xs) == 0 {
+				return Value{}, newError(n, "reduce expects at least 1 value, got an empty list")
+			}
+			acc := xs[0]
+			for _, arg := range xs[1:] {
+				if acc, err = e.callFunction(n, f.Name(), f, []Value{acc, arg}); err != nil {
+					return Value{}, err
+				}
+			}
+			return acc, nil
+		}},
+		// len(xs) is the number of elements of the list xs
+		"len": {1, 1, func(e *Evaluator, n *ast.CallNode, args []Value) (Value, error) {
+			if args[0].Kind != ListKind {
+				return Value{}, newError(n.Args[0], fmt.Sprintf("len expects a list, got a %s", args[0].Kind))
+			}
+			return Number(float64(len(args[0].List))), nil
+		}},
+		"transpose": {1, 1, transposeBuiltin},
+		"det":       {1, 1, detBuiltin},
+		"inverse":   {1, 1, inverseBuiltin},
+		"solve":     {2, 2, solveBuiltin},
+		"identity":  {1, 1, identityBuiltin},
+		"zeros":     {1, 2, zerosBuiltin},
+		// sum(f, lo, hi) is f(lo) + f(lo + 1) + ... + f(hi)
+		"sum": {3, 3, func(e *Evaluator, n *ast.CallNode, args []Value) (Value, error) {
+			return e.series(n, "sum", args, 0, func(acc, term float64) float64 { return acc + term })
+		}},
+		// product(f, lo, hi) is f(lo) * f(lo + 1) * ... * f(hi)
+		"product": {3, 3, func(e *Evaluator, n *ast.CallNode, args []Value) (Value, error) {
+			return e.series(n, "product", args, 1, func(acc, term float64) float64 { return acc * term })
+		}},
+	}
+}
+
+// callValueFunction evaluates the arguments of n and calls fn with them
+func (e *Evaluator) callValueFunction(n *ast.CallNode, fn *valueFunction) (Value, error) {
+	if err := checkArity(n.Name.Name, fn.minArgs, fn.maxArgs, len(n.Args)); err != nil {
+		return Value{}, newError(n, err.Error())
+	}
+	args := make([]Value, len(n.Args))
+	for i, arg := range n.Args {
+		val, err := e.Eval(arg)
+		if err != nil {
+			return Value{}, err
+		}
+		args[i] = val
+	}
+	return fn.fn(e, n, args)
+}
+
+// sequence returns the values a function is mapped over: the elements of
+// xs, or those of its only value if that is a list, so map(f, 1, 2) and
+// map(f, [1, 2]) are the same
+func sequence(xs []Value) []Value {
+	if len(xs) == 1 && xs[0].Kind == ListKind {
+		return xs[0].List
+	}
+	return xs
+}
+
+// series combines f(lo), f(lo + 1), ..., f(hi), where args are f, lo and
+// hi, starting from identity. An empty range gives identity.
+func (e *Evaluator) series(n *ast.CallNode, name string, args []Value, identity float64, combine func(acc, term float64) float64) (Value, error) {
+	f, err := expectFunction(n, name, args[0])
+	if err != nil {
+		return Value{}, err
+	}
+	for _, bound := range args[1:] {
+		if bound.Kind != NumberKind || bound.Number != math.Trunc(bound.Number) {
+			return Value{}, newError(n, fmt.Sprintf("%s expects whole numbers as bounds, got %s", name, bound))
+		}
+		if math.Abs(bound.Number) > maxExactInteger {
+			return Value{}, newError(n, fmt.Sprintf("%s expects bounds of at most 2^53 in size, got %s", name, bound))
+		}
+	}
+	// the bounds are exact, so counting the terms as integers cannot
+	// skip or repeat one the way stepping a float64 past 2^53 would
+	lo, hi := int64(args[1].Number), int64(args[2].Number)
+	count := hi - lo + 1
+	if count > maxSeriesTerms {
+		return Value{}, newError(n, fmt.Sprintf("%s: too many terms, at most %d are allowed", name, maxSeriesTerms))
+	}
+
+	acc := identity
+	for i := int64(0); i < count; i++ {
+		term, err := e.callFunction(n, f.Name(), f, []Value{Number(float64(lo + i))})
+		if err != nil {
+			return Value{}, err
+		}
+		if term.Kind != NumberKind {
+			return Value{}, newError(n, fmt.Sprintf("%s expects its function to return a number, got a %s", name, term.Kind))
+		}
+		acc = combine(acc, term.Number)
+	}
+	return Number(acc), nil
+}
+
+// expectFunction returns the function held by v, the first argument of the
+// built-in name, or an error at n if v is not one
+func expectFunction(n *ast.CallNode, name string, v Value) (*Function, error) {
+	if v.Kind != FunctionKind {
+		return nil, newError(n, fmt.Sprintf("%s expects a function as its first argument, got a %s", name, v.Kind))
+	}
+	return v.Func, nil
+}
@@ -0,0 +1,114 @@
+package eval
+
+import (
+	"basic-arithmetic-parser/diagnostic"
+	"errors"
+	"testing"
+)
+
+func TestLambdasAndHigherOrderFunctions(t *testing.T) {
+	tests := []struct {
+		name     string
+		lines    []string
+		expected Value
+	}{
+		{"Named Lambda", []string{"double = x -> x * 2", "double(21)"}, Number(42)},
+		{"Two Parameters", []string{"add = (a, b) -> a + b", "add(2, 3)"}, Number(5)},
+		{"No Parameters", []string{"answer = () -> 42", "answer()"}, Number(42)},
+		{"Closure", []string{"adder(n) = x -> x + n", "add3 = adder(3)", "add3(4)"}, Number(7)},
+		{"Curried", []string{"add = x -> y -> x + y", "inc = add(1)", "inc(41)"}, Number(42)},
+		{"Call Result Of Call", []string{"adder(n) = x -> x + n", "adder(3)(4)"}, Number(7)},
+		{"Call Lambda", []string{"(x -> x)(5)"}, Number(5)},
+		{"Call Curried", []string{"add = x -> y -> x + y", "add(1)(41)"}, Number(42)},
+		{"Recursive Lambda", []string{"fact = n -> n <= 1 ? 1 : n * fact(n - 1)", "fact(5)"}, Number(120)},
+		{"Call List Element", []string{"fs = [x -> x + 1, x -> x * 2]", "fs[1](5)"}, Number(10)},
+		{"Function Argument", []string{"apply(f, x) = f(x)", "apply(x -> x + 1, 1)"}, Number(2)},
+		{"Named Function As Value", []string{"sq(x) = x * x", "map(sq, 1, 2, 3)"}, List(Number(1), Number(4), Number(9))},
+		{"Map", []string{"map(x -> x * 2, 1, 2, 3)"}, List(Number(2), Number(4), Number(6))},
+		{"Map Nothing", []string{"map(x -> x)"}, List()},
+		{"Map To Bools", []string{"map(x -> x > 1, 1, 2)"}, List(Bool(false), Bool(true))},
+		{"Filter", []string{"filter(x -> x % 2 == 0, 1, 2, 3, 4)"}, List(Number(2), Number(4))},
+		{"Filter Everything", []string{"filter(x -> false, 1, 2)"}, List()},
+		{"Reduce", []string{"reduce((a, b) -> a + b, 1, 2, 3, 4)"}, Number(10)},
+		{"Reduce Left To Right", []string{"reduce((a, b) -> a - b, 10, 1, 2)"}, Number(7)},
+		{"Reduce One", []string{"reduce((a, b) -> a * b, 5)"}, Number(5)},
+		{"Reduce List", []string{"reduce((a, b) -> a + b, [1, 2, 3])"}, Number(6)},
+		{"Reduce List From Initial Value", []string{"reduce((a, b) -> a + b, [1, 2, 3], 0)"}, Number(6)},
+		{"Reduce Left From Initial Value", []string{"reduce((a, b) -> a - b, [1, 2], 10)"}, Number(7)},
+		{"Reduce Empty List From Initial Value", []string{"reduce((a, b) -> a + b, [], 5)"}, Number(5)},
+		{"Sum", []string{"sum(k -> k, 1, 100)"}, Number(5050)},
+		{"Sum Of Squares", []string{"sum(k -> k^2, 1, 3)"}, Number(14)},
+		{"Empty Sum", []string{"sum(k -> k, 1, 0)"}, Number(0)},
+		{"Product", []string{"product(k -> k, 1, 5)"}, Number(120)},
+		{"Empty Product", []string{"product(k -> k, 5, 4)"}, Number(1)},
+		{"Series Over Globals", []string{"r = 0.5", "sum(k -> r^k, 0, 2)"}, Number(1.75)},
+		{"Lambda Equality", []string{"f = x -> x", "g = f", "f == g"}, Bool(true)},
+		{"List Equality", []string{"map(x -> x, 1, 2) == filter(x -> true, 1, 2)"}, Bool(true)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := evalLines(t, NewEvaluator(nil, nil), tt.lines...)
+			if err != nil {
+				t.Fatalf("Eval() returned an error: %v", err)
+			}
+			if !result.Equal(tt.expected) {
+				t.Errorf("Expected %v, but got %v", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestHigherOrderFunctionErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		lines    []string
+		expected string
+	}{
+		{"Not A Function", []string{"map(1, 2)"}, "map expects a function as its first argument, got a number"},
+		{"Missing Function", []string{"map()"}, "map expects at least 1 argument, got 0"},
+		{"Lambda Arity", []string{"map((a, b) -> a, 1)"}, "lambda expects 2 arguments, got 1"},
+		{"Named Lambda Arity", []string{"f = x -> x", "f(1, 2)"}, "f expects 1 argument, got 2"},
+		{"Error In Lambda", []string{"map(x -> 1 / x, 1, 0)"}, "in lambda: division by zero"},
+		{"Error In Named Function", []string{"inv(x) = 1 / x", "map(inv, 0)"}, "in inv: division by zero"},
+		{"Filter Not A Bool", []string{"filter(x -> x, 1)"}, "filter expects its function to return a bool, got a number"},
+		{"Reduce Nothing", []string{"reduce((a, b) -> a + b)"}, "reduce expects at least 2 arguments, got 1"},
+		{"Fractional Bound", []string{"sum(k -> k, 1, 2.5)"}, "sum expects whole numbers as bounds, got 2.5"},
+		{"Bool Bound", []string{"product(k -> k, true, 2)"}, "product expects whole numbers as bounds, got true"},
+		{"Too Many Terms", []string{"sum(k -> k, 1, 10^9)"}, "sum: too many terms, at most 1000000 are allowed"},
+		{"Bounds Past 2^53", []string{"sum(x -> x, 100000000000000000, 100000000000000100)"}, "sum expects bounds of at most 2^53 in size, got 1e+17"},
+		{"Reduce List And Values", []string{"reduce((a, b) -> a + b, [1, 2], 3, 4)"}, "reduce expects a list and an initial value, or separate values"},
+		{"Term Not A Number", []string{"sum(k -> k > 1, 1, 2)"}, "sum expects its function to return a number, got a bool"},
+		{"Redefine Builtin", []string{"map(x) = x"}, "cannot redefine built-in function map"},
+		{"Constant Parameter", []string{"pi -> 1"}, "cannot use constant pi as a parameter"},
+		{"List Power", []string{"map(x -> x, 1) ^ 2"}, "operator ^ expects numbers, got list and number"},
+		{"List Argument", []string{"sqrt(map(x -> x, 1))"}, "sqrt expects a number, got a list"},
+		{"Call A Number", []string{"(1 + 2)(3)"}, "cannot call a number"},
+		{"Call Result Arity", []string{"adder(n) = x -> x + n", "adder(3)(4, 5)"}, "lambda expects 1 argument, got 2"},
+		{"Error In Called Lambda", []string{"(x -> 1 / x)(0)"}, "in lambda: division by zero"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := evalLines(t, NewEvaluator(nil, nil), tt.lines...)
+			var d diagnostic.Diagnostic
+			if !errors.As(err, &d) {
+				t.Fatalf("Expected a diagnostic.Diagnostic, got %T (%v)", err, err)
+			}
+			if d.Message != tt.expected {
+				t.Errorf("Expected error %q, but got %q", tt.expected, d.Message)
+			}
+		})
+	}
+}
+
+func TestHigherOrderRecursionDepth(t *testing.T) {
+	// calls made by the built-ins count towards the depth limit too
+	evaluator := NewEvaluator(nil, nil)
+	evaluator.SetMaxDepth(5)
+	_, err := evalLines(t, evaluator, "f(n) = n == 0 ? 0 : sum(k -> f(n - 1), 1, 1)", "f(10)")
+	var d diagnostic.Diagnostic
+	if !errors.As(err, &d) || d.Message != "in f: maximum recursion depth of 5 exceeded" {
+		t.Errorf("Expected a recursion depth error, got %v", err)
+	}
+}
@@ -0,0 +1,297 @@
+package eval
+
+import (
+	"basic-arithmetic-parser/ast"
+	"basic-arithmetic-parser/diagnostic"
+	"basic-arithmetic-parser/token"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// IntResult is a value computed by EvalInt: an int64, or a float64 once a
+// floating point value has been mixed in
+type IntResult struct {
+	Int     int64
+	Float   float64
+	IsFloat bool
+}
+
+// float returns the value as a float64, promoting an integer
+func (r IntResult) float() float64 {
+	if r.IsFloat {
+		return r.Float
+	}
+	return float64(r.Int)
+}
+
+func (r IntResult) String() string {
+	if r.IsFloat {
+		return fmt.Sprintf("%g", r.Float)
+	}
+	return strconv.FormatInt(r.Int, 10)
+}
+
+// OverflowError is returned by EvalInt when the result of an integer
+// operation, or an integer literal, does not fit in an int64. It unwraps to
+// the diagnostic.Diagnostic locating the overflowing sub-expression.
+type OverflowError struct {
+	Diagnostic diagnostic.Diagnostic
+}
+
+func (e *OverflowError) Error() string {
+	return e.Diagnostic.Error()
+}
+
+func (e *OverflowError) Unwrap() error {
+	return e.Diagnostic
+}
+
+// newOverflowError returns an OverflowError located at node
+func newOverflowError(node ast.Node, msg string) error {
+	return &OverflowError{Diagnostic: diagnostic.Diagnostic{Span: node.Span(), Message: msg}}
+}
+
+var errIntOverflow = errors.New("integer overflow")
+
+// intEvaluator evaluates with checked int64 arithmetic
+type intEvaluator struct {
+	strict bool
+}
+
+// EvalInt evaluates the given AST node with checked 64-bit integer
+// arithmetic. Literals without a decimal point are int64 values; + - * and
+// ^ return an *OverflowError instead of wrapping, / truncates towards zero,
+// and % // rem behave as they do for floats. Literals with a decimal point
+// and negative exponents produce floats: with strict set they are reported
+// as errors, otherwise the operation and everything that uses its result is
+// evaluated with float64. Variables and function calls are not supported.
+func EvalInt(node ast.Node, strict bool) (IntResult, error) {
+	e := &intEvaluator{strict: strict}
+	return e.eval(node)
+}
+
+// promote returns a float result, unless the evaluation is strict
+func (e *intEvaluator) promote(node ast.Node, value float64) (IntResult, error) {
+	if e.strict {
+		return IntResult{}, newError(node, "floating point value in strict integer mode")
+	}
+	return IntResult{Float: value, IsFloat: true}, nil
+}
+
+func (e *intEvaluator) eval(node ast.Node) (IntResult, error) {
+	switch n := node.(type) {
+	case *ast.NumberNode:
+		return e.number(n, "")
+	case *ast.BinaryOpNode:
+		leftVal, err := e.eval(n.Left)
+		if err != nil {
+			return IntResult{}, err
+		}
+		rightVal, err := e.eval(n.Right)
+		if err != nil {
+			return IntResult{}, err
+		}
+
+		if leftVal.IsFloat || rightVal.IsFloat {
+			result, err := floatBinaryOp(n, leftVal.float(), rightVal.float())
+			if err != nil {
+				return IntResult{}, err
+			}
+			return IntResult{Float: result, IsFloat: true}, nil
+		}
+		if n.Op.Type == token.POWER && rightVal.Int < 0 {
+			// the result is a fraction, unless the base is 0 (an error) or ±1
+			switch leftVal.Int {
+			case 0:
+				return IntResult{}, newError(n, "division by zero")
+			case 1, -1:
+				// (±1)^-n == (±1)^n, which only depends on whether n is odd
+				result, _ := powInt(leftVal.Int, rightVal.Int&1)
+				return IntResult{Int: result}, nil
+			}
+			return e.promote(n, math.Pow(float64(leftVal.Int), float64(rightVal.Int)))
+		}
+		result, err := intBinaryOp(n.Op, leftVal.Int, rightVal.Int)
+		switch {
+		case errors.Is(err, errIntOverflow):
+			return IntResult{}, newOverflowError(n, err.Error())
+		case err != nil:
+			return IntResult{}, newError(n, err.Error())
+		}
+		return IntResult{Int: result}, nil
+	case *ast.UnaryOpNode:
+		if number, ok := n.Expr.(*ast.NumberNode); ok && n.Op.Type == token.MINUS {
+			// read the sign with the literal, so that the most negative
+			// int64 can be written
+			return e.number(number, "-")
+		}
+		exprVal, err := e.eval(n.Expr)
+		if err != nil {
+			return IntResult{}, err
+		}
+
+		switch n.Op.Type {
+		case token.PLUS: // Unary plus (identity)
+			return exprVal, nil
+		case token.MINUS: // Unary minus (negation)
+			if exprVal.IsFloat {
+				return IntResult{Float: -exprVal.Float, IsFloat: true}, nil
+			}
+			if exprVal.Int == math.MinInt64 {
+				return IntResult{}, newOverflowError(n, errIntOverflow.Error())
+			}
+			return IntResult{Int: -exprVal.Int}, nil
+		default:
+			return IntResult{}, newError(n, fmt.Sprintf("unknown unary operator: %s", n.Op.Value))
+		}
+	case *ast.IdentNode:
+		return IntResult{}, newError(n, "variables are not supported in integer mode")
+	case *ast.AssignNode:
+		return IntResult{}, newError(n, "variables are not supported in integer mode")
+	case *ast.CallNode:
+		return IntResult{}, newError(n, "function calls are not supported in integer mode")
+	case *ast.ListNode, *ast.IndexNode, *ast.SliceNode:
+		return IntResult{}, listError(n)
+	case *ast.QuantityNode, *ast.ConvertNode:
+		return IntResult{}, unitsError(n)
+	case *ast.DateNode, *ast.DurationNode:
+		return IntResult{}, dateError(n)
+	case *ast.PercentNode:
+		return IntResult{}, percentError(n)
+	case *ast.BoolNode, *ast.ConditionalNode:
+		return IntResult{}, boolError(n)
+	case *ast.FunctionDefNode, *ast.LambdaNode, *ast.ApplyNode:
+		return IntResult{}, functionDefError(n)
+	case *ast.ErrorNode:
+		return IntResult{}, newError(n, "cannot evaluate an expression with syntax errors")
+	default:
+		return IntResult{}, fmt.Errorf("unknown node type: %T", node)
+	}
+}
+
+// number returns the value of a number literal, prefixed with sign
+func (e *intEvaluator) number(n *ast.NumberNode, sign string) (IntResult, error) {
+	if n.Imaginary {
+		return IntResult{}, imaginaryError(n)
+	}
+	value := n.Value
+	if sign == "-" {
+		value = -value
+	}
+	if n.Literal == "" {
+		// built without text: a whole value in range is an integer, and
+		// anything else, such as NaN or an infinity, is promoted
+		if value == math.Trunc(value) && value >= math.MinInt64 && value < math.MaxInt64 {
+			return IntResult{Int: int64(value)}, nil
+		}
+		return e.promote(n, value)
+	}
+	if strings.Contains(n.Literal, ".") {
+		return e.promote(n, value)
+	}
+	i, err := strconv.ParseInt(sign+n.Literal, 10, 64)
+	if err != nil {
+		return IntResult{}, newOverflowError(n, "integer literal out of range")
+	}
+	return IntResult{Int: i}, nil
+}
+
+// intBinaryOp applies op to two int64 operands, returning errIntOverflow if
+// the result does not fit
+func intBinaryOp(op token.Token, a, b int64) (int64, error) {
+	switch op.Type {
+	case token.PLUS:
+		sum := a + b
+		if (a > 0 && b > 0 && sum < 0) || (a < 0 && b < 0 && sum >= 0) {
+			return 0, errIntOverflow
+		}
+		return sum, nil
+	case token.MINUS:
+		diff := a - b
+		if (b > 0 && diff > a) || (b < 0 && diff < a) {
+			return 0, errIntOverflow
+		}
+		return diff, nil
+	case token.MULTIPLY:
+		return mulInt(a, b)
+	case token.DIVIDE:
+		if b == 0 {
+			return 0, errors.New("division by zero")
+		}
+		if a == math.MinInt64 && b == -1 {
+			return 0, errIntOverflow
+		}
+		// Go's integer division truncates towards zero
+		return a / b, nil
+	case token.MODULO:
+		if b == 0 {
+			return 0, errors.New("division by zero")
+		}
+		// floored: the result takes the sign of the divisor
+		r := a % b
+		if r != 0 && (r < 0) != (b < 0) {
+			r += b
+		}
+		return r, nil
+	case token.FLOOR_DIVIDE:
+		if b == 0 {
+			return 0, errors.New("division by zero")
+		}
+		if a == math.MinInt64 && b == -1 {
+			return 0, errIntOverflow
+		}
+		q := a / b
+		if a%b != 0 && (a < 0) != (b < 0) {
+			q--
+		}
+		return q, nil
+	case token.REM:
+		if b == 0 {
+			return 0, errors.New("division by zero")
+		}
+		// truncated: the result takes the sign of the dividend
+		return a % b, nil
+	case token.POWER:
+		return powInt(a, b)
+	default:
+		return 0, fmt.Errorf("operator %s is not supported in integer mode", op.Value)
+	}
+}
+
+// mulInt returns a*b, or errIntOverflow
+func mulInt(a, b int64) (int64, error) {
+	if a == 0 || b == 0 {
+		return 0, nil
+	}
+	product := a * b
+	if product/b != a || (a == -1 && b == math.MinInt64) || (b == -1 && a == math.MinInt64) {
+		return 0, errIntOverflow
+	}
+	return product, nil
+}
+
+// powInt returns base^exponent for exponent >= 0 by repeated squaring, or
+// errIntOverflow
+func powInt(base, exponent int64) (int64, error) {
+	result := int64(1)
+	for exponent > 0 {
+		var err error
+		if exponent&1 == 1 {
+			if result, err = mulInt(result, base); err != nil {
+				return 0, err
+			}
+		}
+		exponent >>= 1
+		// only square when the square is still needed, as it may overflow
+		// even when the result does not
+		if exponent > 0 {
+			if base, err = mulInt(base, base); err != nil {
+				return 0, err
+			}
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,113 @@
+package eval
+
+import (
+	"basic-arithmetic-parser/ast"
+	"basic-arithmetic-parser/diagnostic"
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestEvalInt(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"1 + 2 * 3", "7"},
+		{"7 / 2", "3"},
+		{"-7 / 2", "-3"},
+		{"-7 % 2", "1"},
+		{"7 % -3", "-2"},
+		{"-7 // 2", "-4"},
+		{"-7 rem 2", "-1"},
+		{"2 ^ 62", "4611686018427387904"},
+		{"(-2) ^ 63", "-9223372036854775808"},
+		{"9007199254740993 + 0", "9007199254740993"},
+		{"9223372036854775807", "9223372036854775807"},
+		{"-9223372036854775808", "-9223372036854775808"},
+		{"-9223372036854775807 - 1", "-9223372036854775808"},
+		{"(-1) ^ -3", "-1"},
+		{"1 ^ -2", "1"},
+		// mixing in floats promotes to float64
+		{"7 / 2.0", "3.5"},
+		{"1.5 * 2 + 1", "4"},
+		{"2 ^ -1", "0.5"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			program := mustParse(t, tt.input)
+
+			result, err := EvalInt(program, false)
+			if err != nil {
+				t.Fatalf("EvalInt() returned an error: %v", err)
+			}
+			if got := result.String(); got != tt.expected {
+				t.Errorf("Expected %s, but got %s", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestEvalIntErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		strict   bool
+		expected string
+		overflow bool
+	}{
+		{"9223372036854775807 + 1", false, "integer overflow", true},
+		{"-9223372036854775807 - 2", false, "integer overflow", true},
+		{"4294967296 * 4294967296", false, "integer overflow", true},
+		{"2 ^ 63", false, "integer overflow", true},
+		{"-(-9223372036854775807 - 1)", false, "integer overflow", true},
+		{"(-9223372036854775807 - 1) / -1", false, "integer overflow", true},
+		{"9223372036854775808", false, "integer literal out of range", true},
+		{"1 / 0", false, "division by zero", false},
+		{"1 % 0", false, "division by zero", false},
+		{"0 ^ -1", false, "division by zero", false},
+		{"1.5 + 1", true, "floating point value in strict integer mode", false},
+		{"2 ^ -1", true, "floating point value in strict integer mode", false},
+		{"x + 1", false, "variables are not supported in integer mode", false},
+		{"abs(1)", false, "function calls are not supported in integer mode", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			program := mustParse(t, tt.input)
+
+			_, err := EvalInt(program, tt.strict)
+			var d diagnostic.Diagnostic
+			if !errors.As(err, &d) {
+				t.Fatalf("Expected a diagnostic.Diagnostic, got %T (%v)", err, err)
+			}
+			if d.Message != tt.expected {
+				t.Errorf("Expected error %q, but got %q", tt.expected, d.Message)
+			}
+			var overflow *OverflowError
+			if errors.As(err, &overflow) != tt.overflow {
+				t.Errorf("Expected overflow %v, but got %T", tt.overflow, err)
+			}
+		})
+	}
+}
+
+func TestEvalIntWithoutLiteral(t *testing.T) {
+	// nodes built by hand have no literal text, so integral values are ints
+	result, err := EvalInt(&ast.NumberNode{Value: 3}, true)
+	if err != nil {
+		t.Fatalf("EvalInt() returned an error: %v", err)
+	}
+	if result.IsFloat || result.Int != 3 {
+		t.Errorf("Expected 3, but got %s", result)
+	}
+
+	// and other values are promoted, as a fractional literal would be
+	result, err = EvalInt(&ast.NumberNode{Value: math.Inf(1)}, false)
+	if err != nil {
+		t.Fatalf("EvalInt() returned an error: %v", err)
+	}
+	if !result.IsFloat || !math.IsInf(result.Float, 1) {
+		t.Errorf("Expected +Inf, but got %s", result)
+	}
+}
@@ -0,0 +1,416 @@
+package eval
+
+import (
+	"basic-arithmetic-parser/ast"
+	"basic-arithmetic-parser/token"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+)
+
+// Interval is the closed range of real numbers [Lo, Hi]. Either bound may
+// be infinite.
+type Interval struct {
+	Lo, Hi float64
+}
+
+func (i Interval) String() string {
+	return fmt.Sprintf("[%g, %g]", i.Lo, i.Hi)
+}
+
+// containsZero reports whether 0 lies in i
+func (i Interval) containsZero() bool {
+	return i.Lo <= 0 && i.Hi >= 0
+}
+
+// entire is the interval of all real numbers
+var entire = Interval{Lo: math.Inf(-1), Hi: math.Inf(1)}
+
+// down and up round x, the round-to-nearest result of an operation, to
+// the float64 at or below and at or above the exact result. err is the
+// exact result minus x, or has its sign.
+func down(x, err float64) float64 {
+	if err < 0 {
+		return math.Nextafter(x, math.Inf(-1))
+	}
+	return x
+}
+
+func up(x, err float64) float64 {
+	if err > 0 {
+		return math.Nextafter(x, math.Inf(1))
+	}
+	return x
+}
+
+// overflowError is the sign of the error of x when it is infinite: finite
+// operands overflowed, so the exact result is smaller in magnitude
+func overflowError(x float64, operands ...float64) float64 {
+	for _, operand := range operands {
+		if math.IsInf(operand, 0) {
+			return 0
+		}
+	}
+	return -x
+}
+
+// sumError returns the error of s = a+b, exactly (Knuth's TwoSum)
+func sumError(a, b, s float64) float64 {
+	if math.IsInf(s, 0) {
+		return overflowError(s, a, b)
+	}
+	bb := s - a
+	return (a - (s - bb)) + (b - bb)
+}
+
+// productError returns the error of p = a*b, exactly
+func productError(a, b, p float64) float64 {
+	if math.IsInf(p, 0) {
+		return overflowError(p, a, b)
+	}
+	return math.FMA(a, b, -p)
+}
+
+// quotientError returns a value with the sign of the error of q = a/b
+func quotientError(a, b, q float64) float64 {
+	if math.IsInf(q, 0) {
+		return overflowError(q, a, b)
+	}
+	if math.IsInf(b, 0) {
+		return 0
+	}
+	// a - q*b is exact, and the error is that divided by b
+	r := math.FMA(-q, b, a)
+	if b < 0 {
+		return -r
+	}
+	return r
+}
+
+func addDown(a, b float64) float64 { s := a + b; return down(s, sumError(a, b, s)) }
+func addUp(a, b float64) float64   { s := a + b; return up(s, sumError(a, b, s)) }
+
+// mulDown and mulUp take 0 × ∞ as 0: an infinite bound stands for
+// arbitrarily large finite values, whose product with 0 is 0
+func mulDown(a, b float64) float64 {
+	if a == 0 || b == 0 {
+		return 0
+	}
+	p := a * b
+	return down(p, productError(a, b, p))
+}
+
+func mulUp(a, b float64) float64 {
+	if a == 0 || b == 0 {
+		return 0
+	}
+	p := a * b
+	return up(p, productError(a, b, p))
+}
+
+func divDown(a, b float64) float64 { q := a / b; return down(q, quotientError(a, b, q)) }
+func divUp(a, b float64) float64   { q := a / b; return up(q, quotientError(a, b, q)) }
+
+// widen widens an interval computed by a function that is accurate to
+// within one ulp, but not correctly rounded, so that it still encloses the
+// exact result
+func widen(lo, hi float64) Interval {
+	return Interval{Lo: math.Nextafter(lo, math.Inf(-1)), Hi: math.Nextafter(hi, math.Inf(1))}
+}
+
+// widenNonNegative is widen for results that cannot be negative
+func widenNonNegative(lo, hi float64) Interval {
+	i := widen(lo, hi)
+	i.Lo = math.Max(i.Lo, 0)
+	return i
+}
+
+// intervalFunctions are the functions available in interval mode. All of
+// them are monotonic, so they map the bounds.
+var intervalFunctions = map[string]func(x Interval) (Interval, error){
+	"sqrt": func(x Interval) (Interval, error) {
+		if x.Lo < 0 {
+			return Interval{}, errNegativeSqrt
+		}
+		// math.Sqrt is correctly rounded, so the error can be found
+		lo, hi := math.Sqrt(x.Lo), math.Sqrt(x.Hi)
+		return Interval{Lo: down(lo, math.FMA(-lo, lo, x.Lo)), Hi: up(hi, math.FMA(-hi, hi, x.Hi))}, nil
+	},
+	"exp": func(x Interval) (Interval, error) {
+		return widenNonNegative(math.Exp(x.Lo), math.Exp(x.Hi)), nil
+	},
+	"ln": func(x Interval) (Interval, error) {
+		if x.Lo <= 0 {
+			return Interval{}, errNonPositiveLog
+		}
+		return widen(math.Log(x.Lo), math.Log(x.Hi)), nil
+	},
+	"abs": func(x Interval) (Interval, error) {
+		switch {
+		case x.Lo >= 0:
+			return x, nil
+		case x.Hi <= 0:
+			return Interval{Lo: -x.Hi, Hi: -x.Lo}, nil
+		default:
+			return Interval{Lo: 0, Hi: math.Max(-x.Lo, x.Hi)}, nil
+		}
+	},
+}
+
+// EvalInterval evaluates the given AST node with interval arithmetic,
+// returning an interval guaranteed to contain the exact result for every
+// value of its interval operands. Intervals are written [lo, hi] or x±r,
+// and number literals that float64 cannot represent exactly become the
+// narrowest interval around them. Every operation rounds outwards.
+//
+// Division by an interval containing zero gives the smallest interval
+// holding every quotient, which may be unbounded; only division by exactly
+// zero is an error. It supports + - * / ^, unary signs, the constants pi
+// and e, and the functions sqrt, exp, ln and abs.
+func EvalInterval(node ast.Node) (Interval, error) {
+	switch n := node.(type) {
+	case *ast.NumberNode:
+		if n.Imaginary {
+			return Interval{}, imaginaryError(n)
+		}
+		return literalInterval(n), nil
+	case *ast.ListNode:
+		// [lo, hi] is parsed as a list; here it is an interval
+		if len(n.Elems) != 2 {
+			return Interval{}, newError(n, fmt.Sprintf("an interval needs exactly 2 bounds, got %d", len(n.Elems)))
+		}
+		lo, err := EvalInterval(n.Elems[0])
+		if err != nil {
+			return Interval{}, err
+		}
+		hi, err := EvalInterval(n.Elems[1])
+		if err != nil {
+			return Interval{}, err
+		}
+		if lo.Lo > hi.Hi {
+			return Interval{}, newError(n, "lower bound is greater than upper bound")
+		}
+		return Interval{Lo: lo.Lo, Hi: hi.Hi}, nil
+	case *ast.IndexNode, *ast.SliceNode:
+		return Interval{}, listError(n)
+	case *ast.IdentNode:
+		switch n.Name {
+		// both constants are slightly below the true values
+		case "pi":
+			return Interval{Lo: math.Pi, Hi: math.Nextafter(math.Pi, 4)}, nil
+		case "e":
+			return Interval{Lo: math.E, Hi: math.Nextafter(math.E, 3)}, nil
+		default:
+			return Interval{}, newError(n, "variables are not supported in interval mode")
+		}
+	case *ast.BinaryOpNode:
+		leftVal, err := EvalInterval(n.Left)
+		if err != nil {
+			return Interval{}, err
+		}
+		rightVal, err := EvalInterval(n.Right)
+		if err != nil {
+			return Interval{}, err
+		}
+
+		var result Interval
+		switch n.Op.Type {
+		case token.PLUS:
+			result = Interval{Lo: addDown(leftVal.Lo, rightVal.Lo), Hi: addUp(leftVal.Hi, rightVal.Hi)}
+		case token.MINUS:
+			result = Interval{Lo: addDown(leftVal.Lo, -rightVal.Hi), Hi: addUp(leftVal.Hi, -rightVal.Lo)}
+		case token.PLUSMINUS:
+			if rightVal.Lo < 0 {
+				return Interval{}, newError(n.Right, "the radius after ± must not be negative")
+			}
+			result = Interval{Lo: addDown(leftVal.Lo, -rightVal.Hi), Hi: addUp(leftVal.Hi, rightVal.Hi)}
+		case token.MULTIPLY:
+			result = mulInterval(leftVal, rightVal)
+		case token.DIVIDE:
+			if rightVal.Lo == 0 && rightVal.Hi == 0 {
+				return Interval{}, newError(n, "division by zero")
+			}
+			result = divInterval(leftVal, rightVal)
+		case token.POWER:
+			result, err = powInterval(leftVal, rightVal)
+			if err != nil {
+				return Interval{}, newError(n, err.Error())
+			}
+		default:
+			return Interval{}, newError(n, fmt.Sprintf("operator %s is not supported in interval mode", n.Op.Value))
+		}
+		return result, nil
+	case *ast.UnaryOpNode:
+		exprVal, err := EvalInterval(n.Expr)
+		if err != nil {
+			return Interval{}, err
+		}
+
+		switch n.Op.Type {
+		case token.PLUS: // Unary plus (identity)
+			return exprVal, nil
+		case token.MINUS: // Unary minus (negation)
+			return Interval{Lo: -exprVal.Hi, Hi: -exprVal.Lo}, nil
+		default:
+			return Interval{}, newError(n, fmt.Sprintf("unknown unary operator: %s", n.Op.Value))
+		}
+	case *ast.CallNode:
+		f, ok := intervalFunctions[n.Name.Name]
+		if !ok {
+			return Interval{}, newError(n.Name, fmt.Sprintf("function %s is not supported in interval mode", n.Name.Name))
+		}
+		if err := checkArity(n.Name.Name, 1, 1, len(n.Args)); err != nil {
+			return Interval{}, newError(n, err.Error())
+		}
+		arg, err := EvalInterval(n.Args[0])
+		if err != nil {
+			return Interval{}, err
+		}
+		result, err := f(arg)
+		if err != nil {
+			return Interval{}, newError(n, fmt.Sprintf("%s: %v", n.Name.Name, err))
+		}
+		return result, nil
+	case *ast.AssignNode:
+		return Interval{}, newError(n, "variables are not supported in interval mode")
+	case *ast.QuantityNode, *ast.ConvertNode:
+		return Interval{}, unitsError(n)
+	case *ast.DateNode, *ast.DurationNode:
+		return Interval{}, dateError(n)
+	case *ast.PercentNode:
+		return Interval{}, percentError(n)
+	case *ast.BoolNode, *ast.ConditionalNode:
+		return Interval{}, boolError(n)
+	case *ast.FunctionDefNode, *ast.LambdaNode, *ast.ApplyNode:
+		return Interval{}, functionDefError(n)
+	case *ast.ErrorNode:
+		return Interval{}, newError(n, "cannot evaluate an expression with syntax errors")
+	default:
+		return Interval{}, fmt.Errorf("unknown node type: %T", node)
+	}
+}
+
+// literalInterval returns the value of a number literal: a single point if
+// float64 represents it exactly, and the two floats either side otherwise
+func literalInterval(n *ast.NumberNode) Interval {
+	exact, ok := new(big.Rat).SetString(literalText(n))
+	if !ok {
+		// infinities have no exact value to compare with
+		return Interval{Lo: n.Value, Hi: n.Value}
+	}
+	switch exact.Cmp(new(big.Rat).SetFloat64(n.Value)) {
+	case -1:
+		return Interval{Lo: math.Nextafter(n.Value, math.Inf(-1)), Hi: n.Value}
+	case 1:
+		return Interval{Lo: n.Value, Hi: math.Nextafter(n.Value, math.Inf(1))}
+	default:
+		return Interval{Lo: n.Value, Hi: n.Value}
+	}
+}
+
+// mulInterval returns the product of a and b, from the extremes of the
+// products of their bounds
+func mulInterval(a, b Interval) Interval {
+	lo := min(mulDown(a.Lo, b.Lo), mulDown(a.Lo, b.Hi), mulDown(a.Hi, b.Lo), mulDown(a.Hi, b.Hi))
+	hi := max(mulUp(a.Lo, b.Lo), mulUp(a.Lo, b.Hi), mulUp(a.Hi, b.Lo), mulUp(a.Hi, b.Hi))
+	return Interval{Lo: lo, Hi: hi}
+}
+
+// divInterval returns the smallest interval containing x/y for every x in a
+// and y in b other than 0. b must not be exactly [0, 0].
+func divInterval(a, b Interval) Interval {
+	if !b.containsZero() {
+		lo := min(divDown(a.Lo, b.Lo), divDown(a.Lo, b.Hi), divDown(a.Hi, b.Lo), divDown(a.Hi, b.Hi))
+		hi := max(divUp(a.Lo, b.Lo), divUp(a.Lo, b.Hi), divUp(a.Hi, b.Lo), divUp(a.Hi, b.Hi))
+		if math.IsNaN(lo) || math.IsNaN(hi) {
+			// ∞/∞: the quotient of two unbounded intervals can be anything
+			return entire
+		}
+		return Interval{Lo: lo, Hi: hi}
+	}
+	switch {
+	case a.containsZero(), b.Lo < 0 && b.Hi > 0:
+		// quotients of either sign grow without bound as y approaches 0
+		return entire
+	case b.Lo == 0:
+		// b is [0, d], and the quotient closest to 0 is divided by d
+		if a.Hi < 0 {
+			return Interval{Lo: math.Inf(-1), Hi: divUp(a.Hi, b.Hi)}
+		}
+		return Interval{Lo: divDown(a.Lo, b.Hi), Hi: math.Inf(1)}
+	default:
+		// b is [c, 0]
+		if a.Hi < 0 {
+			return Interval{Lo: divDown(a.Hi, b.Lo), Hi: math.Inf(1)}
+		}
+		return Interval{Lo: math.Inf(-1), Hi: divUp(a.Lo, b.Lo)}
+	}
+}
+
+// powDown and powUp return x^n for x >= 0 and n >= 0 by repeated squaring,
+// rounding every product in one direction
+func powDown(x float64, n int64) float64 {
+	result := 1.0
+	for ; n > 0; n >>= 1 {
+		if n&1 == 1 {
+			result = mulDown(result, x)
+		}
+		x = mulDown(x, x)
+	}
+	return result
+}
+
+func powUp(x float64, n int64) float64 {
+	result := 1.0
+	for ; n > 0; n >>= 1 {
+		if n&1 == 1 {
+			result = mulUp(result, x)
+		}
+		x = mulUp(x, x)
+	}
+	return result
+}
+
+// powInterval returns base^exponent. A single integer exponent can take
+// any base; otherwise the base must be positive, and x^y is then monotonic
+// in each argument so the extremes are at the corners.
+func powInterval(base, exponent Interval) (Interval, error) {
+	if exponent.Lo == exponent.Hi && exponent.Lo == math.Trunc(exponent.Lo) && math.Abs(exponent.Lo) <= math.MaxInt32 {
+		n := int64(exponent.Lo)
+		if n < 0 {
+			if base.Lo == 0 && base.Hi == 0 {
+				return Interval{}, errors.New("division by zero")
+			}
+			positive, _ := powInterval(base, Interval{Lo: float64(-n), Hi: float64(-n)})
+			return divInterval(Interval{Lo: 1, Hi: 1}, positive), nil
+		}
+		odd := n%2 == 1
+		switch {
+		case base.Lo >= 0:
+			return Interval{Lo: powDown(base.Lo, n), Hi: powUp(base.Hi, n)}, nil
+		case base.Hi <= 0:
+			// powers of -x, with the sign put back for odd n
+			lo, hi := powDown(-base.Hi, n), powUp(-base.Lo, n)
+			if odd {
+				return Interval{Lo: -hi, Hi: -lo}, nil
+			}
+			return Interval{Lo: lo, Hi: hi}, nil
+		case odd:
+			return Interval{Lo: -powUp(-base.Lo, n), Hi: powUp(base.Hi, n)}, nil
+		default:
+			// even powers have their minimum at 0
+			return Interval{Lo: 0, Hi: max(powUp(-base.Lo, n), powUp(base.Hi, n))}, nil
+		}
+	}
+
+	if base.Lo <= 0 {
+		return Interval{}, errors.New("fractional power of a number that may be negative")
+	}
+	corners := []float64{
+		math.Pow(base.Lo, exponent.Lo), math.Pow(base.Lo, exponent.Hi),
+		math.Pow(base.Hi, exponent.Lo), math.Pow(base.Hi, exponent.Hi),
+	}
+	// math.Pow is not correctly rounded, so allow for an ulp of error
+	return widenNonNegative(min(corners[0], corners[1], corners[2], corners[3]), max(corners[0], corners[1], corners[2], corners[3])), nil
+}
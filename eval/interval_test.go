@@ -0,0 +1,131 @@
+package eval
+
+import (
+	"basic-arithmetic-parser/diagnostic"
+	"errors"
+	"math"
+	"math/big"
+	"testing"
+)
+
+func TestEvalInterval(t *testing.T) {
+	inf := math.Inf(1)
+	tests := []struct {
+		input    string
+		expected Interval
+	}{
+		{"[1, 2] + [3, 4]", Interval{4, 6}},
+		{"[1, 2] - [3, 4]", Interval{-3, -1}},
+		{"[-1, 2] * [3, 4]", Interval{-4, 8}},
+		{"[-2, -1] * [-3, 4]", Interval{-8, 6}},
+		{"[1, 2] / [4, 8]", Interval{0.125, 0.5}},
+		{"2±0.5", Interval{1.5, 2.5}},
+		{"(2±0.5) * 2", Interval{3, 5}},
+		{"-[1, 2]", Interval{-2, -1}},
+		{"[-2, 3] ^ 2", Interval{0, 9}},
+		{"[-2, -1] ^ 2", Interval{1, 4}},
+		{"[-2, 1] ^ 3", Interval{-8, 1}},
+		{"[2, 4] ^ -1", Interval{0.25, 0.5}},
+		{"sqrt([4, 9])", Interval{2, 3}},
+		{"abs([-3, 2])", Interval{0, 3}},
+		// division by intervals containing zero
+		{"[1, 2] / [0, 4]", Interval{0.25, inf}},
+		{"[1, 2] / [-4, 0]", Interval{-inf, -0.25}},
+		{"[-2, -1] / [0, 4]", Interval{-inf, -0.25}},
+		{"[1, 2] / [-1, 1]", Interval{-inf, inf}},
+		{"[-1, 1] / [0, 1]", Interval{-inf, inf}},
+		{"3", Interval{3, 3}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			program := mustParse(t, tt.input)
+
+			result, err := EvalInterval(program)
+			if err != nil {
+				t.Fatalf("EvalInterval() returned an error: %v", err)
+			}
+			if result != tt.expected {
+				t.Errorf("Expected %s, but got %s", tt.expected, result)
+			}
+		})
+	}
+}
+
+// contains reports whether the exact value of the decimal string x lies in i
+func contains(i Interval, x string) bool {
+	exact, _ := new(big.Rat).SetString(x)
+	lo, hi := new(big.Rat).SetFloat64(i.Lo), new(big.Rat).SetFloat64(i.Hi)
+	return lo.Cmp(exact) <= 0 && exact.Cmp(hi) <= 0
+}
+
+func TestEvalIntervalEnclosure(t *testing.T) {
+	// the results are not representable, so the intervals must be strictly
+	// wider than a point and contain the exact values
+	tests := []struct {
+		input string
+		exact string
+	}{
+		{"0.1", "0.1"},
+		{"0.1 + 0.2", "0.3"},
+		{"1 / 3", "0.333333333333333333333333333333333333"},
+		{"[1, 1] / 3 * 3", "1"},
+		{"0.1 * 3", "0.3"},
+		{"sqrt(2)", "1.41421356237309504880168872420969807856967187537694"},
+		{"pi", "3.14159265358979323846264338327950288419716939937510"},
+		{"e", "2.71828182845904523536028747135266249775724709369995"},
+		{"ln(2)", "0.69314718055994530941723212145817656807550013436025"},
+		{"exp(1)", "2.71828182845904523536028747135266249775724709369995"},
+		{"2 ^ 0.5", "1.41421356237309504880168872420969807856967187537694"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			program := mustParse(t, tt.input)
+
+			result, err := EvalInterval(program)
+			if err != nil {
+				t.Fatalf("EvalInterval() returned an error: %v", err)
+			}
+			if result.Lo == result.Hi || !contains(result, tt.exact) {
+				t.Errorf("Expected an interval around %s, but got %s", tt.exact, result)
+			}
+		})
+	}
+}
+
+func TestEvalIntervalErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"1 / [0, 0]", "division by zero"},
+		{"[0, 0] ^ -1", "division by zero"},
+		{"[2, 1]", "lower bound is greater than upper bound"},
+		{"1 ± -0.5", "the radius after ± must not be negative"},
+		{"[-1, 2] ^ 0.5", "fractional power of a number that may be negative"},
+		{"sqrt([-1, 4])", "sqrt: square root of a negative number"},
+		{"ln([0, 1])", "ln: logarithm of a non-positive number"},
+		{"sin(1)", "function sin is not supported in interval mode"},
+		{"7 % 2", "operator % is not supported in interval mode"},
+		{"x + 1", "variables are not supported in interval mode"},
+		{"[1, 2, 3]", "an interval needs exactly 2 bounds, got 3"},
+		{"[1]", "an interval needs exactly 2 bounds, got 1"},
+		{"[1, 2][0]", "lists are only supported in float mode"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			program := mustParse(t, tt.input)
+
+			_, err := EvalInterval(program)
+			var d diagnostic.Diagnostic
+			if !errors.As(err, &d) {
+				t.Fatalf("Expected a diagnostic.Diagnostic, got %T (%v)", err, err)
+			}
+			if d.Message != tt.expected {
+				t.Errorf("Expected error %q, but got %q", tt.expected, d.Message)
+			}
+		})
+	}
+}
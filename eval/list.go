@@ -0,0 +1,144 @@
+package eval
+
+import (
+	"basic-arithmetic-parser/ast"
+	"basic-arithmetic-parser/token"
+	"fmt"
+	"math"
+)
+
+// evalList evaluates the elements of a list literal
+func (e *Evaluator) evalList(n *ast.ListNode) (Value, error) {
+	elems := make([]Value, len(n.Elems))
+	for i, elem := range n.Elems {
+		val, err := e.Eval(elem)
+		if err != nil {
+			return Value{}, err
+		}
+		elems[i] = val
+	}
+	return List(elems...), nil
+}
+
+// evalIndex evaluates v[i]. Indices start at 0, and negative ones count
+// from the end, so v[-1] is the last element.
+func (e *Evaluator) evalIndex(n *ast.IndexNode) (Value, error) {
+	list, err := e.evalIndexed(n.Expr)
+	if err != nil {
+		return Value{}, err
+	}
+	i, err := e.evalListIndex(n.Index)
+	if err != nil {
+		return Value{}, err
+	}
+	j := fromEnd(i, len(list))
+	if j < 0 || j >= len(list) {
+		return Value{}, newError(n.Index, fmt.Sprintf("index %d is out of range for a list of length %d", i, len(list)))
+	}
+	return list[j], nil
+}
+
+// evalSlice evaluates v[lo:hi], the elements from lo up to but not
+// including hi. Left-out bounds are the ends of the list, and bounds past
+// them are clamped, so v[:100] is the whole of a short list.
+func (e *Evaluator) evalSlice(n *ast.SliceNode) (Value, error) {
+	list, err := e.evalIndexed(n.Expr)
+	if err != nil {
+		return Value{}, err
+	}
+	lo, hi := 0, len(list)
+	if n.Lo != nil {
+		if lo, err = e.evalListIndex(n.Lo); err != nil {
+			return Value{}, err
+		}
+		lo = fromEnd(lo, len(list))
+	}
+	if n.Hi != nil {
+		if hi, err = e.evalListIndex(n.Hi); err != nil {
+			return Value{}, err
+		}
+		hi = fromEnd(hi, len(list))
+	}
+	lo = min(max(lo, 0), len(list))
+	hi = min(max(hi, lo), len(list))
+	return List(list[lo:hi]...), nil
+}
+
+// evalIndexed evaluates the list being indexed or sliced
+func (e *Evaluator) evalIndexed(node ast.Node) ([]Value, error) {
+	val, err := e.Eval(node)
+	if err != nil {
+		return nil, err
+	}
+	if val.Kind != ListKind {
+		return nil, newError(node, fmt.Sprintf("cannot index a %s", val.Kind))
+	}
+	return val.List, nil
+}
+
+// evalListIndex evaluates an index or slice bound
+func (e *Evaluator) evalListIndex(node ast.Node) (int, error) {
+	val, err := e.Eval(node)
+	if err != nil {
+		return 0, err
+	}
+	if val.Kind != NumberKind || val.Number != math.Trunc(val.Number) || math.IsInf(val.Number, 0) {
+		return 0, newError(node, fmt.Sprintf("list index must be a whole number, got %s", val))
+	}
+	return int(val.Number), nil
+}
+
+// fromEnd turns a negative index into a list of the given length into the
+// index it counts back to
+func fromEnd(i, length int) int {
+	if i < 0 {
+		return i + length
+	}
+	return i
+}
+
+// isElementwise reports whether the operator of n applies element by
+// element when an operand is a list
+func isElementwise(n *ast.BinaryOpNode) bool {
+	switch n.Op.Type {
+	case token.PLUS, token.MINUS, token.MULTIPLY, token.DIVIDE, token.OF:
+		return true
+	}
+	return false
+}
+
+// broadcast applies the operator of n element by element. Two lists must
+// have the same length and are combined pairwise; a list and a scalar
+// combine the scalar with each element, so [1, 2] * 10 is [10, 20].
+func broadcast(n *ast.BinaryOpNode, leftVal, rightVal Value) (Value, error) {
+	var elems []Value
+	var err error
+	switch {
+	case leftVal.Kind == ListKind && rightVal.Kind == ListKind:
+		if len(leftVal.List) != len(rightVal.List) {
+			return Value{}, newError(n, fmt.Sprintf("shape mismatch: cannot %s lists of lengths %d and %d",
+				operatorVerbs[n.Op.Type], len(leftVal.List), len(rightVal.List)))
+		}
+		elems = make([]Value, len(leftVal.List))
+		for i := range elems {
+			if elems[i], err = valueBinaryOp(n, leftVal.List[i], rightVal.List[i]); err != nil {
+				return Value{}, err
+			}
+		}
+	case leftVal.Kind == ListKind:
+		elems = make([]Value, len(leftVal.List))
+		for i := range elems {
+			if elems[i], err = valueBinaryOp(n, leftVal.List[i], rightVal); err != nil {
+				return Value{}, err
+			}
+		}
+	default:
+		elems = make([]Value, len(rightVal.List))
+		for i := range elems {
+			if elems[i], err = valueBinaryOp(n, leftVal, rightVal.List[i]); err != nil {
+				return Value{}, err
+			}
+		}
+	}
+	return List(elems...), nil
+}
@@ -0,0 +1,99 @@
+package eval
+
+import (
+	"basic-arithmetic-parser/diagnostic"
+	"errors"
+	"testing"
+)
+
+func TestLists(t *testing.T) {
+	tests := []struct {
+		name     string
+		lines    []string
+		expected Value
+	}{
+		{"Literal", []string{"[1, 2 + 3, 2 > 1]"}, List(Number(1), Number(5), Bool(true))},
+		{"Empty", []string{"[]"}, List()},
+		{"Nested", []string{"[[1], []]"}, List(List(Number(1)), List())},
+		{"Index", []string{"v = [10, 20, 30]", "v[1]"}, Number(20)},
+		{"Negative Index", []string{"v = [10, 20, 30]", "v[-1]"}, Number(30)},
+		{"Computed Index", []string{"v = [10, 20, 30]", "i = 1", "v[i + 1]"}, Number(30)},
+		{"Nested Index", []string{"m = [[1, 2], [3, 4]]", "m[1][0]"}, Number(3)},
+		{"Index Literal", []string{"[1, 2, 3][0]"}, Number(1)},
+		{"Index Call", []string{"map(x -> x * 2, 1, 2)[1]"}, Number(4)},
+		{"Slice", []string{"v = [1, 2, 3, 4]", "v[1:3]"}, List(Number(2), Number(3))},
+		{"Slice From Start", []string{"v = [1, 2, 3, 4]", "v[:2]"}, List(Number(1), Number(2))},
+		{"Slice To End", []string{"v = [1, 2, 3, 4]", "v[2:]"}, List(Number(3), Number(4))},
+		{"Slice Negative", []string{"v = [1, 2, 3, 4]", "v[-2:]"}, List(Number(3), Number(4))},
+		{"Slice Everything", []string{"v = [1, 2]", "v[:]"}, List(Number(1), Number(2))},
+		{"Slice Clamped", []string{"v = [1, 2]", "v[1:100]"}, List(Number(2))},
+		{"Slice Empty", []string{"v = [1, 2]", "v[2:1]"}, List()},
+		{"Len", []string{"len([1, 2, 3])"}, Number(3)},
+		{"Len Empty", []string{"len([])"}, Number(0)},
+		{"Add Scalar", []string{"[1, 2, 3] + 1"}, List(Number(2), Number(3), Number(4))},
+		{"Scalar Minus List", []string{"10 - [1, 2]"}, List(Number(9), Number(8))},
+		{"Multiply Scalar", []string{"2 * [1, 2]"}, List(Number(2), Number(4))},
+		{"Divide Scalar", []string{"[2, 4] / 2"}, List(Number(1), Number(2))},
+		{"Add Lists", []string{"[1, 2] + [10, 20]"}, List(Number(11), Number(22))},
+		{"Multiply Lists", []string{"[1, 2] * [3, 4]"}, List(Number(3), Number(8))},
+		{"Nested Broadcast", []string{"[[1, 2], [3]] * 2"}, List(List(Number(2), Number(4)), List(Number(6)))},
+		{"Empty Broadcast", []string{"[] + 1"}, List()},
+		{"Negate", []string{"-[1, -2]"}, List(Number(-1), Number(2))},
+		{"Relative Percent", []string{"[100, 200] + 10%"}, List(Number(110), Number(220))},
+		{"Percent Of", []string{"50% of [10, 20]"}, List(Number(5), Number(10))},
+		{"Map List", []string{"map(x -> x^2, [1, 2, 3])"}, List(Number(1), Number(4), Number(9))},
+		{"Filter List", []string{"filter(x -> x > 1, [1, 2, 3])"}, List(Number(2), Number(3))},
+		{"Reduce List", []string{"reduce((a, b) -> a + b, [1, 2, 3])"}, Number(6)},
+		{"List Equality", []string{"[1, 2] + 1 == [2, 3]"}, Bool(true)},
+		{"Lambda Over List", []string{"norm2(v) = reduce((a, b) -> a + b, v * v)", "norm2([3, 4])"}, Number(25)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := evalLines(t, NewEvaluator(nil, nil), tt.lines...)
+			if err != nil {
+				t.Fatalf("Eval() returned an error: %v", err)
+			}
+			if !result.Equal(tt.expected) {
+				t.Errorf("Expected %v, but got %v", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestListErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		lines    []string
+		expected string
+	}{
+		{"Shape Mismatch", []string{"[1, 2] + [1, 2, 3]"}, "shape mismatch: cannot add lists of lengths 2 and 3"},
+		{"Nested Shape Mismatch", []string{"[[1, 2]] * [[1]]"}, "shape mismatch: cannot multiply lists of lengths 2 and 1"},
+		{"Element Division By Zero", []string{"[1, 2] / [1, 0]"}, "division by zero"},
+		{"Element Not A Number", []string{"[1, true] + 1"}, "cannot add bool and number"},
+		{"Not Elementwise", []string{"[1, 2] % 2"}, "operator % expects numbers, got list and number"},
+		{"Index Out Of Range", []string{"[1, 2][2]"}, "index 2 is out of range for a list of length 2"},
+		{"Negative Index Out Of Range", []string{"[1, 2][-3]"}, "index -3 is out of range for a list of length 2"},
+		{"Fractional Index", []string{"[1, 2][0.5]"}, "list index must be a whole number, got 0.5"},
+		{"Bool Index", []string{"[1, 2][true]"}, "list index must be a whole number, got true"},
+		{"Fractional Slice Bound", []string{"[1, 2][:1.5]"}, "list index must be a whole number, got 1.5"},
+		{"Index A Number", []string{"x = 1", "x[0]"}, "cannot index a number"},
+		{"Len Of A Number", []string{"len(1)"}, "len expects a list, got a number"},
+		{"Len Arity", []string{"len([1], [2])"}, "len expects 1 argument, got 2"},
+		{"Reduce Empty List", []string{"reduce((a, b) -> a + b, [])"}, "reduce expects at least 1 value, got an empty list"},
+		{"Not Of A List", []string{"![true]"}, "operator ! expects a bool, got a list"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := evalLines(t, NewEvaluator(nil, nil), tt.lines...)
+			var d diagnostic.Diagnostic
+			if !errors.As(err, &d) {
+				t.Fatalf("Expected a diagnostic.Diagnostic, got %T (%v)", err, err)
+			}
+			if d.Message != tt.expected {
+				t.Errorf("Expected error %q, but got %q", tt.expected, d.Message)
+			}
+		})
+	}
+}
@@ -0,0 +1,432 @@
+package eval
+
+import (
+	"basic-arithmetic-parser/ast"
+	"fmt"
+	"math"
+)
+
+// maxMatrixElements bounds the size of the matrices identity and zeros
+// build, so a typo in a dimension does not exhaust memory
+const maxMatrixElements = 1_000_000
+
+// singularTolerance is how small a pivot may be, relative to the largest
+// element of the matrix, before the matrix is taken to be singular. Without
+// it, rounding makes [[1, 2, 3], [4, 5, 6], [7, 8, 9]] invertible.
+const singularTolerance = 1e-12
+
+// matrix is a dense matrix of numbers, stored as its rows. A matrix Value is
+// a list of rows, each a list of numbers of the same length.
+type matrix [][]float64
+
+// newMatrix returns a rows x cols matrix of zeros
+func newMatrix(rows, cols int) matrix {
+	m := make(matrix, rows)
+	for i := range m {
+		m[i] = make([]float64, cols)
+	}
+	return m
+}
+
+func identityMatrix(n int) matrix {
+	m := newMatrix(n, n)
+	for i := range m {
+		m[i][i] = 1
+	}
+	return m
+}
+
+func (m matrix) rows() int {
+	return len(m)
+}
+
+func (m matrix) cols() int {
+	return len(m[0])
+}
+
+func (m matrix) clone() matrix {
+	c := make(matrix, len(m))
+	for i, row := range m {
+		c[i] = append([]float64(nil), row...)
+	}
+	return c
+}
+
+// String describes the shape of m for errors, e.g. a 2x3 matrix
+func (m matrix) String() string {
+	return fmt.Sprintf("a %dx%d matrix", m.rows(), m.cols())
+}
+
+func (m matrix) value() Value {
+	rows := make([]Value, len(m))
+	for i, row := range m {
+		rows[i] = vectorValue(row)
+	}
+	return List(rows...)
+}
+
+func vectorValue(v []float64) Value {
+	elems := make([]Value, len(v))
+	for i, x := range v {
+		elems[i] = Number(x)
+	}
+	return List(elems...)
+}
+
+// isVector reports whether v is a list of numbers rather than of rows. The
+// empty list is a vector of length 0.
+func isVector(v Value) bool {
+	return v.Kind == ListKind && (len(v.List) == 0 || v.List[0].Kind != ListKind)
+}
+
+// asVector returns the numbers of the vector v, which name expects at node
+func asVector(node ast.Node, name string, v Value) ([]float64, error) {
+	if !isVector(v) {
+		return nil, newError(node, fmt.Sprintf("%s expects a vector, got %s", name, v))
+	}
+	vec := make([]float64, len(v.List))
+	for i, elem := range v.List {
+		if elem.Kind != NumberKind {
+			return nil, newError(node, fmt.Sprintf("%s expects a vector of numbers, got a %s", name, elem.Kind))
+		}
+		vec[i] = elem.Number
+	}
+	return vec, nil
+}
+
+// asMatrix returns the matrix held by v, which name expects at node: a
+// non-empty list of rows of numbers, all of the same non-zero length
+func asMatrix(node ast.Node, name string, v Value) (matrix, error) {
+	if v.Kind != ListKind || len(v.List) == 0 || isVector(v) {
+		return nil, newError(node, fmt.Sprintf("%s expects a matrix, got %s", name, v))
+	}
+	m := make(matrix, len(v.List))
+	for i, row := range v.List {
+		if row.Kind != ListKind || len(row.List) == 0 {
+			return nil, newError(node, fmt.Sprintf("%s expects a matrix, got %s", name, v))
+		}
+		if len(row.List) != len(v.List[0].List) {
+			return nil, newError(node, fmt.Sprintf("%s expects a matrix, but its rows have lengths %d and %d",
+				name, len(v.List[0].List), len(row.List)))
+		}
+		var err error
+		if m[i], err = asVector(node, name, row); err != nil {
+			return nil, newError(node, fmt.Sprintf("%s expects a matrix of numbers, got %s", name, v))
+		}
+	}
+	return m, nil
+}
+
+// asSquareMatrix is asMatrix for the built-ins that need as many rows as
+// columns
+func asSquareMatrix(node ast.Node, name string, v Value) (matrix, error) {
+	m, err := asMatrix(node, name, v)
+	if err != nil {
+		return nil, err
+	}
+	if m.rows() != m.cols() {
+		return nil, newError(node, fmt.Sprintf("%s expects a square matrix, got %s", name, m))
+	}
+	return m, nil
+}
+
+// matmul evaluates a @ b. Either operand may be a vector, which is a row
+// on the left and a column on the right, so a matrix times a vector is a
+// vector and two vectors give their dot product.
+func matmul(n *ast.BinaryOpNode, leftVal, rightVal Value) (Value, error) {
+	left, leftVector, err := matmulOperand(n, leftVal)
+	if err != nil {
+		return Value{}, err
+	}
+	right, rightVector, err := matmulOperand(n, rightVal)
+	if err != nil {
+		return Value{}, err
+	}
+	if rightVector {
+		// a column vector is one column of one number per row
+		column := newMatrix(len(right[0]), 1)
+		for i, x := range right[0] {
+			column[i][0] = x
+		}
+		right = column
+	}
+	if len(left[0]) != len(right) {
+		return Value{}, newError(n, fmt.Sprintf("dimension mismatch: cannot multiply %s by %s",
+			describeOperand(left, leftVector), describeOperand(right, rightVector)))
+	}
+
+	product := multiply(left, right)
+	switch {
+	case leftVector && rightVector:
+		return Number(product[0][0]), nil
+	case leftVector:
+		return vectorValue(product[0]), nil
+	case rightVector:
+		column := make([]float64, product.rows())
+		for i, row := range product {
+			column[i] = row[0]
+		}
+		return vectorValue(column), nil
+	default:
+		return product.value(), nil
+	}
+}
+
+// matmulOperand returns an operand of @ as a matrix, with a vector as a
+// single row, and whether it was a vector
+func matmulOperand(n *ast.BinaryOpNode, v Value) (matrix, bool, error) {
+	if v.Kind == ListKind && len(v.List) == 0 {
+		return nil, false, newError(n, "operator @ expects matrices or vectors, got []")
+	}
+	if isVector(v) {
+		vec, err := asVector(n, "operator @", v)
+		if err != nil {
+			return nil, false, err
+		}
+		return matrix{vec}, true, nil
+	}
+	if v.Kind != ListKind {
+		return nil, false, newError(n, fmt.Sprintf("operator @ expects matrices or vectors, got a %s", v.Kind))
+	}
+	m, err := asMatrix(n, "operator @", v)
+	return m, false, err
+}
+
+// describeOperand describes an operand of @ for errors
+func describeOperand(m matrix, vector bool) string {
+	if !vector {
+		return m.String()
+	}
+	if len(m) == 1 {
+		return fmt.Sprintf("a vector of length %d", len(m[0]))
+	}
+	return fmt.Sprintf("a vector of length %d", len(m))
+}
+
+// multiply returns a b, where a has as many columns as b has rows
+func multiply(a, b matrix) matrix {
+	product := newMatrix(len(a), len(b[0]))
+	for i := range a {
+		for j := range b[0] {
+			for k := range b {
+				product[i][j] += a[i][k] * b[k][j]
+			}
+		}
+	}
+	return product
+}
+
+func transpose(m matrix) matrix {
+	t := newMatrix(m.cols(), m.rows())
+	for i, row := range m {
+		for j, x := range row {
+			t[j][i] = x
+		}
+	}
+	return t
+}
+
+// maxAbs returns the largest absolute value of the elements of m
+func maxAbs(m matrix) float64 {
+	largest := 0.0
+	for _, row := range m {
+		for _, x := range row {
+			largest = math.Max(largest, math.Abs(x))
+		}
+	}
+	return largest
+}
+
+// pivot swaps the row from col down with the largest element in column col
+// into row col, and reports whether that element is large enough to divide
+// by. swapped is whether rows were exchanged.
+func pivot(a, b matrix, col int, tolerance float64) (ok, swapped bool) {
+	best := col
+	for i := col + 1; i < len(a); i++ {
+		if math.Abs(a[i][col]) > math.Abs(a[best][col]) {
+			best = i
+		}
+	}
+	if math.Abs(a[best][col]) <= tolerance {
+		return false, false
+	}
+	if best == col {
+		return true, false
+	}
+	a[col], a[best] = a[best], a[col]
+	if b != nil {
+		b[col], b[best] = b[best], b[col]
+	}
+	return true, true
+}
+
+// determinant returns det(a) by Gaussian elimination with partial
+// pivoting. A singular matrix gives exactly 0.
+func determinant(a matrix) float64 {
+	a = a.clone()
+	tolerance := singularTolerance * maxAbs(a)
+	det := 1.0
+	for col := range a {
+		ok, swapped := pivot(a, nil, col, tolerance)
+		if !ok {
+			return 0
+		}
+		if swapped {
+			det = -det
+		}
+		det *= a[col][col]
+		for i := col + 1; i < len(a); i++ {
+			factor := a[i][col] / a[col][col]
+			for j := col; j < len(a); j++ {
+				a[i][j] -= factor * a[col][j]
+			}
+		}
+	}
+	return det
+}
+
+// solveLinear returns x such that a x = b, by Gauss-Jordan elimination with
+// partial pivoting, or false if a is singular
+func solveLinear(a, b matrix) (matrix, bool) {
+	a, b = a.clone(), b.clone()
+	tolerance := singularTolerance * maxAbs(a)
+	for col := range a {
+		if ok, _ := pivot(a, b, col, tolerance); !ok {
+			return nil, false
+		}
+		p := a[col][col]
+		for j := range a[col] {
+			a[col][j] /= p
+		}
+		for j := range b[col] {
+			b[col][j] /= p
+		}
+		for i := range a {
+			if i == col || a[i][col] == 0 {
+				continue
+			}
+			factor := a[i][col]
+			for j := range a[i] {
+				a[i][j] -= factor * a[col][j]
+			}
+			for j := range b[i] {
+				b[i][j] -= factor * b[col][j]
+			}
+		}
+	}
+	return b, true
+}
+
+// matrixDimension returns a dimension given to identity or zeros
+func matrixDimension(n *ast.CallNode, v Value) (int, error) {
+	if v.Kind != NumberKind || v.Number != math.Trunc(v.Number) || v.Number < 1 {
+		return 0, newError(n, fmt.Sprintf("%s expects positive whole numbers as dimensions, got %s", n.Name.Name, v))
+	}
+	if v.Number > maxMatrixElements {
+		return 0, newError(n, fmt.Sprintf("%s: too many elements, at most %d are allowed", n.Name.Name, maxMatrixElements))
+	}
+	return int(v.Number), nil
+}
+
+// transposeBuiltin is transpose(A)
+func transposeBuiltin(e *Evaluator, n *ast.CallNode, args []Value) (Value, error) {
+	m, err := asMatrix(n.Args[0], "transpose", args[0])
+	if err != nil {
+		return Value{}, err
+	}
+	return transpose(m).value(), nil
+}
+
+// detBuiltin is det(A)
+func detBuiltin(e *Evaluator, n *ast.CallNode, args []Value) (Value, error) {
+	m, err := asSquareMatrix(n.Args[0], "det", args[0])
+	if err != nil {
+		return Value{}, err
+	}
+	return Number(determinant(m)), nil
+}
+
+// inverseBuiltin is inverse(A)
+func inverseBuiltin(e *Evaluator, n *ast.CallNode, args []Value) (Value, error) {
+	m, err := asSquareMatrix(n.Args[0], "inverse", args[0])
+	if err != nil {
+		return Value{}, err
+	}
+	inv, ok := solveLinear(m, identityMatrix(m.rows()))
+	if !ok {
+		return Value{}, newError(n, "inverse: matrix is singular")
+	}
+	return inv.value(), nil
+}
+
+// solveBuiltin is solve(A, b), the x for which A @ x == b. b is a vector,
+// giving a vector, or a matrix, giving a matrix with a column of x for each
+// of its columns.
+func solveBuiltin(e *Evaluator, n *ast.CallNode, args []Value) (Value, error) {
+	a, err := asSquareMatrix(n.Args[0], "solve", args[0])
+	if err != nil {
+		return Value{}, err
+	}
+
+	var b matrix
+	var shape string
+	vector := isVector(args[1])
+	if vector {
+		vec, err := asVector(n.Args[1], "solve", args[1])
+		if err != nil {
+			return Value{}, err
+		}
+		b = newMatrix(len(vec), 1)
+		for i, x := range vec {
+			b[i][0] = x
+		}
+		shape = fmt.Sprintf("a vector of length %d", len(vec))
+	} else {
+		if b, err = asMatrix(n.Args[1], "solve", args[1]); err != nil {
+			return Value{}, err
+		}
+		shape = b.String()
+	}
+	if b.rows() != a.rows() {
+		return Value{}, newError(n, fmt.Sprintf("solve: dimension mismatch: %s and %s", a, shape))
+	}
+
+	x, ok := solveLinear(a, b)
+	if !ok {
+		return Value{}, newError(n, "solve: matrix is singular")
+	}
+	if vector {
+		return vectorValue(transpose(x)[0]), nil
+	}
+	return x.value(), nil
+}
+
+// identityBuiltin is identity(n), the n x n identity matrix
+func identityBuiltin(e *Evaluator, n *ast.CallNode, args []Value) (Value, error) {
+	size, err := matrixDimension(n, args[0])
+	if err != nil {
+		return Value{}, err
+	}
+	if size*size > maxMatrixElements {
+		return Value{}, newError(n, fmt.Sprintf("identity: too many elements, at most %d are allowed", maxMatrixElements))
+	}
+	return identityMatrix(size).value(), nil
+}
+
+// zerosBuiltin is zeros(n), the n x n matrix of zeros, or zeros(rows, cols)
+func zerosBuiltin(e *Evaluator, n *ast.CallNode, args []Value) (Value, error) {
+	rows, err := matrixDimension(n, args[0])
+	if err != nil {
+		return Value{}, err
+	}
+	cols := rows
+	if len(args) == 2 {
+		if cols, err = matrixDimension(n, args[1]); err != nil {
+			return Value{}, err
+		}
+	}
+	if rows*cols > maxMatrixElements {
+		return Value{}, newError(n, fmt.Sprintf("zeros: too many elements, at most %d are allowed", maxMatrixElements))
+	}
+	return newMatrix(rows, cols).value(), nil
+}
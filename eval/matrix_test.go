@@ -0,0 +1,116 @@
+package eval
+
+import (
+	"basic-arithmetic-parser/diagnostic"
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestMatrices(t *testing.T) {
+	tests := []struct {
+		name     string
+		lines    []string
+		expected Value
+	}{
+		{"Literal", []string{"[[1, 2], [3, 4]]"}, matrix{{1, 2}, {3, 4}}.value()},
+		{"Matmul", []string{"[[1, 2], [3, 4]] @ [[5, 6], [7, 8]]"}, matrix{{19, 22}, {43, 50}}.value()},
+		{"Matmul Rectangular", []string{"[[1, 2, 3]] @ [[1], [2], [3]]"}, matrix{{14}}.value()},
+		{"Matrix Times Vector", []string{"[[1, 2], [3, 4]] @ [1, 1]"}, List(Number(3), Number(7))},
+		{"Vector Times Matrix", []string{"[1, 1] @ [[1, 2], [3, 4]]"}, List(Number(4), Number(6))},
+		{"Dot Product", []string{"[1, 2, 3] @ [4, 5, 6]"}, Number(32)},
+		{"Matmul Binds Like Multiply", []string{"2 * [[1]] @ [[3]] + 1"}, matrix{{7}}.value()},
+		{"Elementwise Multiply", []string{"[[1, 2], [3, 4]] * [[5, 6], [7, 8]]"}, matrix{{5, 12}, {21, 32}}.value()},
+		{"Scale", []string{"[[1, 2], [3, 4]] / 2"}, matrix{{0.5, 1}, {1.5, 2}}.value()},
+		{"Index Row", []string{"A = [[1, 2], [3, 4]]", "A[1]"}, List(Number(3), Number(4))},
+		{"Index Element", []string{"A = [[1, 2], [3, 4]]", "A[1][0]"}, Number(3)},
+		{"Transpose", []string{"transpose([[1, 2, 3], [4, 5, 6]])"}, matrix{{1, 4}, {2, 5}, {3, 6}}.value()},
+		{"Det 2x2", []string{"det([[1, 2], [3, 4]])"}, Number(-2)},
+		{"Det 3x3", []string{"det([[2, 0, 1], [1, 3, 2], [1, 1, 2]])"}, Number(6)},
+		{"Det Needs Pivoting", []string{"det([[0, 1], [1, 0]])"}, Number(-1)},
+		{"Det Singular", []string{"det([[1, 2, 3], [4, 5, 6], [7, 8, 9]])"}, Number(0)},
+		{"Inverse", []string{"inverse([[4, 7], [2, 6]])"}, matrix{{0.6, -0.7}, {-0.2, 0.4}}.value()},
+		{"Inverse Times Matrix", []string{"A = [[2, 0, 1], [1, 3, 2], [1, 1, 2]]", "inverse(A) @ A"}, identityMatrix(3).value()},
+		{"Solve", []string{"solve([[2, 1], [1, 3]], [3, 5])"}, List(Number(0.8), Number(1.4))},
+		{"Solve Needs Pivoting", []string{"solve([[0, 1], [1, 0]], [2, 3])"}, List(Number(3), Number(2))},
+		{"Solve Matrix", []string{"solve([[2, 0], [0, 4]], [[2, 4], [4, 8]])"}, matrix{{1, 2}, {1, 2}}.value()},
+		{"Identity", []string{"identity(2)"}, matrix{{1, 0}, {0, 1}}.value()},
+		{"Zeros Square", []string{"zeros(2)"}, matrix{{0, 0}, {0, 0}}.value()},
+		{"Zeros", []string{"zeros(1, 3)"}, matrix{{0, 0, 0}}.value()},
+		{"Identity Is Neutral", []string{"A = [[1, 2], [3, 4]]", "A @ identity(2) == A"}, Bool(true)},
+		{"State Update", []string{"A = [[1, 0.1], [0, 1]]", "x = [0, 1]", "A @ (A @ x)"}, List(Number(0.2), Number(1))},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := evalLines(t, NewEvaluator(nil, nil), tt.lines...)
+			if err != nil {
+				t.Fatalf("Eval() returned an error: %v", err)
+			}
+			if !approxEqual(result, tt.expected) {
+				t.Errorf("Expected %v, but got %v", tt.expected, result)
+			}
+		})
+	}
+}
+
+// approxEqual is Value.Equal, but allows for rounding in the numbers
+func approxEqual(v, w Value) bool {
+	switch {
+	case v.Kind == NumberKind && w.Kind == NumberKind:
+		return math.Abs(v.Number-w.Number) <= 1e-9*math.Max(1, math.Abs(w.Number))
+	case v.Kind == ListKind && w.Kind == ListKind && len(v.List) == len(w.List):
+		for i := range v.List {
+			if !approxEqual(v.List[i], w.List[i]) {
+				return false
+			}
+		}
+		return true
+	default:
+		return v.Equal(w)
+	}
+}
+
+func TestMatrixErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		lines    []string
+		expected string
+	}{
+		{"Matmul Mismatch", []string{"[[1, 2, 3], [4, 5, 6]] @ [[1, 2], [3, 4]]"}, "dimension mismatch: cannot multiply a 2x3 matrix by a 2x2 matrix"},
+		{"Matmul Vector Mismatch", []string{"[[1, 2], [3, 4]] @ [1, 2, 3]"}, "dimension mismatch: cannot multiply a 2x2 matrix by a vector of length 3"},
+		{"Dot Product Mismatch", []string{"[1, 2] @ [1, 2, 3]"}, "dimension mismatch: cannot multiply a vector of length 2 by a vector of length 3"},
+		{"Matmul Number", []string{"2 @ [[1]]"}, "operator @ expects matrices or vectors, got a number"},
+		{"Matmul Empty", []string{"[] @ []"}, "operator @ expects matrices or vectors, got []"},
+		{"Matmul Bools", []string{"[true] @ [1]"}, "operator @ expects a vector of numbers, got a bool"},
+		{"Ragged", []string{"det([[1, 2], [3]])"}, "det expects a matrix, but its rows have lengths 2 and 1"},
+		{"Not A Matrix", []string{"transpose([1, 2])"}, "transpose expects a matrix, got [1, 2]"},
+		{"Empty Row", []string{"transpose([[]])"}, "transpose expects a matrix, got [[]]"},
+		{"Matrix Of Bools", []string{"det([[true]])"}, "det expects a matrix of numbers, got [[true]]"},
+		{"Det Not Square", []string{"det([[1, 2, 3], [4, 5, 6]])"}, "det expects a square matrix, got a 2x3 matrix"},
+		{"Inverse Singular", []string{"inverse([[1, 2], [2, 4]])"}, "inverse: matrix is singular"},
+		{"Inverse Zero", []string{"inverse(zeros(3))"}, "inverse: matrix is singular"},
+		{"Solve Singular", []string{"solve([[1, 2], [2, 4]], [1, 2])"}, "solve: matrix is singular"},
+		{"Solve Mismatch", []string{"solve(identity(3), [1, 2])"}, "solve: dimension mismatch: a 3x3 matrix and a vector of length 2"},
+		{"Solve Matrix Mismatch", []string{"solve(identity(3), zeros(2, 4))"}, "solve: dimension mismatch: a 3x3 matrix and a 2x4 matrix"},
+		{"Solve Not Square", []string{"solve(zeros(2, 3), [1, 2])"}, "solve expects a square matrix, got a 2x3 matrix"},
+		{"Fractional Dimension", []string{"identity(2.5)"}, "identity expects positive whole numbers as dimensions, got 2.5"},
+		{"Zero Dimension", []string{"zeros(2, 0)"}, "zeros expects positive whole numbers as dimensions, got 0"},
+		{"Too Large", []string{"zeros(10000)"}, "zeros: too many elements, at most 1000000 are allowed"},
+		{"Identity Too Large", []string{"identity(10^7)"}, "identity: too many elements, at most 1000000 are allowed"},
+		{"Redefine Builtin", []string{"det(A) = 0"}, "cannot redefine built-in function det"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := evalLines(t, NewEvaluator(nil, nil), tt.lines...)
+			var d diagnostic.Diagnostic
+			if !errors.As(err, &d) {
+				t.Fatalf("Expected a diagnostic.Diagnostic, got %T (%v)", err, err)
+			}
+			if d.Message != tt.expected {
+				t.Errorf("Expected error %q, but got %q", tt.expected, d.Message)
+			}
+		})
+	}
+}
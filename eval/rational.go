@@ -0,0 +1,163 @@
+package eval
+
+import (
+	"basic-arithmetic-parser/ast"
+	"basic-arithmetic-parser/token"
+	"fmt"
+	"math/big"
+)
+
+// maxRationalExponent bounds the exponents EvalRational and EvalDecimal
+// accept, as exact powers grow without limit
+const maxRationalExponent = 1 << 16
+
+// EvalRational evaluates the given AST node exactly, using arbitrary
+// precision rationals: number literals are read from their text rather than
+// from the float64 value, so 0.1 + 0.2 is exactly 3/10. It supports + - * /,
+// unary signs, integer powers and percentages; other operators, variables
+// and function calls are reported as errors.
+func EvalRational(node ast.Node) (*big.Rat, error) {
+	switch n := node.(type) {
+	case *ast.NumberNode:
+		if n.Imaginary {
+			return nil, imaginaryError(n)
+		}
+		return ratFromLiteral(n)
+	case *ast.BinaryOpNode:
+		leftVal, err := EvalRational(n.Left)
+		if err != nil {
+			return nil, err
+		}
+		rightVal, err := EvalRational(n.Right)
+		if err != nil {
+			return nil, err
+		}
+		if isRelativePercent(n) {
+			rightVal = new(big.Rat).Mul(rightVal, leftVal)
+		}
+
+		result := new(big.Rat)
+		switch n.Op.Type {
+		case token.PLUS:
+			return result.Add(leftVal, rightVal), nil
+		case token.MINUS:
+			return result.Sub(leftVal, rightVal), nil
+		case token.MULTIPLY, token.OF:
+			return result.Mul(leftVal, rightVal), nil
+		case token.DIVIDE:
+			if rightVal.Sign() == 0 {
+				return nil, newError(n, "division by zero")
+			}
+			return result.Quo(leftVal, rightVal), nil
+		case token.POWER:
+			return ratPow(n, leftVal, rightVal)
+		default:
+			return nil, newError(n, fmt.Sprintf("operator %s is not supported in rational mode", n.Op.Value))
+		}
+	case *ast.UnaryOpNode:
+		exprVal, err := EvalRational(n.Expr)
+		if err != nil {
+			return nil, err
+		}
+
+		switch n.Op.Type {
+		case token.PLUS: // Unary plus (identity)
+			return exprVal, nil
+		case token.MINUS: // Unary minus (negation)
+			return new(big.Rat).Neg(exprVal), nil
+		default:
+			return nil, newError(n, fmt.Sprintf("unknown unary operator: %s", n.Op.Value))
+		}
+	case *ast.PercentNode:
+		exprVal, err := EvalRational(n.Expr)
+		if err != nil {
+			return nil, err
+		}
+		return new(big.Rat).Quo(exprVal, big.NewRat(100, 1)), nil
+	case *ast.IdentNode:
+		return nil, newError(n, "variables are not supported in rational mode")
+	case *ast.AssignNode:
+		return nil, newError(n, "variables are not supported in rational mode")
+	case *ast.CallNode:
+		return nil, newError(n, "function calls are not supported in rational mode")
+	case *ast.ListNode, *ast.IndexNode, *ast.SliceNode:
+		return nil, listError(n)
+	case *ast.QuantityNode, *ast.ConvertNode:
+		return nil, unitsError(n)
+	case *ast.DateNode, *ast.DurationNode:
+		return nil, dateError(n)
+	case *ast.BoolNode, *ast.ConditionalNode:
+		return nil, boolError(n)
+	case *ast.FunctionDefNode, *ast.LambdaNode, *ast.ApplyNode:
+		return nil, functionDefError(n)
+	case *ast.ErrorNode:
+		return nil, newError(n, "cannot evaluate an expression with syntax errors")
+	default:
+		return nil, fmt.Errorf("unknown node type: %T", node)
+	}
+}
+
+// ratFromLiteral returns the exact value of a number literal
+func ratFromLiteral(n *ast.NumberNode) (*big.Rat, error) {
+	literal := literalText(n)
+	r, ok := new(big.Rat).SetString(literal)
+	if !ok {
+		return nil, newError(n, fmt.Sprintf("invalid number: %s", literal))
+	}
+	return r, nil
+}
+
+// ratPow raises base to an integer exponent exactly
+func ratPow(n *ast.BinaryOpNode, base, exponent *big.Rat) (*big.Rat, error) {
+	if !exponent.IsInt() {
+		return nil, newError(n, "exponent must be an integer in rational mode")
+	}
+	if exponent.Num().CmpAbs(big.NewInt(maxRationalExponent)) > 0 {
+		return nil, newError(n, "exponent is too large")
+	}
+	exp := exponent.Num().Int64()
+	if base.Sign() == 0 && exp < 0 {
+		return nil, newError(n, "division by zero")
+	}
+
+	absExp := big.NewInt(exp)
+	absExp.Abs(absExp)
+	num := new(big.Int).Exp(base.Num(), absExp, nil)
+	denom := new(big.Int).Exp(base.Denom(), absExp, nil)
+	if exp < 0 {
+		num, denom = denom, num
+	}
+	return new(big.Rat).SetFrac(num, denom), nil
+}
+
+// FormatRat formats r as an exact decimal when it has a finite decimal
+// expansion (e.g. 0.3, -1.25, 42) and as a fraction otherwise (e.g. 1/3)
+func FormatRat(r *big.Rat) string {
+	if r.IsInt() {
+		return r.Num().String()
+	}
+
+	// a reduced fraction has a finite decimal expansion if its denominator
+	// has no prime factors other than 2 and 5; the number of digits needed
+	// is the larger of the two multiplicities
+	denom := new(big.Int).Set(r.Denom())
+	digits := 0
+	for _, factor := range []int64{2, 5} {
+		count := 0
+		f := big.NewInt(factor)
+		rem := new(big.Int)
+		for {
+			quo, m := new(big.Int).QuoRem(denom, f, rem)
+			if m.Sign() != 0 {
+				break
+			}
+			denom = quo
+			count++
+		}
+		digits = max(digits, count)
+	}
+	if denom.Cmp(big.NewInt(1)) != 0 {
+		return r.RatString()
+	}
+	return r.FloatString(digits)
+}
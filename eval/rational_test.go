@@ -0,0 +1,88 @@
+package eval
+
+import (
+	"basic-arithmetic-parser/ast"
+	"basic-arithmetic-parser/diagnostic"
+	"errors"
+	"math/big"
+	"testing"
+)
+
+func TestEvalRational(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"0.1 + 0.2", "0.3"},
+		{"1 / 3", "1/3"},
+		{"1 / 3 + 1 / 6", "0.5"},
+		{"2 / 3 * 3", "2"},
+		{"-(1 / 7) - 1", "-8/7"},
+		{"10 / 4", "2.5"},
+		{"1 / 1024", "0.0009765625"},
+		{"(2 / 3) ^ 3", "8/27"},
+		{"2 ^ -3", "0.125"},
+		{"2 ^ 64", "18446744073709551616"},
+		{"0.1 * 3 - 0.3", "0"},
+		{"123456789.123456789 * 1000000000", "123456789123456789"},
+		{"200 + 10%", "220"},
+		{"1 / 3 - 10%", "0.3"},
+		{"12.5% of 8", "1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			program := mustParse(t, tt.input)
+
+			result, err := EvalRational(program)
+			if err != nil {
+				t.Fatalf("EvalRational() returned an error: %v", err)
+			}
+			if got := FormatRat(result); got != tt.expected {
+				t.Errorf("Expected %s, but got %s", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestEvalRationalErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"1 / (1 / 2 - 0.5)", "division by zero"},
+		{"0 ^ -1", "division by zero"},
+		{"2 ^ 0.5", "exponent must be an integer in rational mode"},
+		{"2 ^ 100000", "exponent is too large"},
+		{"7 % 2", "operator % is not supported in rational mode"},
+		{"x + 1", "variables are not supported in rational mode"},
+		{"sqrt(2)", "function calls are not supported in rational mode"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			program := mustParse(t, tt.input)
+
+			_, err := EvalRational(program)
+			var d diagnostic.Diagnostic
+			if !errors.As(err, &d) {
+				t.Fatalf("Expected a diagnostic.Diagnostic, got %T (%v)", err, err)
+			}
+			if d.Message != tt.expected {
+				t.Errorf("Expected error %q, but got %q", tt.expected, d.Message)
+			}
+		})
+	}
+}
+
+func TestEvalRationalWithoutLiteral(t *testing.T) {
+	// nodes built by hand have no literal text, so the float64 value is used
+	node := &ast.NumberNode{Value: 0.5}
+	result, err := EvalRational(node)
+	if err != nil {
+		t.Fatalf("EvalRational() returned an error: %v", err)
+	}
+	if result.Cmp(big.NewRat(1, 2)) != 0 {
+		t.Errorf("Expected 1/2, but got %s", result.RatString())
+	}
+}
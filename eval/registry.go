@@ -0,0 +1,158 @@
+package eval
+
+import (
+	"basic-arithmetic-parser/token"
+	"fmt"
+	"maps"
+	"math"
+)
+
+// function is a function callable from expressions
+type function struct {
+	// minArgs and maxArgs bound the number of arguments; maxArgs is -1 for
+	// variadic functions
+	minArgs int
+	maxArgs int
+	fn      func(args []float64) (float64, error)
+}
+
+// Registry holds the functions and named constants available to
+// expressions. Host code can register its own alongside (or in place of) the
+// built-in ones; see DefaultRegistry.
+type Registry struct {
+	functions map[string]function
+	constants map[string]float64
+}
+
+// NewRegistry returns an empty registry
+func NewRegistry() *Registry {
+	return &Registry{
+		functions: map[string]function{},
+		constants: map[string]float64{},
+	}
+}
+
+// defaultRegistry is shared by evaluators that are not given a registry, and
+// must not be modified
+var defaultRegistry = newDefaultRegistry()
+
+func newDefaultRegistry() *Registry {
+	r := NewRegistry()
+	registerBuiltins(r)
+	r.constants["pi"] = math.Pi
+	r.constants["e"] = math.E
+	return r
+}
+
+// DefaultRegistry returns a new registry holding the built-in math functions
+// and the constants pi and e
+func DefaultRegistry() *Registry {
+	return defaultRegistry.Clone()
+}
+
+// Clone returns a copy of r that can be modified independently
+func (r *Registry) Clone() *Registry {
+	return &Registry{
+		functions: maps.Clone(r.functions),
+		constants: maps.Clone(r.constants),
+	}
+}
+
+// validName reports whether name can be written in an expression: an
+// identifier that is not a keyword
+func validName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for i, ch := range name {
+		letter := 'a' <= ch && ch <= 'z' || 'A' <= ch && ch <= 'Z' || ch == '_'
+		digit := '0' <= ch && ch <= '9'
+		if !letter && !(digit && i > 0) {
+			return false
+		}
+	}
+	_, keyword := token.LookupKeyword(name)
+	return !keyword
+}
+
+func (r *Registry) register(name string, f function) error {
+	if !validName(name) {
+		return fmt.Errorf("invalid function name %q", name)
+	}
+	r.functions[name] = f
+	return nil
+}
+
+// RegisterFunc registers fn as a function callable by name, replacing any
+// existing function of that name. fn must have one of the signatures
+//
+//	func() float64
+//	func(float64) float64
+//	func(float64, float64) float64
+//	func(float64, float64, float64) float64
+//	func(...float64) float64
+//
+// or the same with an (float64, error) result; a non-nil error is reported
+// as an evaluation error of the call. Variadic functions accept any number of
+// arguments; use RegisterVariadic to require a minimum.
+func (r *Registry) RegisterFunc(name string, fn any) error {
+	var f function
+	switch fn := fn.(type) {
+	case func() float64:
+		f = function{0, 0, func(args []float64) (float64, error) { return fn(), nil }}
+	case func() (float64, error):
+		f = function{0, 0, func(args []float64) (float64, error) { return fn() }}
+	case func(float64) float64:
+		f = function{1, 1, func(args []float64) (float64, error) { return fn(args[0]), nil }}
+	case func(float64) (float64, error):
+		f = function{1, 1, func(args []float64) (float64, error) { return fn(args[0]) }}
+	case func(float64, float64) float64:
+		f = function{2, 2, func(args []float64) (float64, error) { return fn(args[0], args[1]), nil }}
+	case func(float64, float64) (float64, error):
+		f = function{2, 2, func(args []float64) (float64, error) { return fn(args[0], args[1]) }}
+	case func(float64, float64, float64) float64:
+		f = function{3, 3, func(args []float64) (float64, error) { return fn(args[0], args[1], args[2]), nil }}
+	case func(float64, float64, float64) (float64, error):
+		f = function{3, 3, func(args []float64) (float64, error) { return fn(args[0], args[1], args[2]) }}
+	case func(...float64) float64:
+		f = function{0, -1, func(args []float64) (float64, error) { return fn(args...), nil }}
+	case func(...float64) (float64, error):
+		f = function{0, -1, func(args []float64) (float64, error) { return fn(args...) }}
+	default:
+		return fmt.Errorf("unsupported signature for function %q: %T", name, fn)
+	}
+	return r.register(name, f)
+}
+
+// RegisterVariadic registers fn as a function callable by name with between
+// minArgs and maxArgs arguments (maxArgs -1 for no upper limit), replacing
+// any existing function of that name
+func (r *Registry) RegisterVariadic(name string, minArgs, maxArgs int, fn func(...float64) (float64, error)) error {
+	if minArgs < 0 || maxArgs >= 0 && maxArgs < minArgs {
+		return fmt.Errorf("invalid argument count for function %q: %d to %d", name, minArgs, maxArgs)
+	}
+	return r.register(name, function{minArgs, maxArgs, func(args []float64) (float64, error) { return fn(args...) }})
+}
+
+// RegisterConstant makes value available under name, replacing any existing
+// constant of that name. Constants take precedence over variables and cannot
+// be assigned to.
+func (r *Registry) RegisterConstant(name string, value float64) error {
+	if !validName(name) {
+		return fmt.Errorf("invalid constant name %q", name)
+	}
+	r.constants[name] = value
+	return nil
+}
+
+// IsFunction reports whether name is a registered function
+func (r *Registry) IsFunction(name string) bool {
+	_, ok := r.functions[name]
+	return ok
+}
+
+// Constant returns the value of the named constant, and whether there is one
+func (r *Registry) Constant(name string) (float64, bool) {
+	value, ok := r.constants[name]
+	return value, ok
+}
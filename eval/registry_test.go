@@ -0,0 +1,175 @@
+package eval
+
+import (
+	"basic-arithmetic-parser/diagnostic"
+	"basic-arithmetic-parser/lexer"
+	"basic-arithmetic-parser/parser"
+	"errors"
+	"fmt"
+	"math"
+	"testing"
+)
+
+func evalWithRegistry(t *testing.T, input string, registry *Registry) (Value, error) {
+	t.Helper()
+	program := mustParse(t, input)
+	return NewEvaluator(nil, registry).Eval(program)
+}
+
+func TestRegisterFunc(t *testing.T) {
+	registry := DefaultRegistry()
+	registrations := map[string]any{
+		"answer": func() float64 { return 42 },
+		"tax": func(amount float64) (float64, error) {
+			if amount < 0 {
+				return 0, errors.New("negative amount")
+			}
+			return amount * 0.2, nil
+		},
+		"avg2":  func(a, b float64) float64 { return (a + b) / 2 },
+		"clamp": func(x, lo, hi float64) float64 { return min(max(x, lo), hi) },
+		"sum": func(args ...float64) float64 {
+			total := 0.0
+			for _, arg := range args {
+				total += arg
+			}
+			return total
+		},
+	}
+	for name, fn := range registrations {
+		if err := registry.RegisterFunc(name, fn); err != nil {
+			t.Fatalf("RegisterFunc(%q) returned an error: %v", name, err)
+		}
+	}
+
+	tests := []struct {
+		input    string
+		expected float64
+	}{
+		{"answer()", 42},
+		{"tax(100) + 1", 21},
+		{"avg2(1, 4)", 2.5},
+		{"clamp(15, 0, 10)", 10},
+		{"sum()", 0},
+		{"sum(1, 2, 3, 4)", 10},
+		{"sqrt(sum(9, 7))", 4},
+	}
+
+	for _, tt := range tests {
+		result, err := evalWithRegistry(t, tt.input, registry)
+		if err != nil {
+			t.Fatalf("Eval(%q) returned an error: %v", tt.input, err)
+		}
+		if !result.Equal(Number(tt.expected)) {
+			t.Errorf("Eval(%q) wrong. expected=%g, got=%v", tt.input, tt.expected, result)
+		}
+	}
+
+	// errors from host functions are reported against the call
+	_, err := evalWithRegistry(t, "tax(-1)", registry)
+	var d diagnostic.Diagnostic
+	if !errors.As(err, &d) || d.Message != "tax: negative amount" {
+		t.Errorf("Expected error %q, got %v", "tax: negative amount", err)
+	}
+
+	// the built-in registry is unaffected
+	if defaultRegistry.IsFunction("tax") {
+		t.Errorf("Registering on DefaultRegistry() modified the shared built-ins")
+	}
+}
+
+func TestRegisterVariadic(t *testing.T) {
+	registry := NewRegistry()
+	err := registry.RegisterVariadic("first", 1, 3, func(args ...float64) (float64, error) {
+		return args[0], nil
+	})
+	if err != nil {
+		t.Fatalf("RegisterVariadic returned an error: %v", err)
+	}
+
+	if result, err := evalWithRegistry(t, "first(7, 8)", registry); err != nil || !result.Equal(Number(7)) {
+		t.Errorf("Expected 7, got %v (error: %v)", result, err)
+	}
+	for input, expected := range map[string]string{
+		"first()":           "first expects 1 to 3 arguments, got 0",
+		"first(1, 2, 3, 4)": "first expects 1 to 3 arguments, got 4",
+		"sqrt(4)":           "unknown function: sqrt",
+	} {
+		_, err := evalWithRegistry(t, input, registry)
+		var d diagnostic.Diagnostic
+		if !errors.As(err, &d) || d.Message != expected {
+			t.Errorf("Eval(%q): expected error %q, got %v", input, expected, err)
+		}
+	}
+
+	if err := registry.RegisterVariadic("bad", 2, 1, nil); err == nil {
+		t.Errorf("Expected an error for maxArgs < minArgs")
+	}
+}
+
+func TestRegisterConstant(t *testing.T) {
+	registry := DefaultRegistry()
+	if err := registry.RegisterConstant("vat", 0.2); err != nil {
+		t.Fatalf("RegisterConstant returned an error: %v", err)
+	}
+
+	if result, err := evalWithRegistry(t, "100 * (1 + vat)", registry); err != nil || !result.Equal(Number(120)) {
+		t.Errorf("Expected 120, got %v (error: %v)", result, err)
+	}
+	for input, expected := range map[string]float64{"pi": math.Pi, "e": math.E} {
+		if result, err := evalWithRegistry(t, input, registry); err != nil || !result.Equal(Number(expected)) {
+			t.Errorf("Expected %g, got %v (error: %v)", expected, result, err)
+		}
+	}
+
+	_, err := evalWithRegistry(t, "vat = 0.1", registry)
+	var d diagnostic.Diagnostic
+	if !errors.As(err, &d) || d.Message != "cannot assign to constant vat" {
+		t.Errorf("Expected error %q, got %v", "cannot assign to constant vat", err)
+	}
+}
+
+func TestRegisterInvalid(t *testing.T) {
+	registry := NewRegistry()
+	tests := []struct {
+		name string
+		err  error
+	}{
+		{"unsupported signature", registry.RegisterFunc("f", func(int) int { return 0 })},
+		{"name starting with a digit", registry.RegisterFunc("2f", func(x float64) float64 { return x })},
+		{"name with punctuation", registry.RegisterConstant("a-b", 1)},
+		{"keyword", registry.RegisterConstant("rem", 1)},
+		{"empty name", registry.RegisterConstant("", 1)},
+	}
+
+	for _, tt := range tests {
+		if tt.err == nil {
+			t.Errorf("%s: expected an error, got none", tt.name)
+		}
+	}
+}
+
+func TestEvaluatorAsResolver(t *testing.T) {
+	registry := NewRegistry()
+	if err := registry.RegisterFunc("tax", func(x float64) float64 { return x * 0.2 }); err != nil {
+		t.Fatalf("RegisterFunc returned an error: %v", err)
+	}
+
+	l := lexer.New("tax(10) + sqrt(4) + fee(1)")
+	p := parser.New(l)
+	p.SetResolver(NewEvaluator(nil, registry))
+	_, err := p.Parse()
+
+	var parseErrs *parser.ParseErrors
+	if !errors.As(err, &parseErrs) {
+		t.Fatalf("Expected *parser.ParseErrors, got %T (%v)", err, err)
+	}
+	var messages []string
+	for _, d := range parseErrs.Diagnostics {
+		messages = append(messages, d.Message)
+	}
+	expected := []string{"unknown function: sqrt", "unknown function: fee"}
+	if fmt.Sprint(messages) != fmt.Sprint(expected) {
+		t.Errorf("Expected diagnostics %v, got %v", expected, messages)
+	}
+}
@@ -0,0 +1,171 @@
+package eval
+
+import (
+	"basic-arithmetic-parser/ast"
+	"basic-arithmetic-parser/token"
+	"fmt"
+	"math"
+)
+
+// Measurement is a value with the standard deviation of its uncertainty
+type Measurement struct {
+	Value, Sigma float64
+}
+
+func (m Measurement) String() string {
+	return fmt.Sprintf("%g ± %g", m.Value, m.Sigma)
+}
+
+// uncertaintyDerivatives are the derivatives of the one-argument built-ins
+// that can be used in uncertainty mode
+var uncertaintyDerivatives = map[string]func(x float64) float64{
+	"sqrt":  func(x float64) float64 { return 0.5 / math.Sqrt(x) },
+	"exp":   math.Exp,
+	"ln":    func(x float64) float64 { return 1 / x },
+	"log10": func(x float64) float64 { return 1 / (x * math.Ln10) },
+	"log2":  func(x float64) float64 { return 1 / (x * math.Ln2) },
+	"sin":   math.Cos,
+	"cos":   func(x float64) float64 { return -math.Sin(x) },
+	"tan":   func(x float64) float64 { return 1 / (math.Cos(x) * math.Cos(x)) },
+	"asin":  func(x float64) float64 { return 1 / math.Sqrt(1-x*x) },
+	"acos":  func(x float64) float64 { return -1 / math.Sqrt(1-x*x) },
+	"atan":  func(x float64) float64 { return 1 / (1 + x*x) },
+	"abs": func(x float64) float64 {
+		if x < 0 {
+			return -1
+		}
+		return 1
+	},
+}
+
+// contribution returns the uncertainty a result inherits from an operand
+// with the given sigma, where derivative is the partial derivative of the
+// result with respect to it. Exact operands contribute nothing, even where
+// the derivative is infinite.
+func contribution(derivative, sigma float64) float64 {
+	if sigma == 0 {
+		return 0
+	}
+	return derivative * sigma
+}
+
+// EvalUncertainty evaluates the given AST node with first-order propagation
+// of uncertainty. A measurement is written 5.0 ± 0.2, where 0.2 is its
+// standard deviation; every operand is treated as an independent
+// measurement, so the uncertainties of a result's inputs, each scaled by the
+// partial derivative with respect to it, add in quadrature. It supports
+// + - * / ^, unary signs, the constants pi and e, and the one-argument
+// built-ins sqrt, exp, ln, log10, log2, sin, cos, tan, asin, acos, atan and
+// abs. Variables are reported as errors.
+func EvalUncertainty(node ast.Node) (Measurement, error) {
+	switch n := node.(type) {
+	case *ast.NumberNode:
+		if n.Imaginary {
+			return Measurement{}, imaginaryError(n)
+		}
+		return Measurement{Value: n.Value}, nil
+	case *ast.IdentNode:
+		if value, ok := defaultRegistry.Constant(n.Name); ok {
+			return Measurement{Value: value}, nil
+		}
+		return Measurement{}, newError(n, "variables are not supported in uncertainty mode")
+	case *ast.BinaryOpNode:
+		leftVal, err := EvalUncertainty(n.Left)
+		if err != nil {
+			return Measurement{}, err
+		}
+		rightVal, err := EvalUncertainty(n.Right)
+		if err != nil {
+			return Measurement{}, err
+		}
+
+		if n.Op.Type == token.PLUSMINUS {
+			if rightVal.Sigma != 0 {
+				return Measurement{}, newError(n.Right, "the uncertainty after ± must be an exact number")
+			}
+			if rightVal.Value < 0 {
+				return Measurement{}, newError(n.Right, "the uncertainty after ± must not be negative")
+			}
+			return Measurement{Value: leftVal.Value, Sigma: math.Hypot(leftVal.Sigma, rightVal.Value)}, nil
+		}
+		value, err := floatBinaryOp(n, leftVal.Value, rightVal.Value)
+		if err != nil {
+			return Measurement{}, err
+		}
+		// the partial derivatives of the result with respect to each operand
+		var dLeft, dRight float64
+		switch n.Op.Type {
+		case token.PLUS:
+			dLeft, dRight = 1, 1
+		case token.MINUS:
+			dLeft, dRight = 1, -1
+		case token.MULTIPLY:
+			dLeft, dRight = rightVal.Value, leftVal.Value
+		case token.DIVIDE:
+			dLeft = 1 / rightVal.Value
+			dRight = -leftVal.Value / (rightVal.Value * rightVal.Value)
+		case token.POWER:
+			dLeft = rightVal.Value * math.Pow(leftVal.Value, rightVal.Value-1)
+			if rightVal.Sigma != 0 {
+				if leftVal.Value <= 0 {
+					return Measurement{}, newError(n, "an uncertain exponent needs a positive base")
+				}
+				dRight = value * math.Log(leftVal.Value)
+			}
+		default:
+			return Measurement{}, newError(n, fmt.Sprintf("operator %s is not supported in uncertainty mode", n.Op.Value))
+		}
+		sigma := math.Hypot(contribution(dLeft, leftVal.Sigma), contribution(dRight, rightVal.Sigma))
+		return Measurement{Value: value, Sigma: sigma}, nil
+	case *ast.UnaryOpNode:
+		exprVal, err := EvalUncertainty(n.Expr)
+		if err != nil {
+			return Measurement{}, err
+		}
+
+		switch n.Op.Type {
+		case token.PLUS: // Unary plus (identity)
+			return exprVal, nil
+		case token.MINUS: // Unary minus (negation)
+			return Measurement{Value: -exprVal.Value, Sigma: exprVal.Sigma}, nil
+		default:
+			return Measurement{}, newError(n, fmt.Sprintf("unknown unary operator: %s", n.Op.Value))
+		}
+	case *ast.CallNode:
+		derivative, ok := uncertaintyDerivatives[n.Name.Name]
+		if !ok {
+			return Measurement{}, newError(n.Name, fmt.Sprintf("function %s is not supported in uncertainty mode", n.Name.Name))
+		}
+		if err := checkArity(n.Name.Name, 1, 1, len(n.Args)); err != nil {
+			return Measurement{}, newError(n, err.Error())
+		}
+		arg, err := EvalUncertainty(n.Args[0])
+		if err != nil {
+			return Measurement{}, err
+		}
+		// the built-in computes the value and reports domain errors
+		value, err := defaultRegistry.functions[n.Name.Name].fn([]float64{arg.Value})
+		if err != nil {
+			return Measurement{}, newError(n, fmt.Sprintf("%s: %v", n.Name.Name, err))
+		}
+		return Measurement{Value: value, Sigma: math.Abs(contribution(derivative(arg.Value), arg.Sigma))}, nil
+	case *ast.AssignNode:
+		return Measurement{}, newError(n, "variables are not supported in uncertainty mode")
+	case *ast.ListNode, *ast.IndexNode, *ast.SliceNode:
+		return Measurement{}, listError(n)
+	case *ast.QuantityNode, *ast.ConvertNode:
+		return Measurement{}, unitsError(n)
+	case *ast.DateNode, *ast.DurationNode:
+		return Measurement{}, dateError(n)
+	case *ast.PercentNode:
+		return Measurement{}, percentError(n)
+	case *ast.BoolNode, *ast.ConditionalNode:
+		return Measurement{}, boolError(n)
+	case *ast.FunctionDefNode, *ast.LambdaNode, *ast.ApplyNode:
+		return Measurement{}, functionDefError(n)
+	case *ast.ErrorNode:
+		return Measurement{}, newError(n, "cannot evaluate an expression with syntax errors")
+	default:
+		return Measurement{}, fmt.Errorf("unknown node type: %T", node)
+	}
+}
@@ -0,0 +1,83 @@
+package eval
+
+import (
+	"basic-arithmetic-parser/diagnostic"
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestEvalUncertainty(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected Measurement
+	}{
+		{"5.0 ± 0.2", Measurement{5, 0.2}},
+		{"(5 ± 3) + (2 ± 4)", Measurement{7, 5}},
+		{"(5 ± 3) - (2 ± 4)", Measurement{3, 5}},
+		{"(5 ± 0.2) * 3", Measurement{15, 0.6}},
+		{"-(5 ± 0.2)", Measurement{-5, 0.2}},
+		// relative uncertainties add in quadrature for products and quotients
+		{"(10 ± 0.3) * (20 ± 0.8)", Measurement{200, 10}},
+		{"(10 ± 0.3) / (20 ± 0.8)", Measurement{0.5, 0.025}},
+		{"(2 ± 0.1) ^ 3", Measurement{8, 1.2}},
+		{"2 ^ (3 ± 0.5)", Measurement{8, 4 * math.Ln2}},
+		{"sqrt(16 ± 2)", Measurement{4, 0.25}},
+		{"ln(4 ± 2)", Measurement{math.Log(4), 0.5}},
+		{"abs(-3 ± 0.5)", Measurement{3, 0.5}},
+		{"sqrt(0)", Measurement{0, 0}},
+		{"pi", Measurement{math.Pi, 0}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			program := mustParse(t, tt.input)
+
+			result, err := EvalUncertainty(program)
+			if err != nil {
+				t.Fatalf("EvalUncertainty() returned an error: %v", err)
+			}
+			if math.Abs(result.Value-tt.expected.Value) > 1e-12 || math.Abs(result.Sigma-tt.expected.Sigma) > 1e-12 {
+				t.Errorf("Expected %s, but got %s", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestEvalUncertaintyErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"(1 ± 0.1) / (0 ± 0.1)", "division by zero"},
+		{"5 ± -0.2", "the uncertainty after ± must not be negative"},
+		{"5 ± (0.2 ± 0.1)", "the uncertainty after ± must be an exact number"},
+		{"(-2) ^ (1 ± 0.1)", "an uncertain exponent needs a positive base"},
+		{"sqrt(-4 ± 1)", "sqrt: square root of a negative number"},
+		{"max(1, 2)", "function max is not supported in uncertainty mode"},
+		{"7 % (2 ± 1)", "operator % is not supported in uncertainty mode"},
+		{"[1, 2]", "lists are only supported in float mode, and intervals in interval mode"},
+		{"x ± 1", "variables are not supported in uncertainty mode"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			program := mustParse(t, tt.input)
+
+			_, err := EvalUncertainty(program)
+			var d diagnostic.Diagnostic
+			if !errors.As(err, &d) {
+				t.Fatalf("Expected a diagnostic.Diagnostic, got %T (%v)", err, err)
+			}
+			if d.Message != tt.expected {
+				t.Errorf("Expected error %q, but got %q", tt.expected, d.Message)
+			}
+		})
+	}
+}
+
+func TestMeasurementString(t *testing.T) {
+	if got := (Measurement{Value: 5, Sigma: 0.2}).String(); got != "5 ± 0.2" {
+		t.Errorf("Measurement.String() wrong. expected=%q, got=%q", "5 ± 0.2", got)
+	}
+}
@@ -0,0 +1,444 @@
+package eval
+
+import (
+	"basic-arithmetic-parser/ast"
+	"basic-arithmetic-parser/diagnostic"
+	"basic-arithmetic-parser/token"
+	"fmt"
+	"math"
+)
+
+// Dimension holds the powers of the SI base units kg, m, s, A, K, mol and
+// cd that make up a quantity, so a force (kg*m/s^2) is {1, 1, -2}
+type Dimension [7]int
+
+// baseUnits are the names of the SI base units, in Dimension order
+var baseUnits = [7]string{"kg", "m", "s", "A", "K", "mol", "cd"}
+
+// unit returns the SI unit with this dimension, e.g. kg*m/s^2
+func (d Dimension) unit() *ast.Unit {
+	unit := &ast.Unit{}
+	for i, power := range d {
+		if power != 0 {
+			unit.Factors = append(unit.Factors, ast.UnitFactor{Name: baseUnits[i], Power: power})
+		}
+	}
+	return unit
+}
+
+// unitDef is a unit in the unit table: one of it is scale SI units of
+// dimension dim
+type unitDef struct {
+	scale float64
+	dim   Dimension
+}
+
+var (
+	mass        = Dimension{1, 0, 0}
+	length      = Dimension{0, 1, 0}
+	duration    = Dimension{0, 0, 1}
+	area        = Dimension{0, 2, 0}
+	volume      = Dimension{0, 3, 0}
+	speed       = Dimension{0, 1, -1}
+	force       = Dimension{1, 1, -2}
+	energy      = Dimension{1, 2, -2}
+	powerDim    = Dimension{1, 2, -3}
+	pressure    = Dimension{1, -1, -2}
+	current     = Dimension{0, 0, 0, 1}
+	charge      = Dimension{0, 0, 1, 1}
+	voltage     = Dimension{1, 2, -3, -1}
+	resistance  = Dimension{1, 2, -3, -2}
+	temperature = Dimension{0, 0, 0, 0, 1}
+	amount      = Dimension{0, 0, 0, 0, 0, 1}
+	luminosity  = Dimension{0, 0, 0, 0, 0, 0, 1}
+	frequency   = Dimension{0, 0, -1}
+)
+
+// unitTable holds the units that can be written after a number. Inches are
+// written inch, as in is the conversion operator.
+var unitTable = map[string]unitDef{
+	// length
+	"m":    {1, length},
+	"km":   {1e3, length},
+	"cm":   {1e-2, length},
+	"mm":   {1e-3, length},
+	"um":   {1e-6, length},
+	"nm":   {1e-9, length},
+	"inch": {0.0254, length},
+	"ft":   {0.3048, length},
+	"yd":   {0.9144, length},
+	"mi":   {1609.344, length},
+	"nmi":  {1852, length},
+	// mass
+	"kg": {1, mass},
+	"g":  {1e-3, mass},
+	"mg": {1e-6, mass},
+	"t":  {1e3, mass},
+	"lb": {0.45359237, mass},
+	"oz": {0.028349523125, mass},
+	// time
+	"s":    {1, duration},
+	"ms":   {1e-3, duration},
+	"min":  {60, duration},
+	"h":    {3600, duration},
+	"day":  {86400, duration},
+	"week": {604800, duration},
+	// area and volume
+	"ha": {1e4, area},
+	"L":  {1e-3, volume},
+	"mL": {1e-6, volume},
+	// speed
+	"mph":  {1609.344 / 3600, speed},
+	"knot": {1852.0 / 3600, speed},
+	// mechanics
+	"Hz":   {1, frequency},
+	"N":    {1, force},
+	"kN":   {1e3, force},
+	"J":    {1, energy},
+	"kJ":   {1e3, energy},
+	"cal":  {4.184, energy},
+	"kcal": {4184, energy},
+	"Wh":   {3600, energy},
+	"kWh":  {3.6e6, energy},
+	"W":    {1, powerDim},
+	"kW":   {1e3, powerDim},
+	"Pa":   {1, pressure},
+	"kPa":  {1e3, pressure},
+	"bar":  {1e5, pressure},
+	"atm":  {101325, pressure},
+	// electricity
+	"A":   {1, current},
+	"mA":  {1e-3, current},
+	"C":   {1, charge},
+	"V":   {1, voltage},
+	"ohm": {1, resistance},
+	// the other base units
+	"K":   {1, temperature},
+	"mol": {1, amount},
+	"cd":  {1, luminosity},
+}
+
+// resolveUnit returns how many SI units one of unit is, and its dimension
+func resolveUnit(unit *ast.Unit) (float64, Dimension, error) {
+	scale := 1.0
+	var dim Dimension
+	for _, f := range unit.Factors {
+		def, ok := unitTable[f.Name]
+		if !ok {
+			return 0, Dimension{}, diagnostic.Diagnostic{Span: f.Loc, Message: fmt.Sprintf("unknown unit: %s", f.Name)}
+		}
+		scale *= math.Pow(def.scale, float64(f.Power))
+		for i := range dim {
+			dim[i] += def.dim[i] * f.Power
+		}
+	}
+	return scale, dim, nil
+}
+
+// Quantity is a value with a physical dimension. Value is in SI units; Unit
+// is the unit the quantity is shown in, or nil to show it in SI units.
+type Quantity struct {
+	Value float64
+	Dim   Dimension
+	Unit  *ast.Unit
+}
+
+// String formats q in its unit, e.g. 96.56064 km/h
+func (q Quantity) String() string {
+	if q.Dim == (Dimension{}) {
+		return fmt.Sprintf("%g", q.Value)
+	}
+	unit := q.displayUnit()
+	scale, _, _ := resolveUnit(unit)
+	// 15 digits hide the rounding error of converting to and from SI units,
+	// so 60 mph to km/h is 96.56064 rather than 96.56063999999999
+	return fmt.Sprintf("%.15g %s", q.Value/scale, unit)
+}
+
+// displayUnit returns the unit q is shown in
+func (q Quantity) displayUnit() *ast.Unit {
+	if q.Unit != nil {
+		return q.Unit
+	}
+	return q.Dim.unit()
+}
+
+// describe names the unit of q for error messages
+func (q Quantity) describe() string {
+	if q.Dim == (Dimension{}) {
+		return "a dimensionless number"
+	}
+	return q.displayUnit().String()
+}
+
+// combineUnits returns the unit of a product (sign 1) or quotient (sign -1)
+// of quantities shown in a and b, merging factors with the same name
+func combineUnits(a, b *ast.Unit, sign int) *ast.Unit {
+	unit := &ast.Unit{}
+	add := func(f ast.UnitFactor, power int) {
+		for i := range unit.Factors {
+			if unit.Factors[i].Name == f.Name {
+				unit.Factors[i].Power += power
+				return
+			}
+		}
+		unit.Factors = append(unit.Factors, ast.UnitFactor{Name: f.Name, Power: power})
+	}
+	for _, f := range a.Factors {
+		add(f, f.Power)
+	}
+	for _, f := range b.Factors {
+		add(f, sign*f.Power)
+	}
+	factors := unit.Factors[:0]
+	for _, f := range unit.Factors {
+		if f.Power != 0 {
+			factors = append(factors, f)
+		}
+	}
+	unit.Factors = factors
+	return unit
+}
+
+// scaleUnit returns unit with every power multiplied by k
+func scaleUnit(unit *ast.Unit, k int) *ast.Unit {
+	scaled := &ast.Unit{Factors: make([]ast.UnitFactor, len(unit.Factors))}
+	for i, f := range unit.Factors {
+		scaled.Factors[i] = ast.UnitFactor{Name: f.Name, Power: f.Power * k}
+	}
+	return scaled
+}
+
+// sameDimensionFunctions are the built-ins that take quantities of one
+// dimension and return a quantity of that dimension
+var sameDimensionFunctions = map[string]bool{"abs": true, "min": true, "max": true}
+
+// EvalUnits evaluates the given AST node with dimensional analysis. A
+// number may be followed by a unit from the unit table (3 m, 2.5 km/h,
+// 9.81 m/s^2); quantities are converted automatically between compatible
+// units, and combining incompatible ones (adding metres to seconds) is an
+// error. A result is shown in the unit of its left operand, or in the unit
+// named by a conversion such as 60 mph to km/h. Functions other than sqrt,
+// abs, min and max need dimensionless arguments, and variables are reported
+// as errors.
+func EvalUnits(node ast.Node) (Quantity, error) {
+	switch n := node.(type) {
+	case *ast.NumberNode:
+		if n.Imaginary {
+			return Quantity{}, imaginaryError(n)
+		}
+		return Quantity{Value: n.Value}, nil
+	case *ast.QuantityNode:
+		scale, dim, err := resolveUnit(n.Unit)
+		if err != nil {
+			return Quantity{}, err
+		}
+		q := Quantity{Value: n.Value.Value * scale, Dim: dim}
+		if dim != (Dimension{}) {
+			q.Unit = n.Unit
+		}
+		return q, nil
+	case *ast.ConvertNode:
+		q, err := EvalUnits(n.Expr)
+		if err != nil {
+			return Quantity{}, err
+		}
+		_, dim, err := resolveUnit(n.Unit)
+		if err != nil {
+			return Quantity{}, err
+		}
+		if dim != q.Dim {
+			return Quantity{}, newError(n, fmt.Sprintf("cannot convert %s to %s", q.describe(), n.Unit))
+		}
+		if dim != (Dimension{}) {
+			q.Unit = n.Unit
+		}
+		return q, nil
+	case *ast.IdentNode:
+		if value, ok := defaultRegistry.Constant(n.Name); ok {
+			return Quantity{Value: value}, nil
+		}
+		return Quantity{}, newError(n, "variables are not supported in units mode")
+	case *ast.BinaryOpNode:
+		leftVal, err := EvalUnits(n.Left)
+		if err != nil {
+			return Quantity{}, err
+		}
+		rightVal, err := EvalUnits(n.Right)
+		if err != nil {
+			return Quantity{}, err
+		}
+		return unitsBinaryOp(n, leftVal, rightVal)
+	case *ast.UnaryOpNode:
+		exprVal, err := EvalUnits(n.Expr)
+		if err != nil {
+			return Quantity{}, err
+		}
+
+		switch n.Op.Type {
+		case token.PLUS: // Unary plus (identity)
+			return exprVal, nil
+		case token.MINUS: // Unary minus (negation)
+			exprVal.Value = -exprVal.Value
+			return exprVal, nil
+		default:
+			return Quantity{}, newError(n, fmt.Sprintf("unknown unary operator: %s", n.Op.Value))
+		}
+	case *ast.CallNode:
+		return unitsCall(n)
+	case *ast.AssignNode:
+		return Quantity{}, newError(n, "variables are not supported in units mode")
+	case *ast.ListNode, *ast.IndexNode, *ast.SliceNode:
+		return Quantity{}, listError(n)
+	case *ast.DateNode, *ast.DurationNode:
+		return Quantity{}, dateError(n)
+	case *ast.PercentNode:
+		return Quantity{}, percentError(n)
+	case *ast.BoolNode, *ast.ConditionalNode:
+		return Quantity{}, boolError(n)
+	case *ast.FunctionDefNode, *ast.LambdaNode, *ast.ApplyNode:
+		return Quantity{}, functionDefError(n)
+	case *ast.ErrorNode:
+		return Quantity{}, newError(n, "cannot evaluate an expression with syntax errors")
+	default:
+		return Quantity{}, fmt.Errorf("unknown node type: %T", node)
+	}
+}
+
+// unitsBinaryOp applies a binary operator to two quantities
+func unitsBinaryOp(n *ast.BinaryOpNode, leftVal, rightVal Quantity) (Quantity, error) {
+	dimensionless := Dimension{}
+	switch n.Op.Type {
+	case token.PLUS, token.MINUS:
+		if leftVal.Dim != rightVal.Dim {
+			verb := "add"
+			if n.Op.Type == token.MINUS {
+				verb = "subtract"
+			}
+			return Quantity{}, newError(n, fmt.Sprintf("cannot %s %s and %s", verb, leftVal.describe(), rightVal.describe()))
+		}
+		value, err := floatBinaryOp(n, leftVal.Value, rightVal.Value)
+		if err != nil {
+			return Quantity{}, err
+		}
+		unit := leftVal.Unit
+		if unit == nil {
+			unit = rightVal.Unit
+		}
+		return Quantity{Value: value, Dim: leftVal.Dim, Unit: unit}, nil
+	case token.MULTIPLY, token.DIVIDE:
+		value, err := floatBinaryOp(n, leftVal.Value, rightVal.Value)
+		if err != nil {
+			return Quantity{}, err
+		}
+		sign := 1
+		if n.Op.Type == token.DIVIDE {
+			sign = -1
+		}
+		result := Quantity{Value: value}
+		for i := range result.Dim {
+			result.Dim[i] = leftVal.Dim[i] + sign*rightVal.Dim[i]
+		}
+		// keep the units the operands were written in, unless they cancel
+		if result.Dim != dimensionless && (leftVal.Unit != nil || rightVal.Unit != nil) {
+			result.Unit = combineUnits(leftVal.displayUnit(), rightVal.displayUnit(), sign)
+		}
+		return result, nil
+	case token.POWER:
+		if rightVal.Dim != dimensionless {
+			return Quantity{}, newError(n.Right, fmt.Sprintf("an exponent must be dimensionless, got %s", rightVal.describe()))
+		}
+		value, err := floatBinaryOp(n, leftVal.Value, rightVal.Value)
+		if err != nil {
+			return Quantity{}, err
+		}
+		if leftVal.Dim == dimensionless {
+			return Quantity{Value: value}, nil
+		}
+		k := rightVal.Value
+		if k != math.Trunc(k) || math.Abs(k) > math.MaxInt32 {
+			return Quantity{}, newError(n.Right, "a quantity with a unit can only be raised to a whole number")
+		}
+		result := Quantity{Value: value}
+		for i := range result.Dim {
+			result.Dim[i] = leftVal.Dim[i] * int(k)
+		}
+		if result.Dim != dimensionless && leftVal.Unit != nil {
+			result.Unit = scaleUnit(leftVal.Unit, int(k))
+		}
+		return result, nil
+	default:
+		if leftVal.Dim != dimensionless || rightVal.Dim != dimensionless {
+			return Quantity{}, newError(n, fmt.Sprintf("operator %s needs dimensionless operands in units mode", n.Op.Value))
+		}
+		value, err := floatBinaryOp(n, leftVal.Value, rightVal.Value)
+		if err != nil {
+			return Quantity{}, err
+		}
+		return Quantity{Value: value}, nil
+	}
+}
+
+// unitsCall calls a built-in function on quantities
+func unitsCall(n *ast.CallNode) (Quantity, error) {
+	name := n.Name.Name
+	f, ok := defaultRegistry.functions[name]
+	if !ok {
+		return Quantity{}, newError(n.Name, fmt.Sprintf("unknown function: %s", name))
+	}
+	if err := checkArity(name, f.minArgs, f.maxArgs, len(n.Args)); err != nil {
+		return Quantity{}, newError(n, err.Error())
+	}
+	args := make([]Quantity, len(n.Args))
+	values := make([]float64, len(n.Args))
+	for i, arg := range n.Args {
+		val, err := EvalUnits(arg)
+		if err != nil {
+			return Quantity{}, err
+		}
+		args[i], values[i] = val, val.Value
+	}
+
+	// the dimension and unit of the result
+	result := Quantity{}
+	switch {
+	case name == "sqrt":
+		for i, power := range args[0].Dim {
+			if power%2 != 0 {
+				return Quantity{}, newError(n, fmt.Sprintf("sqrt: %s has no square root", args[0].describe()))
+			}
+			result.Dim[i] = power / 2
+		}
+		if unit := args[0].Unit; unit != nil {
+			result.Unit = &ast.Unit{}
+			for _, f := range unit.Factors {
+				if f.Power%2 != 0 {
+					// shown in SI units instead
+					result.Unit = nil
+					break
+				}
+				result.Unit.Factors = append(result.Unit.Factors, ast.UnitFactor{Name: f.Name, Power: f.Power / 2})
+			}
+		}
+	case sameDimensionFunctions[name]:
+		for i, arg := range args[1:] {
+			if arg.Dim != args[0].Dim {
+				return Quantity{}, newError(n.Args[i+1], fmt.Sprintf("%s: cannot compare %s and %s", name, args[0].describe(), arg.describe()))
+			}
+		}
+		result.Dim, result.Unit = args[0].Dim, args[0].Unit
+	default:
+		for i, arg := range args {
+			if arg.Dim != (Dimension{}) {
+				return Quantity{}, newError(n.Args[i], fmt.Sprintf("%s needs a dimensionless argument, got %s", name, arg.describe()))
+			}
+		}
+	}
+
+	value, err := f.fn(values)
+	if err != nil {
+		return Quantity{}, newError(n, fmt.Sprintf("%s: %v", name, err))
+	}
+	result.Value = value
+	return result, nil
+}
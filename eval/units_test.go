@@ -0,0 +1,109 @@
+package eval
+
+import (
+	"basic-arithmetic-parser/ast"
+	"basic-arithmetic-parser/diagnostic"
+	"basic-arithmetic-parser/lexer"
+	"basic-arithmetic-parser/parser"
+	"errors"
+	"testing"
+)
+
+// mustParseUnits parses input with units enabled, as in units mode, failing
+// the test if it has syntax errors
+func mustParseUnits(t *testing.T, input string) ast.Node {
+	t.Helper()
+	p := parser.New(lexer.New(input))
+	p.EnableUnits()
+	program, err := p.Parse()
+	if err != nil {
+		t.Fatalf("Parse(%q) returned an error: %v", input, err)
+	}
+	return program
+}
+
+func TestEvalUnits(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"3 m", "3 m"},
+		{"5m + 3ft", "5.9144 m"},
+		{"2.5 km/h", "2.5 km/h"},
+		{"9.81 m/s^2", "9.81 m/s^2"},
+		{"3 km + 200 m", "3.2 km"},
+		{"200 m + 3 km", "3200 m"},
+		{"1 h - 15 min", "0.75 h"},
+		{"60 mph to km/h", "96.56064 km/h"},
+		{"1 m + 2 ft in cm", "160.96 cm"},
+		{"100 km / 2 h", "50 km/h"},
+		{"6 m / 2", "3 m"},
+		{"2 * 3 s", "6 s"},
+		{"2 kg * 9.81 m/s^2", "19.62 kg*m/s^2"},
+		{"2 kg * 9.81 m/s^2 to N", "19.62 N"},
+		{"50 km/h * 2 h", "100 km"},
+		{"1 km / 1 m", "1000"},
+		{"(2 m) ^ 2", "4 m^2"},
+		{"2 m ^ -1", "2 1/m"},
+		{"sqrt(16 m^2)", "4 m"},
+		{"abs(-3 s)", "3 s"},
+		{"max(1 m, 2 ft)", "1 m"},
+		{"-(5 kg)", "-5 kg"},
+		{"1 kWh to J", "3600000 J"},
+		{"1 J / 1 s to W", "1 W"},
+		{"sin(pi / 2)", "1"},
+		{"7 % 4", "3"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			program := mustParseUnits(t, tt.input)
+
+			result, err := EvalUnits(program)
+			if err != nil {
+				t.Fatalf("EvalUnits() returned an error: %v", err)
+			}
+			if got := result.String(); got != tt.expected {
+				t.Errorf("Expected %s, but got %s", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestEvalUnitsErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"3 m + 2 s", "cannot add m and s"},
+		{"3 m - 2", "cannot subtract m and a dimensionless number"},
+		{"60 mph to kg", "cannot convert mph to kg"},
+		{"2 kg * 3 m to J", "cannot convert kg*m to J"},
+		{"5 furlong", "unknown unit: furlong"},
+		{"1 m to parsec", "unknown unit: parsec"},
+		{"2 ^ (1 m)", "an exponent must be dimensionless, got m"},
+		{"(2 m) ^ 0.5", "a quantity with a unit can only be raised to a whole number"},
+		{"sqrt(4 m)", "sqrt: m has no square root"},
+		{"sin(1 m)", "sin needs a dimensionless argument, got m"},
+		{"max(1 m, 1 s)", "max: cannot compare m and s"},
+		{"7 m % 2", "operator % needs dimensionless operands in units mode"},
+		{"1 m / 0", "division by zero"},
+		{"x + 1 m", "variables are not supported in units mode"},
+		{"[1, 2]", "lists are only supported in float mode, and intervals in interval mode"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			program := mustParseUnits(t, tt.input)
+
+			_, err := EvalUnits(program)
+			var d diagnostic.Diagnostic
+			if !errors.As(err, &d) {
+				t.Fatalf("Expected a diagnostic.Diagnostic, got %T (%v)", err, err)
+			}
+			if d.Message != tt.expected {
+				t.Errorf("Expected error %q, but got %q", tt.expected, d.Message)
+			}
+		})
+	}
+}
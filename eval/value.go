@@ -0,0 +1,220 @@
+package eval
+
+import (
+	"basic-arithmetic-parser/ast"
+	"basic-arithmetic-parser/token"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Kind says which of its fields a Value holds
+type Kind int
+
+const (
+	NumberKind Kind = iota
+	BoolKind
+	FunctionKind
+	ListKind
+)
+
+func (k Kind) String() string {
+	switch k {
+	case NumberKind:
+		return "number"
+	case BoolKind:
+		return "bool"
+	case FunctionKind:
+		return "function"
+	case ListKind:
+		return "list"
+	default:
+		return fmt.Sprintf("Kind(%d)", int(k))
+	}
+}
+
+// Value is the result of Eval: a number, a bool, a user-defined function
+// or a list, as given by Kind. Compare values with Equal.
+type Value struct {
+	Kind   Kind
+	Number float64
+	Bool   bool
+	Func   *Function
+	List   []Value
+}
+
+// Number returns a Value holding x
+func Number(x float64) Value {
+	return Value{Kind: NumberKind, Number: x}
+}
+
+// Bool returns a Value holding b
+func Bool(b bool) Value {
+	return Value{Kind: BoolKind, Bool: b}
+}
+
+// List returns a Value holding elems
+func List(elems ...Value) Value {
+	return Value{Kind: ListKind, List: elems}
+}
+
+func (v Value) String() string {
+	switch v.Kind {
+	case BoolKind:
+		return strconv.FormatBool(v.Bool)
+	case FunctionKind:
+		return v.Func.String()
+	case ListKind:
+		elems := make([]string, len(v.List))
+		for i, elem := range v.List {
+			elems[i] = elem.String()
+		}
+		return fmt.Sprintf("[%s]", strings.Join(elems, ", "))
+	default:
+		return fmt.Sprintf("%g", v.Number)
+	}
+}
+
+// Equal reports whether v and w are the same kind and hold the same value.
+// Lists are equal when their elements are, and functions only when they
+// are the same function.
+func (v Value) Equal(w Value) bool {
+	if v.Kind != w.Kind {
+		return false
+	}
+	switch v.Kind {
+	case BoolKind:
+		return v.Bool == w.Bool
+	case FunctionKind:
+		return v.Func == w.Func
+	case ListKind:
+		return slices.EqualFunc(v.List, w.List, Value.Equal)
+	default:
+		return v.Number == w.Number
+	}
+}
+
+// Function is a user-defined function, e.g. f(x, y) = x^2 + y, or a lambda,
+// e.g. x -> x * 2
+type Function struct {
+	// Node is the *ast.FunctionDefNode or *ast.LambdaNode defining the
+	// function
+	Node   ast.Node
+	params []*ast.IdentNode
+	body   ast.Node
+	// env is where the function was defined; its body sees the variables
+	// there, not those of the caller
+	env Environment
+}
+
+// newFunction returns the function defined by node, which must be an
+// *ast.FunctionDefNode or an *ast.LambdaNode, in env
+func newFunction(node ast.Node, env Environment) *Function {
+	f := &Function{Node: node, env: env}
+	switch n := node.(type) {
+	case *ast.FunctionDefNode:
+		f.params, f.body = n.Params, n.Body
+	case *ast.LambdaNode:
+		f.params, f.body = n.Params, n.Body
+	}
+	return f
+}
+
+// Name returns the name of the function, or "lambda" for a lambda
+func (f *Function) Name() string {
+	if def, ok := f.Node.(*ast.FunctionDefNode); ok {
+		return def.Name.Name
+	}
+	return "lambda"
+}
+
+func (f *Function) String() string {
+	return f.Node.String()
+}
+
+// valueBinaryOp applies the operator of n to two values. && and || are
+// handled by the evaluator, as they do not always evaluate their right
+// operand.
+func valueBinaryOp(n *ast.BinaryOpNode, leftVal, rightVal Value) (Value, error) {
+	switch n.Op.Type {
+	case token.EQ, token.NOT_EQ:
+		if leftVal.Kind != rightVal.Kind {
+			return Value{}, newError(n, fmt.Sprintf("cannot compare %s and %s", leftVal.Kind, rightVal.Kind))
+		}
+		equal := leftVal.Equal(rightVal)
+		return Bool(equal == (n.Op.Type == token.EQ)), nil
+	case token.MATMUL:
+		return matmul(n, leftVal, rightVal)
+	}
+
+	if (leftVal.Kind == ListKind || rightVal.Kind == ListKind) && isElementwise(n) {
+		return broadcast(n, leftVal, rightVal)
+	}
+	if leftVal.Kind != NumberKind || rightVal.Kind != NumberKind {
+		if verb, ok := operatorVerbs[n.Op.Type]; ok {
+			return Value{}, newError(n, fmt.Sprintf("cannot %s %s and %s", verb, leftVal.Kind, rightVal.Kind))
+		}
+		return Value{}, newError(n, fmt.Sprintf("operator %s expects numbers, got %s and %s", n.Op.Value, leftVal.Kind, rightVal.Kind))
+	}
+
+	switch n.Op.Type {
+	case token.LT:
+		return Bool(leftVal.Number < rightVal.Number), nil
+	case token.LT_EQ:
+		return Bool(leftVal.Number <= rightVal.Number), nil
+	case token.GT:
+		return Bool(leftVal.Number > rightVal.Number), nil
+	case token.GT_EQ:
+		return Bool(leftVal.Number >= rightVal.Number), nil
+	}
+	result, err := floatBinaryOp(n, leftVal.Number, rightVal.Number)
+	if err != nil {
+		return Value{}, err
+	}
+	return Number(result), nil
+}
+
+// valueUnaryOp applies the operator of n to a value. Signs apply to each
+// element of a list.
+func valueUnaryOp(n *ast.UnaryOpNode, v Value) (Value, error) {
+	if v.Kind == ListKind && n.Op.Type != token.NOT {
+		elems := make([]Value, len(v.List))
+		for i, elem := range v.List {
+			var err error
+			if elems[i], err = valueUnaryOp(n, elem); err != nil {
+				return Value{}, err
+			}
+		}
+		return List(elems...), nil
+	}
+
+	switch n.Op.Type {
+	case token.NOT:
+		b, err := expectBool(n, n.Op.Value, v)
+		if err != nil {
+			return Value{}, err
+		}
+		return Bool(!b), nil
+	case token.PLUS: // Unary plus (identity)
+		if v.Kind != NumberKind {
+			return Value{}, newError(n, fmt.Sprintf("operator + expects a number, got a %s", v.Kind))
+		}
+		return v, nil
+	case token.MINUS: // Unary minus (negation)
+		if v.Kind != NumberKind {
+			return Value{}, newError(n, fmt.Sprintf("cannot negate a %s", v.Kind))
+		}
+		return Number(-v.Number), nil
+	default:
+		return Value{}, newError(n, fmt.Sprintf("unknown unary operator: %s", n.Op.Value))
+	}
+}
+
+// expectBool returns the bool held by v, or an error at node if v is not one
+func expectBool(node ast.Node, op string, v Value) (bool, error) {
+	if v.Kind != BoolKind {
+		return false, newError(node, fmt.Sprintf("operator %s expects a bool, got a %s", op, v.Kind))
+	}
+	return v.Bool, nil
+}
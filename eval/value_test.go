@@ -0,0 +1,172 @@
+package eval
+
+import (
+	"basic-arithmetic-parser/ast"
+	"basic-arithmetic-parser/diagnostic"
+	"errors"
+	"testing"
+)
+
+func TestEvalBool(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected Value
+	}{
+		{"true", Bool(true)},
+		{"!true", Bool(false)},
+		{"!!false", Bool(false)},
+		{"1 + 2 == 3", Bool(true)},
+		{"0.1 + 0.2 == 0.3", Bool(false)},
+		{"2 != 3", Bool(true)},
+		{"true == false", Bool(false)},
+		{"true != false", Bool(true)},
+		{"1 < 2", Bool(true)},
+		{"2 <= 2", Bool(true)},
+		{"-1 > 0", Bool(false)},
+		{"3 >= 4", Bool(false)},
+		{"1 < 2 && 2 < 3", Bool(true)},
+		{"1 > 2 || 2 > 3", Bool(false)},
+		{"true || false && false", Bool(true)},
+		{"!(1 > 2)", Bool(true)},
+		{"sqrt(16) == 4", Bool(true)},
+		{"10% == 0.1", Bool(true)},
+		{"x = 3 > 2", Bool(true)},
+		// the right operand is not evaluated when the left one decides
+		{"false && 1 / 0 > 1", Bool(false)},
+		{"true || undefined", Bool(true)},
+		{"1 + 2", Number(3)},
+		{"1 < 2 ? 10 : 20", Number(10)},
+		{"if 1 > 2 then 10 else 20", Number(20)},
+		{"0 == 0 ? true : 1", Bool(true)},
+		// only the branch that is taken is evaluated
+		{"0 != 0 ? 1 / 0 : 0", Number(0)},
+		{"if true then 1 else undefined", Number(1)},
+		{"2 > 1 ? 3 > 4 ? 1 : 2 : 3", Number(2)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			program := mustParse(t, tt.input)
+
+			result, err := Eval(program)
+			if err != nil {
+				t.Fatalf("Eval() returned an error: %v", err)
+			}
+			if !result.Equal(tt.expected) {
+				t.Errorf("Expected %v, but got %v", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestEvalBoolErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"true + 1", "cannot add bool and number"},
+		{"2 * false", "cannot multiply number and bool"},
+		{"true ^ 2", "operator ^ expects numbers, got bool and number"},
+		{"true < false", "operator < expects numbers, got bool and bool"},
+		{"1 == true", "cannot compare number and bool"},
+		{"-true", "cannot negate a bool"},
+		{"+false", "operator + expects a number, got a bool"},
+		{"!1", "operator ! expects a bool, got a number"},
+		{"1 && true", "operator && expects a bool, got a number"},
+		{"false || 0", "operator || expects a bool, got a number"},
+		{"sqrt(true)", "sqrt expects a number, got a bool"},
+		{"(1 < 2)%", "a percentage must be a number, not a bool"},
+		{"true && 1 / 0 > 1", "division by zero"},
+		{"1 ? 2 : 3", "condition must be a bool, got a number"},
+		{"true ? 1 / 0 : 0", "division by zero"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			program := mustParse(t, tt.input)
+
+			_, err := Eval(program)
+			var d diagnostic.Diagnostic
+			if !errors.As(err, &d) {
+				t.Fatalf("Expected a diagnostic.Diagnostic, got %T (%v)", err, err)
+			}
+			if d.Message != tt.expected {
+				t.Errorf("Expected error %q, but got %q", tt.expected, d.Message)
+			}
+		})
+	}
+}
+
+func TestValueEqual(t *testing.T) {
+	tests := []struct {
+		a, b     Value
+		expected bool
+	}{
+		{Number(1), Number(1), true},
+		{Number(1), Number(2), false},
+		{Number(0), Bool(false), false},
+		{Bool(true), Bool(true), true},
+		{List(Number(1), Number(2)), List(Number(1), Number(2)), true},
+		{List(Number(1), Number(2)), List(Number(1)), false},
+		{List(List(Bool(true))), List(List(Bool(true))), true},
+		{List(), Number(0), false},
+	}
+
+	for _, tt := range tests {
+		if got := tt.a.Equal(tt.b); got != tt.expected {
+			t.Errorf("%v.Equal(%v) = %v, expected %v", tt.a, tt.b, got, tt.expected)
+		}
+	}
+}
+
+func TestValueString(t *testing.T) {
+	tests := []struct {
+		value    Value
+		expected string
+	}{
+		{Number(42), "42"},
+		{Number(0.1), "0.1"},
+		{Number(-1.5e20), "-1.5e+20"},
+		{Bool(true), "true"},
+		{Bool(false), "false"},
+		{List(Number(1), Bool(true), List()), "[1, true, []]"},
+	}
+
+	for _, tt := range tests {
+		if got := tt.value.String(); got != tt.expected {
+			t.Errorf("String() wrong. expected=%q, got=%q", tt.expected, got)
+		}
+	}
+}
+
+func TestOutsideFloatMode(t *testing.T) {
+	expected := map[string]string{
+		"true":      "booleans are only supported in float and date modes",
+		"x ? 1 : 2": "booleans are only supported in float and date modes",
+		"f(x) = x":  "user-defined functions are only supported in float mode",
+		"x -> x":    "user-defined functions are only supported in float mode",
+		"f(1)(2)":   "user-defined functions are only supported in float mode",
+	}
+	// date mode has booleans too
+	inDateMode := map[string]bool{"true": true, "x ? 1 : 2": true}
+	evaluators := map[string]func(node ast.Node) error{
+		"rational": func(node ast.Node) error { _, err := EvalRational(node); return err },
+		"int":      func(node ast.Node) error { _, err := EvalInt(node, false); return err },
+		"complex":  func(node ast.Node) error { _, err := EvalComplex(node); return err },
+		"interval": func(node ast.Node) error { _, err := EvalInterval(node); return err },
+		"units":    func(node ast.Node) error { _, err := EvalUnits(node); return err },
+		"date":     func(node ast.Node) error { _, err := EvalDate(node, fixedClock); return err },
+	}
+	for input := range expected {
+		program := mustParse(t, input)
+		for mode, evaluate := range evaluators {
+			if mode == "date" && inDateMode[input] {
+				continue
+			}
+			var d diagnostic.Diagnostic
+			if err := evaluate(program); !errors.As(err, &d) || d.Message != expected[input] {
+				t.Errorf("%s mode, %q: expected error %q, got %v", mode, input, expected[input], err)
+			}
+		}
+	}
+}
@@ -1,21 +1,32 @@
 package lexer
 
 import (
+	"basic-arithmetic-parser/diagnostic"
 	"basic-arithmetic-parser/token"
 	"fmt"
+	"regexp"
+	"strings"
 	"unicode"
+	"unicode/utf8"
 )
 
 type Lexer struct {
 	input       string
 	position    int
+	line        int
+	column      int
 	currentChar byte
+	errors      []diagnostic.Diagnostic
+	// dates is whether dates and durations are tokens of their own
+	dates bool
 }
 
 func New(input string) *Lexer {
 	lexer := &Lexer{
 		input:    input,
 		position: 0,
+		line:     1,
+		column:   1,
 	}
 	if len(input) > 0 {
 		lexer.currentChar = input[0]
@@ -25,7 +36,41 @@ func New(input string) *Lexer {
 	return lexer
 }
 
+// EnableDates makes the lexer read dates such as 2026-10-17 and durations
+// such as 4h30m as DATETIME and DURATION tokens, for date mode. Otherwise
+// they are numbers, operators and names, so 2026-10-17 is a subtraction and
+// 5m is five metres. It must be called before the first token is read.
+func (l *Lexer) EnableDates() {
+	l.dates = true
+}
+
+// Errors returns the lexical errors found so far. Each one corresponds to an
+// ILLEGAL token returned by GetNextToken.
+func (l *Lexer) Errors() []diagnostic.Diagnostic {
+	return l.errors
+}
+
+func (l *Lexer) error(span token.Span, msg, label string) {
+	l.errors = append(l.errors, diagnostic.Diagnostic{Span: span, Message: msg, Label: label})
+}
+
+// pos returns the position of the current character
+func (l *Lexer) pos() token.Position {
+	return token.Position{Offset: l.position, Line: l.line, Column: l.column}
+}
+
+// newToken builds a token spanning from start up to the current character
+func (l *Lexer) newToken(tokenType token.TokenType, value string, start token.Position) token.Token {
+	return token.Token{Type: tokenType, Value: value, Span: token.Span{Start: start, End: l.pos()}}
+}
+
 func (l *Lexer) advance() {
+	if l.currentChar == '\n' {
+		l.line++
+		l.column = 1
+	} else {
+		l.column++
+	}
 	l.position++
 	if l.position < len(l.input) {
 		l.currentChar = l.input[l.position]
@@ -34,16 +79,35 @@ func (l *Lexer) advance() {
 	}
 }
 
+// skip advances past the next n bytes of input
+func (l *Lexer) skip(n int) {
+	for range n {
+		l.advance()
+	}
+}
+
+// peek returns the character after the current one without consuming it
+func (l *Lexer) peek() byte {
+	if l.position+1 < len(l.input) {
+		return l.input[l.position+1]
+	}
+	return 0
+}
+
 func (l *Lexer) skipWhitespace() {
 	for l.currentChar != 0 && unicode.IsSpace(rune(l.currentChar)) {
 		l.advance()
 	}
 }
 
-// number returns a string representation of a number in the input
-func (l *Lexer) number() string {
+// number returns the type and string representation of a number in the
+// input, and whether it is well formed. A number followed directly by i or j
+// (and nothing else that could continue a word) is IMAGINARY, with the
+// suffix included in its value.
+func (l *Lexer) number() (token.TokenType, string, bool) {
 	result := ""
 	decimalPointSeen := false
+	valid := true
 
 	// collect digits and decimal point
 	for l.currentChar != 0 {
@@ -52,10 +116,9 @@ func (l *Lexer) number() string {
 			l.advance()
 		} else if l.currentChar == '.' {
 			if decimalPointSeen {
-				// Found a second decimal point, stop consuming the number here.
-				// The next call to GetNextToken will likely treat it as an invalid character or operator.
-				// TODO: we also need to collect cases like this
-				panic(fmt.Sprintf("Invalid number format: %s", result))
+				// Found a second decimal point; keep consuming so the whole
+				// malformed literal is reported once as a single token.
+				valid = false
 			}
 			decimalPointSeen = true
 			result += string(l.currentChar)
@@ -66,7 +129,52 @@ func (l *Lexer) number() string {
 		}
 	}
 
-	return result
+	if (l.currentChar == 'i' || l.currentChar == 'j') && !isLetter(l.peek()) && !unicode.IsDigit(rune(l.peek())) {
+		result += string(l.currentChar)
+		l.advance()
+		return token.IMAGINARY, result, valid
+	}
+	return token.NUMBER, result, valid
+}
+
+var (
+	// dateTimePattern matches an ISO 8601 date with an optional time of
+	// day and UTC offset: 2026-10-17, 2026-10-17T09:00 or
+	// 2026-10-17T09:00:30+02:00
+	dateTimePattern = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}(T\d{2}:\d{2}(:\d{2})?(Z|[+-]\d{2}:\d{2})?)?`)
+	// durationPattern matches numbers of weeks, days, hours, minutes and
+	// seconds written together: 3d, 4h30m or 1.5s
+	durationPattern = regexp.MustCompile(`^(\d+(\.\d+)?[wdhms])+`)
+)
+
+// match returns the text of pattern at the current position, or "" if it
+// does not match there or is followed by something that would continue it,
+// in which case the input is lexed as a number and a word instead
+func (l *Lexer) match(pattern *regexp.Regexp) string {
+	rest := l.input[l.position:]
+	text := pattern.FindString(rest)
+	if text == "" || len(text) < len(rest) && isWordChar(rest[len(text)]) {
+		return ""
+	}
+	return text
+}
+
+func isWordChar(ch byte) bool {
+	return isLetter(ch) || unicode.IsDigit(rune(ch)) || ch == '.'
+}
+
+func isLetter(ch byte) bool {
+	return 'a' <= ch && ch <= 'z' || 'A' <= ch && ch <= 'Z' || ch == '_'
+}
+
+// word returns the run of letters, digits and underscores at the current
+// position
+func (l *Lexer) word() string {
+	start := l.position
+	for isLetter(l.currentChar) || unicode.IsDigit(rune(l.currentChar)) {
+		l.advance()
+	}
+	return l.input[start:l.position]
 }
 
 func (l *Lexer) GetNextToken() token.Token {
@@ -76,36 +184,171 @@ func (l *Lexer) GetNextToken() token.Token {
 			continue
 		}
 
+		start := l.pos()
+
+		// Check for dates and durations, which start like numbers
+		if l.dates {
+			if text := l.match(dateTimePattern); text != "" {
+				l.skip(len(text))
+				return l.newToken(token.DATETIME, text, start)
+			}
+			if text := l.match(durationPattern); text != "" {
+				l.skip(len(text))
+				return l.newToken(token.DURATION, text, start)
+			}
+		}
+
 		// Check for numbers
 		if unicode.IsDigit(rune(l.currentChar)) {
-			return token.Token{Type: token.NUMBER, Value: l.number()}
+			tokenType, value, ok := l.number()
+			tok := l.newToken(tokenType, value, start)
+			if !ok {
+				tok.Type = token.ILLEGAL
+				l.error(tok.Span, fmt.Sprintf("invalid number format: %s", value), "more than one decimal point")
+			}
+			return tok
+		}
+
+		// Check for keywords and identifiers
+		if isLetter(l.currentChar) {
+			value := l.word()
+			if tokenType, ok := token.LookupKeyword(value); ok {
+				return l.newToken(tokenType, value, start)
+			}
+			return l.newToken(token.IDENT, value, start)
+		}
+
+		// ± is the only operator outside ASCII
+		if strings.HasPrefix(l.input[l.position:], "±") {
+			l.skip(len("±"))
+			return l.newToken(token.PLUSMINUS, "±", start)
 		}
 
 		// Check for operators
 		switch l.currentChar {
 		case '+':
 			l.advance()
-			return token.Token{Type: token.PLUS, Value: "+"}
+			return l.newToken(token.PLUS, "+", start)
 		case '-':
+			if l.peek() == '>' {
+				l.advance()
+				l.advance()
+				return l.newToken(token.ARROW, "->", start)
+			}
 			l.advance()
-			return token.Token{Type: token.MINUS, Value: "-"}
+			return l.newToken(token.MINUS, "-", start)
 		case '*':
+			// ** is an alias for ^
+			if l.peek() == '*' {
+				l.advance()
+				l.advance()
+				return l.newToken(token.POWER, "**", start)
+			}
 			l.advance()
-			return token.Token{Type: token.MULTIPLY, Value: "*"}
+			return l.newToken(token.MULTIPLY, "*", start)
 		case '/':
+			if l.peek() == '/' {
+				l.advance()
+				l.advance()
+				return l.newToken(token.FLOOR_DIVIDE, "//", start)
+			}
+			l.advance()
+			return l.newToken(token.DIVIDE, "/", start)
+		case '%':
+			l.advance()
+			return l.newToken(token.MODULO, "%", start)
+		case '@':
+			l.advance()
+			return l.newToken(token.MATMUL, "@", start)
+		case '=':
+			if l.peek() == '=' {
+				l.advance()
+				l.advance()
+				return l.newToken(token.EQ, "==", start)
+			}
 			l.advance()
-			return token.Token{Type: token.DIVIDE, Value: "/"}
+			return l.newToken(token.ASSIGN, "=", start)
+		case '!':
+			if l.peek() == '=' {
+				l.advance()
+				l.advance()
+				return l.newToken(token.NOT_EQ, "!=", start)
+			}
+			l.advance()
+			return l.newToken(token.NOT, "!", start)
+		case '<':
+			if l.peek() == '=' {
+				l.advance()
+				l.advance()
+				return l.newToken(token.LT_EQ, "<=", start)
+			}
+			l.advance()
+			return l.newToken(token.LT, "<", start)
+		case '>':
+			if l.peek() == '=' {
+				l.advance()
+				l.advance()
+				return l.newToken(token.GT_EQ, ">=", start)
+			}
+			l.advance()
+			return l.newToken(token.GT, ">", start)
+		case '&':
+			if l.peek() == '&' {
+				l.advance()
+				l.advance()
+				return l.newToken(token.AND, "&&", start)
+			}
+			l.advance()
+			tok := l.newToken(token.ILLEGAL, "&", start)
+			l.error(tok.Span, "invalid character '&'", "did you mean '&&'?")
+			return tok
+		case '|':
+			if l.peek() == '|' {
+				l.advance()
+				l.advance()
+				return l.newToken(token.OR, "||", start)
+			}
+			l.advance()
+			tok := l.newToken(token.ILLEGAL, "|", start)
+			l.error(tok.Span, "invalid character '|'", "did you mean '||'?")
+			return tok
+		case '^':
+			l.advance()
+			return l.newToken(token.POWER, "^", start)
 		case '(':
 			l.advance()
-			return token.Token{Type: token.LPAREN, Value: "("}
+			return l.newToken(token.LPAREN, "(", start)
 		case ')':
 			l.advance()
-			return token.Token{Type: token.RPAREN, Value: ")"}
+			return l.newToken(token.RPAREN, ")", start)
+		case ',':
+			l.advance()
+			return l.newToken(token.COMMA, ",", start)
+		case '?':
+			l.advance()
+			return l.newToken(token.QUESTION, "?", start)
+		case ':':
+			l.advance()
+			return l.newToken(token.COLON, ":", start)
+		case '[':
+			l.advance()
+			return l.newToken(token.LBRACKET, "[", start)
+		case ']':
+			l.advance()
+			return l.newToken(token.RBRACKET, "]", start)
 		default:
-			panic(fmt.Sprintf("Invalid character: %c", l.currentChar))
+			// record the error and skip the whole (possibly multi-byte)
+			// character so lexing can continue
+			char, size := utf8.DecodeRuneInString(l.input[l.position:])
+			for range size {
+				l.advance()
+			}
+			tok := l.newToken(token.ILLEGAL, string(char), start)
+			l.error(tok.Span, fmt.Sprintf("invalid character %q", char), "not valid in an expression")
+			return tok
 		}
 	}
 
 	// End of input
-	return token.Token{Type: token.EOF, Value: ""}
+	return l.newToken(token.EOF, "", l.pos())
 }
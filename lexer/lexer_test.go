@@ -71,3 +71,546 @@ func TestWhitespaceAndNumbers(t *testing.T) {
 		}
 	}
 }
+
+func TestInvalidInput(t *testing.T) {
+	input := `1 $ 2.3.4 + 5`
+
+	tests := []struct {
+		expectedType  token.TokenType
+		expectedValue string
+	}{
+		{token.NUMBER, "1"},
+		{token.ILLEGAL, "$"},
+		{token.ILLEGAL, "2.3.4"},
+		{token.PLUS, "+"},
+		{token.NUMBER, "5"},
+		{token.EOF, ""},
+	}
+
+	l := New(input)
+
+	for i, tt := range tests {
+		tok := l.GetNextToken()
+
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - token type wrong. expected=%q, got=%q",
+				i, tt.expectedType, tok.Type)
+		}
+
+		if tok.Value != tt.expectedValue {
+			t.Fatalf("tests[%d] - token value wrong. expected=%q, got=%q",
+				i, tt.expectedValue, tok.Value)
+		}
+	}
+
+	expectedErrors := []string{"invalid character '$'", "invalid number format: 2.3.4"}
+	if len(l.Errors()) != len(expectedErrors) {
+		t.Fatalf("wrong number of errors. expected=%d, got=%d", len(expectedErrors), len(l.Errors()))
+	}
+	for i, msg := range expectedErrors {
+		if l.Errors()[i].Message != msg {
+			t.Errorf("errors[%d] wrong. expected=%q, got=%q", i, msg, l.Errors()[i].Message)
+		}
+	}
+}
+
+func TestTokenSpans(t *testing.T) {
+	input := "12 + (3.5\n* 4)"
+
+	tests := []struct {
+		expectedType  token.TokenType
+		expectedStart token.Position
+		expectedEnd   token.Position
+	}{
+		{token.NUMBER, token.Position{Offset: 0, Line: 1, Column: 1}, token.Position{Offset: 2, Line: 1, Column: 3}},
+		{token.PLUS, token.Position{Offset: 3, Line: 1, Column: 4}, token.Position{Offset: 4, Line: 1, Column: 5}},
+		{token.LPAREN, token.Position{Offset: 5, Line: 1, Column: 6}, token.Position{Offset: 6, Line: 1, Column: 7}},
+		{token.NUMBER, token.Position{Offset: 6, Line: 1, Column: 7}, token.Position{Offset: 9, Line: 1, Column: 10}},
+		{token.MULTIPLY, token.Position{Offset: 10, Line: 2, Column: 1}, token.Position{Offset: 11, Line: 2, Column: 2}},
+		{token.NUMBER, token.Position{Offset: 12, Line: 2, Column: 3}, token.Position{Offset: 13, Line: 2, Column: 4}},
+		{token.RPAREN, token.Position{Offset: 13, Line: 2, Column: 4}, token.Position{Offset: 14, Line: 2, Column: 5}},
+		{token.EOF, token.Position{Offset: 14, Line: 2, Column: 5}, token.Position{Offset: 14, Line: 2, Column: 5}},
+	}
+
+	l := New(input)
+
+	for i, tt := range tests {
+		tok := l.GetNextToken()
+
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - token type wrong. expected=%q, got=%q",
+				i, tt.expectedType, tok.Type)
+		}
+
+		if tok.Span.Start != tt.expectedStart {
+			t.Fatalf("tests[%d] - span start wrong. expected=%+v, got=%+v",
+				i, tt.expectedStart, tok.Span.Start)
+		}
+
+		if tok.Span.End != tt.expectedEnd {
+			t.Fatalf("tests[%d] - span end wrong. expected=%+v, got=%+v",
+				i, tt.expectedEnd, tok.Span.End)
+		}
+	}
+}
+
+func TestPowerOperators(t *testing.T) {
+	input := `2^3 ** 4 * 5`
+
+	tests := []struct {
+		expectedType  token.TokenType
+		expectedValue string
+	}{
+		{token.NUMBER, "2"},
+		{token.POWER, "^"},
+		{token.NUMBER, "3"},
+		{token.POWER, "**"},
+		{token.NUMBER, "4"},
+		{token.MULTIPLY, "*"},
+		{token.NUMBER, "5"},
+		{token.EOF, ""},
+	}
+
+	l := New(input)
+
+	for i, tt := range tests {
+		tok := l.GetNextToken()
+
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - token type wrong. expected=%q, got=%q",
+				i, tt.expectedType, tok.Type)
+		}
+
+		if tok.Value != tt.expectedValue {
+			t.Fatalf("tests[%d] - token value wrong. expected=%q, got=%q",
+				i, tt.expectedValue, tok.Value)
+		}
+	}
+}
+
+func TestModuloOperators(t *testing.T) {
+	input := `7 % 3 // 2 rem 1 / remainder`
+
+	tests := []struct {
+		expectedType  token.TokenType
+		expectedValue string
+	}{
+		{token.NUMBER, "7"},
+		{token.MODULO, "%"},
+		{token.NUMBER, "3"},
+		{token.FLOOR_DIVIDE, "//"},
+		{token.NUMBER, "2"},
+		{token.REM, "rem"},
+		{token.NUMBER, "1"},
+		{token.DIVIDE, "/"},
+		{token.IDENT, "remainder"},
+		{token.EOF, ""},
+	}
+
+	l := New(input)
+
+	for i, tt := range tests {
+		tok := l.GetNextToken()
+
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - token type wrong. expected=%q, got=%q",
+				i, tt.expectedType, tok.Type)
+		}
+
+		if tok.Value != tt.expectedValue {
+			t.Fatalf("tests[%d] - token value wrong. expected=%q, got=%q",
+				i, tt.expectedValue, tok.Value)
+		}
+	}
+}
+
+func TestIdentifiersAndAssignment(t *testing.T) {
+	input := `rate_2 = x1 * _y`
+
+	tests := []struct {
+		expectedType  token.TokenType
+		expectedValue string
+	}{
+		{token.IDENT, "rate_2"},
+		{token.ASSIGN, "="},
+		{token.IDENT, "x1"},
+		{token.MULTIPLY, "*"},
+		{token.IDENT, "_y"},
+		{token.EOF, ""},
+	}
+
+	l := New(input)
+
+	for i, tt := range tests {
+		tok := l.GetNextToken()
+
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - token type wrong. expected=%q, got=%q",
+				i, tt.expectedType, tok.Type)
+		}
+
+		if tok.Value != tt.expectedValue {
+			t.Fatalf("tests[%d] - token value wrong. expected=%q, got=%q",
+				i, tt.expectedValue, tok.Value)
+		}
+	}
+}
+
+func TestImaginaryNumbers(t *testing.T) {
+	// a suffix is only taken when it doesn't start a longer word
+	input := `3i + 2.5j * 4 inch 1jx`
+
+	tests := []struct {
+		expectedType  token.TokenType
+		expectedValue string
+	}{
+		{token.IMAGINARY, "3i"},
+		{token.PLUS, "+"},
+		{token.IMAGINARY, "2.5j"},
+		{token.MULTIPLY, "*"},
+		{token.NUMBER, "4"},
+		{token.IDENT, "inch"},
+		{token.NUMBER, "1"},
+		{token.IDENT, "jx"},
+		{token.EOF, ""},
+	}
+
+	l := New(input)
+
+	for i, tt := range tests {
+		tok := l.GetNextToken()
+
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - token type wrong. expected=%q, got=%q",
+				i, tt.expectedType, tok.Type)
+		}
+
+		if tok.Value != tt.expectedValue {
+			t.Fatalf("tests[%d] - token value wrong. expected=%q, got=%q",
+				i, tt.expectedValue, tok.Value)
+		}
+	}
+}
+
+func TestIntervalTokens(t *testing.T) {
+	input := `[1.9, 2.1] * 2±0.1`
+
+	tests := []struct {
+		expectedType  token.TokenType
+		expectedValue string
+	}{
+		{token.LBRACKET, "["},
+		{token.NUMBER, "1.9"},
+		{token.COMMA, ","},
+		{token.NUMBER, "2.1"},
+		{token.RBRACKET, "]"},
+		{token.MULTIPLY, "*"},
+		{token.NUMBER, "2"},
+		{token.PLUSMINUS, "±"},
+		{token.NUMBER, "0.1"},
+		{token.EOF, ""},
+	}
+
+	l := New(input)
+
+	for i, tt := range tests {
+		tok := l.GetNextToken()
+
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - token type wrong. expected=%q, got=%q",
+				i, tt.expectedType, tok.Type)
+		}
+
+		if tok.Value != tt.expectedValue {
+			t.Fatalf("tests[%d] - token value wrong. expected=%q, got=%q",
+				i, tt.expectedValue, tok.Value)
+		}
+	}
+}
+
+func TestUnitTokens(t *testing.T) {
+	input := `60 mph to km/h in inch`
+
+	tests := []struct {
+		expectedType  token.TokenType
+		expectedValue string
+	}{
+		{token.NUMBER, "60"},
+		{token.IDENT, "mph"},
+		{token.TO, "to"},
+		{token.IDENT, "km"},
+		{token.DIVIDE, "/"},
+		{token.IDENT, "h"},
+		{token.TO, "in"},
+		{token.IDENT, "inch"},
+		{token.EOF, ""},
+	}
+
+	l := New(input)
+
+	for i, tt := range tests {
+		tok := l.GetNextToken()
+
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - token type wrong. expected=%q, got=%q",
+				i, tt.expectedType, tok.Type)
+		}
+
+		if tok.Value != tt.expectedValue {
+			t.Fatalf("tests[%d] - token value wrong. expected=%q, got=%q",
+				i, tt.expectedValue, tok.Value)
+		}
+	}
+}
+
+func TestDateAndDurationTokens(t *testing.T) {
+	input := `2026-10-17T09:00Z + 4h30m - 2026-10-17 * 1.5d 2026-10-17x 3dx 10ms`
+
+	tests := []struct {
+		expectedType  token.TokenType
+		expectedValue string
+	}{
+		{token.DATETIME, "2026-10-17T09:00Z"},
+		{token.PLUS, "+"},
+		{token.DURATION, "4h30m"},
+		{token.MINUS, "-"},
+		{token.DATETIME, "2026-10-17"},
+		{token.MULTIPLY, "*"},
+		{token.DURATION, "1.5d"},
+		// followed by more of a word, these are a number and an identifier
+		{token.NUMBER, "2026"},
+		{token.MINUS, "-"},
+		{token.NUMBER, "10"},
+		{token.MINUS, "-"},
+		{token.NUMBER, "17"},
+		{token.IDENT, "x"},
+		{token.NUMBER, "3"},
+		{token.IDENT, "dx"},
+		{token.NUMBER, "10"},
+		{token.IDENT, "ms"},
+		{token.EOF, ""},
+	}
+
+	l := New(input)
+	l.EnableDates()
+
+	for i, tt := range tests {
+		tok := l.GetNextToken()
+
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - token type wrong. expected=%q, got=%q",
+				i, tt.expectedType, tok.Type)
+		}
+
+		if tok.Value != tt.expectedValue {
+			t.Fatalf("tests[%d] - token value wrong. expected=%q, got=%q",
+				i, tt.expectedValue, tok.Value)
+		}
+	}
+}
+
+func TestDatesNotEnabled(t *testing.T) {
+	// outside date mode, dates are arithmetic and durations are quantities
+	input := `2026-10-17 5m`
+
+	tests := []struct {
+		expectedType  token.TokenType
+		expectedValue string
+	}{
+		{token.NUMBER, "2026"},
+		{token.MINUS, "-"},
+		{token.NUMBER, "10"},
+		{token.MINUS, "-"},
+		{token.NUMBER, "17"},
+		{token.NUMBER, "5"},
+		{token.IDENT, "m"},
+		{token.EOF, ""},
+	}
+
+	l := New(input)
+
+	for i, tt := range tests {
+		tok := l.GetNextToken()
+
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - token type wrong. expected=%q, got=%q",
+				i, tt.expectedType, tok.Type)
+		}
+		if tok.Value != tt.expectedValue {
+			t.Fatalf("tests[%d] - token value wrong. expected=%q, got=%q",
+				i, tt.expectedValue, tok.Value)
+		}
+	}
+}
+
+func TestComparisonAndLogicalTokens(t *testing.T) {
+	input := `x == 1 != y < 2 <= 3 > 4 >= 5 && true || !false = 6 & 7 | 8`
+
+	tests := []struct {
+		expectedType  token.TokenType
+		expectedValue string
+	}{
+		{token.IDENT, "x"},
+		{token.EQ, "=="},
+		{token.NUMBER, "1"},
+		{token.NOT_EQ, "!="},
+		{token.IDENT, "y"},
+		{token.LT, "<"},
+		{token.NUMBER, "2"},
+		{token.LT_EQ, "<="},
+		{token.NUMBER, "3"},
+		{token.GT, ">"},
+		{token.NUMBER, "4"},
+		{token.GT_EQ, ">="},
+		{token.NUMBER, "5"},
+		{token.AND, "&&"},
+		{token.TRUE, "true"},
+		{token.OR, "||"},
+		{token.NOT, "!"},
+		{token.FALSE, "false"},
+		{token.ASSIGN, "="},
+		{token.NUMBER, "6"},
+		{token.ILLEGAL, "&"},
+		{token.NUMBER, "7"},
+		{token.ILLEGAL, "|"},
+		{token.NUMBER, "8"},
+		{token.EOF, ""},
+	}
+
+	l := New(input)
+
+	for i, tt := range tests {
+		tok := l.GetNextToken()
+
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - token type wrong. expected=%q, got=%q",
+				i, tt.expectedType, tok.Type)
+		}
+
+		if tok.Value != tt.expectedValue {
+			t.Fatalf("tests[%d] - token value wrong. expected=%q, got=%q",
+				i, tt.expectedValue, tok.Value)
+		}
+	}
+
+	expectedErrors := []string{"invalid character '&'", "invalid character '|'"}
+	if len(l.Errors()) != len(expectedErrors) {
+		t.Fatalf("wrong number of errors. expected=%d, got=%d", len(expectedErrors), len(l.Errors()))
+	}
+	for i, msg := range expectedErrors {
+		if l.Errors()[i].Message != msg {
+			t.Errorf("errors[%d] wrong. expected=%q, got=%q", i, msg, l.Errors()[i].Message)
+		}
+	}
+}
+
+func TestConditionalTokens(t *testing.T) {
+	input := `x ? 1 : 2 if ok then a else b iffy`
+
+	tests := []struct {
+		expectedType  token.TokenType
+		expectedValue string
+	}{
+		{token.IDENT, "x"},
+		{token.QUESTION, "?"},
+		{token.NUMBER, "1"},
+		{token.COLON, ":"},
+		{token.NUMBER, "2"},
+		{token.IF, "if"},
+		{token.IDENT, "ok"},
+		{token.THEN, "then"},
+		{token.IDENT, "a"},
+		{token.ELSE, "else"},
+		{token.IDENT, "b"},
+		{token.IDENT, "iffy"},
+		{token.EOF, ""},
+	}
+
+	l := New(input)
+
+	for i, tt := range tests {
+		tok := l.GetNextToken()
+
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - token type wrong. expected=%q, got=%q",
+				i, tt.expectedType, tok.Type)
+		}
+
+		if tok.Value != tt.expectedValue {
+			t.Fatalf("tests[%d] - token value wrong. expected=%q, got=%q",
+				i, tt.expectedValue, tok.Value)
+		}
+	}
+}
+
+func TestMatmulToken(t *testing.T) {
+	input := `a @ b@[1]`
+
+	tests := []struct {
+		expectedType  token.TokenType
+		expectedValue string
+	}{
+		{token.IDENT, "a"},
+		{token.MATMUL, "@"},
+		{token.IDENT, "b"},
+		{token.MATMUL, "@"},
+		{token.LBRACKET, "["},
+		{token.NUMBER, "1"},
+		{token.RBRACKET, "]"},
+		{token.EOF, ""},
+	}
+
+	l := New(input)
+
+	for i, tt := range tests {
+		tok := l.GetNextToken()
+
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - token type wrong. expected=%q, got=%q",
+				i, tt.expectedType, tok.Type)
+		}
+		if tok.Value != tt.expectedValue {
+			t.Fatalf("tests[%d] - token value wrong. expected=%q, got=%q",
+				i, tt.expectedValue, tok.Value)
+		}
+	}
+}
+
+func TestArrowToken(t *testing.T) {
+	input := `(a, b) -> a - b->-1`
+
+	tests := []struct {
+		expectedType  token.TokenType
+		expectedValue string
+	}{
+		{token.LPAREN, "("},
+		{token.IDENT, "a"},
+		{token.COMMA, ","},
+		{token.IDENT, "b"},
+		{token.RPAREN, ")"},
+		{token.ARROW, "->"},
+		{token.IDENT, "a"},
+		{token.MINUS, "-"},
+		{token.IDENT, "b"},
+		{token.ARROW, "->"},
+		{token.MINUS, "-"},
+		{token.NUMBER, "1"},
+		{token.EOF, ""},
+	}
+
+	l := New(input)
+
+	for i, tt := range tests {
+		tok := l.GetNextToken()
+
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - token type wrong. expected=%q, got=%q",
+				i, tt.expectedType, tok.Type)
+		}
+
+		if tok.Value != tt.expectedValue {
+			t.Fatalf("tests[%d] - token value wrong. expected=%q, got=%q",
+				i, tt.expectedValue, tok.Value)
+		}
+	}
+}
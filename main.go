@@ -2,32 +2,66 @@ package main
 
 import (
 	"basic-arithmetic-parser/ast"
+	"basic-arithmetic-parser/diagnostic"
 	"basic-arithmetic-parser/eval"
 	"basic-arithmetic-parser/lexer"
 	"basic-arithmetic-parser/parser"
 	"bufio"
+	"errors"
 	"flag"
 	"fmt"
 	"io"
+	"math/big"
 	"os"
 	"strings"
 )
 
 var printAST = flag.Bool("ast", false, "Print the Abstract Syntax Tree")
 var inputFile = flag.String("input", "", "Input file to read expressions from")
+var colorMode = flag.String("color", "auto", "Colorize error output: auto, always or never")
+var evalMode = flag.String("mode", "float", "Evaluation mode: float, rational, int, complex, decimal, interval, uncertainty, units or date")
+var strictInt = flag.Bool("strict", false, "In int mode, report floating point values as errors instead of promoting them")
+var precision = flag.Uint("precision", 0, "Evaluate with this many significant digits instead of float64 (float mode only)")
+var roundingMode = flag.String("rounding", "half-even", "Rounding mode with -precision and in decimal mode: half-even, half-up, down, up, floor or ceiling")
+var scale = flag.Int("scale", 2, "Number of decimal places in decimal mode")
+var maxDepth = flag.Int("max-depth", eval.DefaultMaxDepth, "Maximum depth of calls to user-defined functions (float mode only)")
 
-func parseExpression(input string) (ast.Node, error) {
-	// TODO: parser needs changes to collect errors rather than this 'exception handling' hack
-	defer func() {
-		if r := recover(); r != nil {
-			fmt.Println("Error:", r)
-		}
-	}()
+// roundingModes maps the -rounding flag values to big.Float rounding modes
+var roundingModes = map[string]big.RoundingMode{
+	"half-even": big.ToNearestEven,
+	"half-up":   big.ToNearestAway,
+	"down":      big.ToZero,
+	"up":        big.AwayFromZero,
+	"floor":     big.ToNegativeInf,
+	"ceiling":   big.ToPositiveInf,
+}
+
+// decimalRoundingModes maps the -rounding flag values to the rounding modes
+// of decimal mode
+var decimalRoundingModes = map[string]eval.RoundingMode{
+	"half-even": eval.RoundHalfEven,
+	"half-up":   eval.RoundHalfUp,
+	"down":      eval.RoundDown,
+	"up":        eval.RoundUp,
+	"floor":     eval.RoundFloor,
+	"ceiling":   eval.RoundCeiling,
+}
 
-	input = strings.TrimSpace(input)
+func parseExpression(input string, resolver parser.Resolver) (ast.Node, error) {
 	l := lexer.New(input)
+	if *evalMode == "date" {
+		l.EnableDates()
+	}
 	p := parser.New(l)
-	return p.Parse(), nil
+	if *evalMode == "units" {
+		p.EnableUnits()
+	}
+	// the other modes have functions of their own, which they check while
+	// evaluating
+	if *evalMode == "float" {
+		p.SetResolver(resolver)
+	}
+	return p.Parse()
 }
 
 func getInfix(exprAst *ast.Node) string {
@@ -43,17 +77,121 @@ func showAST(exprAst *ast.Node) {
 	}
 }
 
-func doEval(exprAst *ast.Node, prefix *string) {
-	result, evalErr := eval.Eval(*exprAst)
+// useColor reports whether error output should be colorized, based on the
+// -color flag and, for "auto", whether stdout is a terminal
+func useColor() bool {
+	switch *colorMode {
+	case "always":
+		return true
+	case "never":
+		return false
+	}
+	// https://no-color.org
+	if os.Getenv("NO_COLOR") != "" {
+		return false
+	}
+	info, err := os.Stdout.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// reportError renders err against the source it came from. Parse errors
+// carry every diagnostic that was collected; each one is shown in turn.
+func reportError(err error, source string, renderer diagnostic.Renderer) {
+	var parseErrs *parser.ParseErrors
+	var d diagnostic.Diagnostic
+	switch {
+	case errors.As(err, &parseErrs):
+		for _, d := range parseErrs.Diagnostics {
+			fmt.Print(renderer.Render(source, d))
+		}
+	case errors.As(err, &d):
+		fmt.Print(renderer.Render(source, d))
+	default:
+		fmt.Print(renderer.Render(source, diagnostic.Diagnostic{Message: err.Error()}))
+	}
+}
+
+// evaluate evaluates exprAst in the mode selected with -mode and
+// -precision and formats the result
+func evaluate(exprAst ast.Node, evaluator *eval.Evaluator) (string, error) {
+	switch {
+	case *evalMode == "rational":
+		result, err := eval.EvalRational(exprAst)
+		if err != nil {
+			return "", err
+		}
+		return eval.FormatRat(result), nil
+	case *evalMode == "int":
+		result, err := eval.EvalInt(exprAst, *strictInt)
+		if err != nil {
+			return "", err
+		}
+		return result.String(), nil
+	case *evalMode == "complex":
+		result, err := eval.EvalComplex(exprAst)
+		if err != nil {
+			return "", err
+		}
+		return eval.FormatComplex(result), nil
+	case *evalMode == "decimal":
+		result, err := eval.EvalDecimal(exprAst, *scale, decimalRoundingModes[*roundingMode])
+		if err != nil {
+			return "", err
+		}
+		return result.String(), nil
+	case *evalMode == "interval":
+		result, err := eval.EvalInterval(exprAst)
+		if err != nil {
+			return "", err
+		}
+		return result.String(), nil
+	case *evalMode == "uncertainty":
+		result, err := eval.EvalUncertainty(exprAst)
+		if err != nil {
+			return "", err
+		}
+		return result.String(), nil
+	case *evalMode == "units":
+		result, err := eval.EvalUnits(exprAst)
+		if err != nil {
+			return "", err
+		}
+		return result.String(), nil
+	case *evalMode == "date":
+		result, err := eval.EvalDate(exprAst, nil)
+		if err != nil {
+			return "", err
+		}
+		return result.String(), nil
+	case *precision > 0:
+		// evaluate with more bits than the digits need, so the result is
+		// rounded to them only once, when it is formatted
+		mode := roundingModes[*roundingMode]
+		result, err := eval.EvalBigFloat(exprAst, eval.DigitsPrecision(*precision), mode)
+		if err != nil {
+			return "", err
+		}
+		return eval.FormatBigFloat(result, *precision, mode), nil
+	default:
+		result, err := evaluator.Eval(exprAst)
+		if err != nil {
+			return "", err
+		}
+		return result.String(), nil
+	}
+}
+
+func doEval(exprAst *ast.Node, evaluator *eval.Evaluator, source string, renderer diagnostic.Renderer) {
+	result, evalErr := evaluate(*exprAst, evaluator)
 	if evalErr != nil {
 		// no need to propagate the error; each use will continue
-		fmt.Printf("  %sEvaluation error: %v\n", *prefix, evalErr)
+		reportError(evalErr, source, renderer)
 	} else {
-		fmt.Printf("Result =  '%g'\n", result)
+		fmt.Printf("Result =  '%s'\n", result)
 	}
 }
 
-func processLine(line string, lineNum int) {
+func processLine(line string, lineNum int, evaluator *eval.Evaluator) {
 	input := strings.TrimSpace(line)
 	if input == "" {
 		return
@@ -62,26 +200,27 @@ func processLine(line string, lineNum int) {
 	// Print the line being processed from the file
 	fmt.Printf("%s'%s'\n", prefix, input)
 
-	exprAst, parseErr := parseExpression(input)
+	// line numbers in diagnostics are relative to this line, so offset them
+	// to match the file
+	renderer := diagnostic.Renderer{Name: *inputFile, LineOffset: lineNum - 1, Color: useColor()}
+	exprAst, parseErr := parseExpression(line, evaluator)
 	if parseErr != nil {
-		fmt.Printf("  %sError: %v\n", prefix, parseErr)
+		reportError(parseErr, line, renderer)
+		// the partial AST can still help locate the problem
+		showAST(&exprAst)
 		// Exit if parsing failed
 		return
 	}
 
-	// If exprAst is nil after parseExpression returns (without error),
-	// it might mean a parsing issue was recovered via panic.
-	// We check again to avoid nil pointer dereference.
-	if exprAst == nil {
-		return
-	}
-
 	showAST(&exprAst)
-	doEval(&exprAst, &prefix)
+	doEval(&exprAst, evaluator, line, renderer)
 }
 
 func repl() {
 	reader := bufio.NewReader(os.Stdin)
+	// variables persist from one line to the next
+	evaluator := eval.NewEvaluator(nil, nil)
+	evaluator.SetMaxDepth(*maxDepth)
 	for {
 		fmt.Print("> ")
 		input, err := reader.ReadString('\n')
@@ -102,14 +241,16 @@ func repl() {
 			continue
 		}
 
-		exprAst, parseErr := parseExpression(input)
+		renderer := diagnostic.Renderer{Color: useColor()}
+		exprAst, parseErr := parseExpression(input, evaluator)
 		if parseErr != nil {
-			fmt.Printf("  Error: %v\n", parseErr)
+			reportError(parseErr, input, renderer)
+			showAST(&exprAst)
 			continue
 		}
 
 		showAST(&exprAst)
-		doEval(&exprAst, nil)
+		doEval(&exprAst, evaluator, input, renderer)
 	}
 
 }
@@ -125,16 +266,41 @@ func processFile(filePath string) {
 	fmt.Printf("Using input file: %s\n", *inputFile)
 	scanner := bufio.NewScanner(file)
 	lineNum := 0
+	// later lines can use variables assigned on earlier ones
+	evaluator := eval.NewEvaluator(nil, nil)
+	evaluator.SetMaxDepth(*maxDepth)
 
 	// we expect an input file where each line contains a single expression
 	for scanner.Scan() {
 		lineNum++
-		processLine(scanner.Text(), lineNum)
+		processLine(scanner.Text(), lineNum, evaluator)
 	}
 }
 
 func main() {
 	flag.Parse()
+	switch *evalMode {
+	case "float", "rational", "int", "complex", "decimal", "interval", "uncertainty", "units", "date":
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown mode %q: expected float, rational, int, complex, decimal, interval, uncertainty, units or date\n", *evalMode)
+		os.Exit(2)
+	}
+	if *precision > 0 && *evalMode != "float" {
+		fmt.Fprintln(os.Stderr, "-precision can only be used in float mode")
+		os.Exit(2)
+	}
+	if *maxDepth < 1 {
+		fmt.Fprintln(os.Stderr, "-max-depth must be at least 1")
+		os.Exit(2)
+	}
+	if *scale < 0 {
+		fmt.Fprintln(os.Stderr, "-scale must not be negative")
+		os.Exit(2)
+	}
+	if _, ok := roundingModes[*roundingMode]; !ok {
+		fmt.Fprintf(os.Stderr, "Unknown rounding mode %q: expected half-even, half-up, down, up, floor or ceiling\n", *roundingMode)
+		os.Exit(2)
+	}
 	fmt.Println("Basic Arithmetic Parser REPL")
 	fmt.Println("Enter expressions to evaluate or type 'exit' to quit.")
 
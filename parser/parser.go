@@ -2,15 +2,57 @@ package parser
 
 import (
 	"basic-arithmetic-parser/ast"
+	"basic-arithmetic-parser/diagnostic"
 	"basic-arithmetic-parser/lexer"
 	"basic-arithmetic-parser/token"
 	"fmt"
+	"math"
+	"regexp"
+	"slices"
+	"sort"
 	"strconv"
+	"strings"
+	"time"
 )
 
+// ParseErrors is returned by Parse when the input contains lexical or syntax
+// errors. It holds every diagnostic that was collected.
+type ParseErrors struct {
+	Diagnostics []diagnostic.Diagnostic
+}
+
+func (e *ParseErrors) Error() string {
+	messages := make([]string, len(e.Diagnostics))
+	for i, d := range e.Diagnostics {
+		messages[i] = d.Error()
+	}
+	return strings.Join(messages, "; ")
+}
+
+// Resolver tells the parser which names can be called as functions.
+// eval.Registry and eval.Evaluator implement it.
+type Resolver interface {
+	IsFunction(name string) bool
+}
+
 type Parser struct {
 	lexer        *lexer.Lexer
 	currentToken token.Token
+	peekToken    token.Token
+	resolver     Resolver
+	// whether a name after a number is its unit
+	units bool
+	// the names of the functions called in the statement, checked against
+	// the resolver once the whole statement has been parsed
+	calls []*ast.IdentNode
+	// the parameters of the lambdas being parsed, which can be called
+	// without the resolver knowing them
+	locals []string
+	errors []diagnostic.Diagnostic
+	// set after a syntax error until the next token is successfully
+	// consumed; errors in between are likely a cascade of the first one and
+	// are not reported
+	recovering bool
 }
 
 func New(lexer *lexer.Lexer) *Parser {
@@ -18,16 +60,355 @@ func New(lexer *lexer.Lexer) *Parser {
 		lexer: lexer,
 	}
 	p.currentToken = p.lexer.GetNextToken()
+	p.peekToken = p.lexer.GetNextToken()
 	return p
 }
 
+// SetResolver makes the parser report calls to functions that r does not
+// know about. Without a resolver any name can be called, and unknown
+// functions are only found during evaluation.
+func (p *Parser) SetResolver(r Resolver) {
+	p.resolver = r
+}
+
+// EnableUnits makes the parser read a name straight after a number as its
+// unit, so that 3 m is a QuantityNode, for units mode. Otherwise a name
+// after a number is a syntax error, as an operator is missing between them.
+func (p *Parser) EnableUnits() {
+	p.units = true
+}
+
+// errorAt records an error covering span
+func (p *Parser) errorAt(span token.Span, msg, label string) {
+	p.errors = append(p.errors, diagnostic.Diagnostic{Span: span, Message: msg, Label: label})
+}
+
+// syntaxError records a syntax error at the current token unless it is an
+// ILLEGAL token (already reported by the lexer) or the parser is still
+// recovering from an earlier error. label is a short note shown under the
+// token when the error is rendered.
+func (p *Parser) syntaxError(msg, label string) {
+	if !p.recovering && p.currentToken.Type != token.ILLEGAL {
+		p.errorAt(p.currentToken.Span, msg, label)
+	}
+	p.recovering = true
+}
+
+// nextToken moves on to the next token from the lexer
+func (p *Parser) nextToken() {
+	p.currentToken = p.peekToken
+	p.peekToken = p.lexer.GetNextToken()
+}
+
+// isSyncToken reports whether parsing can resume at the current token after
+// an error: at an operator, a closing parenthesis or the end of input
+func (p *Parser) isSyncToken() bool {
+	switch p.currentToken.Type {
+	case token.PLUS, token.MINUS, token.RPAREN, token.RBRACKET, token.COMMA, token.EOF, token.POWER, token.PLUSMINUS, token.TO, token.OF,
+		token.AND, token.OR, token.QUESTION, token.COLON, token.THEN, token.ELSE, token.ARROW:
+		return true
+	}
+	return isTermOperator(p.currentToken.Type) || isComparisonOperator(p.currentToken.Type)
+}
+
+// isTermOperator reports whether tokenType is one of the operators at the
+// precedence of multiplication
+func isTermOperator(tokenType token.TokenType) bool {
+	switch tokenType {
+	case token.MULTIPLY, token.DIVIDE, token.MODULO, token.FLOOR_DIVIDE, token.REM, token.MATMUL:
+		return true
+	}
+	return false
+}
+
+// isComparisonOperator reports whether tokenType is one of == != < <= > >=
+func isComparisonOperator(tokenType token.TokenType) bool {
+	switch tokenType {
+	case token.EQ, token.NOT_EQ, token.LT, token.LT_EQ, token.GT, token.GT_EQ:
+		return true
+	}
+	return false
+}
+
+// skipToClosingParen discards tokens up to and including the ')' that closes
+// the current parenthesised expression, keeping track of nested pairs
+func (p *Parser) skipToClosingParen() {
+	depth := 0
+	for p.currentToken.Type != token.EOF {
+		switch p.currentToken.Type {
+		case token.LPAREN:
+			depth++
+		case token.RPAREN:
+			if depth == 0 {
+				p.eat(token.RPAREN)
+				return
+			}
+			depth--
+		}
+		p.nextToken()
+	}
+}
+
+// spanBetween returns the span covering both left and right
+func spanBetween(left, right ast.Node) token.Span {
+	return token.Span{Start: left.Span().Start, End: right.Span().End}
+}
+
+// spanFrom returns the span from start to the end of node
+func spanFrom(start token.Position, node ast.Node) token.Span {
+	return token.Span{Start: start, End: node.Span().End}
+}
+
+// setSpan overrides the span recorded on node
+func setSpan(node ast.Node, span token.Span) {
+	switch n := node.(type) {
+	case *ast.NumberNode:
+		n.Loc = span
+	case *ast.BinaryOpNode:
+		n.Loc = span
+	case *ast.UnaryOpNode:
+		n.Loc = span
+	case *ast.IdentNode:
+		n.Loc = span
+	case *ast.ListNode:
+		n.Loc = span
+	case *ast.IndexNode:
+		n.Loc = span
+	case *ast.ApplyNode:
+		n.Loc = span
+	case *ast.SliceNode:
+		n.Loc = span
+	case *ast.QuantityNode:
+		n.Loc = span
+	case *ast.ConvertNode:
+		n.Loc = span
+	case *ast.DateNode:
+		n.Loc = span
+	case *ast.DurationNode:
+		n.Loc = span
+	case *ast.PercentNode:
+		n.Loc = span
+	case *ast.BoolNode:
+		n.Loc = span
+	case *ast.ConditionalNode:
+		n.Loc = span
+	case *ast.LambdaNode:
+		n.Loc = span
+	case *ast.ErrorNode:
+		n.Loc = span
+	}
+}
+
 func (p *Parser) eat(tokenType token.TokenType) {
 	if p.currentToken.Type == tokenType {
-		p.currentToken = p.lexer.GetNextToken()
+		p.nextToken()
+		p.recovering = false
 	} else {
-		// parse error -- TODO: collect errors instead of panic
-		panic(fmt.Sprintf("Syntax error: expected %v, got %v", tokenType, p.currentToken.Type))
+		p.syntaxError(
+			fmt.Sprintf("expected %v, found %v", tokenType, p.currentToken.Type),
+			fmt.Sprintf("expected %v", tokenType),
+		)
+	}
+}
+
+// statement → IDENT ASSIGN conversion | call ASSIGN conversion | conversion
+//
+// A call followed by '=' defines a function, whose arguments must be the
+// names of its parameters.
+func (p *Parser) statement() ast.Node {
+	if p.currentToken.Type == token.IDENT && p.peekToken.Type == token.LPAREN {
+		node := p.conversion()
+		call, ok := node.(*ast.CallNode)
+		if !ok || p.currentToken.Type != token.ASSIGN {
+			return node
+		}
+		p.eat(token.ASSIGN)
+		return p.functionDef(call, p.conversion())
+	}
+	if p.currentToken.Type == token.IDENT && p.peekToken.Type == token.ASSIGN {
+		name := &ast.IdentNode{Name: p.currentToken.Value, Loc: p.currentToken.Span}
+		p.eat(token.IDENT)
+		p.eat(token.ASSIGN)
+		value := p.conversion()
+		return &ast.AssignNode{
+			Name:  name,
+			Value: value,
+			Loc:   spanBetween(name, value),
+		}
+	}
+	return p.conversion()
+}
+
+// functionDef turns the call on the left of a definition into the
+// function's name and parameters
+func (p *Parser) functionDef(call *ast.CallNode, body ast.Node) ast.Node {
+	return &ast.FunctionDefNode{Name: call.Name, Params: p.params(call.Args), Body: body, Loc: spanBetween(call, body)}
+}
+
+// params checks that the expressions written as the parameters of a
+// function or lambda are distinct names, and returns them
+func (p *Parser) params(exprs []ast.Node) []*ast.IdentNode {
+	var params []*ast.IdentNode
+	seen := map[string]bool{}
+	for _, expr := range exprs {
+		param, ok := expr.(*ast.IdentNode)
+		if !ok {
+			if _, isError := expr.(*ast.ErrorNode); !isError {
+				p.errorAt(expr.Span(), "function parameters must be names", "not a name")
+			}
+			continue
+		}
+		if seen[param.Name] {
+			p.errorAt(param.Loc, fmt.Sprintf("duplicate parameter %s", param.Name), "already a parameter")
+		}
+		seen[param.Name] = true
+		params = append(params, param)
 	}
+	return params
+}
+
+// lambda → ARROW conditional
+//
+// exprs are the parameters before the arrow, already parsed as expressions
+// from x or (a, b). The body extends as far right as it can, so
+// x -> x + 1 is x -> (x + 1).
+func (p *Parser) lambda(start token.Position, exprs []ast.Node) ast.Node {
+	params := p.params(exprs)
+	p.eat(token.ARROW)
+	for _, param := range params {
+		p.locals = append(p.locals, param.Name)
+	}
+	body := p.conditional()
+	p.locals = p.locals[:len(p.locals)-len(params)]
+	return &ast.LambdaNode{Params: params, Body: body, Loc: spanFrom(start, body)}
+}
+
+// checkCalls reports the calls in node to functions the resolver does not
+// know about. A function being defined can call itself, and its parameters
+// are not checked either; neither can a lambda being assigned to a name,
+// as in f = x -> x <= 1 ? 1 : x * f(x - 1).
+func (p *Parser) checkCalls(node ast.Node) {
+	if p.resolver == nil {
+		return
+	}
+	local := map[string]bool{}
+	switch n := node.(type) {
+	case *ast.FunctionDefNode:
+		local[n.Name.Name] = true
+		for _, param := range n.Params {
+			local[param.Name] = true
+		}
+	case *ast.AssignNode:
+		if _, ok := n.Value.(*ast.LambdaNode); ok {
+			local[n.Name.Name] = true
+		}
+	}
+	for _, name := range p.calls {
+		if !local[name.Name] && !p.resolver.IsFunction(name.Name) {
+			p.errorAt(name.Loc, fmt.Sprintf("unknown function: %s", name.Name), "not a known function")
+		}
+	}
+}
+
+// conversion → conditional (TO unit)?
+//
+// The conversion applies to the whole expression, so 1 m + 2 ft to cm is
+// (1 m + 2 ft) to cm.
+func (p *Parser) conversion() ast.Node {
+	node := p.conditional()
+
+	if p.currentToken.Type == token.TO {
+		p.eat(token.TO)
+		unit := p.unit()
+		node = &ast.ConvertNode{
+			Expr: node,
+			Unit: unit,
+			Loc:  token.Span{Start: node.Span().Start, End: unit.Loc.End},
+		}
+	}
+
+	return node
+}
+
+// conditional → or (QUESTION conditional COLON conditional)?
+//
+// The branches extend as far right as they can, so a ? b : c ? d : e is
+// a ? b : (c ? d : e).
+func (p *Parser) conditional() ast.Node {
+	node := p.or()
+
+	if p.currentToken.Type == token.QUESTION {
+		p.eat(token.QUESTION)
+		then := p.conditional()
+		p.eat(token.COLON)
+		els := p.conditional()
+		node = &ast.ConditionalNode{Cond: node, Then: then, Else: els, Loc: spanBetween(node, els)}
+	}
+
+	return node
+}
+
+// or → and (OR and)*
+func (p *Parser) or() ast.Node {
+	node := p.and()
+
+	for p.currentToken.Type == token.OR {
+		currTok := p.currentToken
+		p.eat(token.OR)
+		right := p.and()
+		node = &ast.BinaryOpNode{
+			Left:  node,
+			Op:    currTok,
+			Right: right,
+			Loc:   spanBetween(node, right),
+		}
+	}
+
+	return node
+}
+
+// and → comparison (AND comparison)*
+func (p *Parser) and() ast.Node {
+	node := p.comparison()
+
+	for p.currentToken.Type == token.AND {
+		currTok := p.currentToken
+		p.eat(token.AND)
+		right := p.comparison()
+		node = &ast.BinaryOpNode{
+			Left:  node,
+			Op:    currTok,
+			Right: right,
+			Loc:   spanBetween(node, right),
+		}
+	}
+
+	return node
+}
+
+// comparison → expr ((EQ | NOT_EQ | LT | LT_EQ | GT | GT_EQ) expr)?
+//
+// Comparisons do not chain: 1 < x < 3 is a syntax error rather than
+// (1 < x) < 3, which would compare a bool with a number.
+func (p *Parser) comparison() ast.Node {
+	node := p.expr()
+
+	if isComparisonOperator(p.currentToken.Type) {
+		currTok := p.currentToken
+		p.eat(currTok.Type)
+		right := p.expr()
+		node = &ast.BinaryOpNode{
+			Left:  node,
+			Op:    currTok,
+			Right: right,
+			Loc:   spanBetween(node, right),
+		}
+		if isComparisonOperator(p.currentToken.Type) {
+			p.syntaxError("comparisons cannot be chained", "use && to combine comparisons")
+		}
+	}
+
+	return node
 }
 
 // expr → term ((PLUS | MINUS) term)*
@@ -38,17 +419,21 @@ func (p *Parser) expr() ast.Node {
 		currTok := p.currentToken
 		if currTok.Type == token.PLUS {
 			p.eat(token.PLUS)
+			right := p.term()
 			node = &ast.BinaryOpNode{
 				Left:  node,
 				Op:    currTok,
-				Right: p.term(),
+				Right: right,
+				Loc:   spanBetween(node, right),
 			}
 		} else if currTok.Type == token.MINUS {
 			p.eat(token.MINUS)
+			right := p.term()
 			node = &ast.BinaryOpNode{
 				Left:  node,
 				Op:    currTok,
-				Right: p.term(),
+				Right: right,
+				Loc:   spanBetween(node, right),
 			}
 		}
 	}
@@ -56,25 +441,86 @@ func (p *Parser) expr() ast.Node {
 	return node
 }
 
-// term → factor ((MUL | DIV) factor)*
+// term → tolerance ((MUL | DIV | MOD | FLOORDIV | REM | MATMUL) tolerance)*
 func (p *Parser) term() ast.Node {
+	node := p.tolerance()
+
+	for isTermOperator(p.currentToken.Type) {
+		currTok := p.currentToken
+		p.eat(currTok.Type)
+		right := p.tolerance()
+		node = &ast.BinaryOpNode{
+			Left:  node,
+			Op:    currTok,
+			Right: right,
+			Loc:   spanBetween(node, right),
+		}
+	}
+
+	return node
+}
+
+// tolerance → factor (PLUSMINUS factor)?
+//
+// ± binds tighter than * but looser than ^ and a leading sign, so
+// 2±0.1 * 3 is (2±0.1) * 3 and 2^2±0.1 is (2^2)±0.1.
+func (p *Parser) tolerance() ast.Node {
 	node := p.factor()
 
-	for p.currentToken.Type == token.MULTIPLY || p.currentToken.Type == token.DIVIDE {
+	if p.currentToken.Type == token.PLUSMINUS {
 		currTok := p.currentToken
-		if currTok.Type == token.MULTIPLY {
-			p.eat(token.MULTIPLY)
-			node = &ast.BinaryOpNode{
-				Left:  node,
-				Op:    currTok,
-				Right: p.factor(),
-			}
-		} else if currTok.Type == token.DIVIDE {
-			p.eat(token.DIVIDE)
+		p.eat(token.PLUSMINUS)
+		right := p.factor()
+		node = &ast.BinaryOpNode{
+			Left:  node,
+			Op:    currTok,
+			Right: right,
+			Loc:   spanBetween(node, right),
+		}
+	}
+
+	return node
+}
+
+// factor → (PLUS | MINUS | NOT) factor | percent
+func (p *Parser) factor() ast.Node {
+	currTok := p.currentToken
+
+	switch currTok.Type {
+	case token.MINUS, token.PLUS, token.NOT:
+		p.eat(currTok.Type)
+		expr := p.factor()
+		return &ast.UnaryOpNode{
+			Op:   currTok,
+			Expr: expr,
+			Loc:  spanFrom(currTok.Span.Start, expr),
+		}
+	default:
+		return p.percent()
+	}
+}
+
+// percent → power (PERCENT (OF factor)?)?
+//
+// PERCENT is a '%' with no operand after it, so 10% is a percentage while
+// 10 % 3 is still modulo. x% of y is the percentage applied to y.
+func (p *Parser) percent() ast.Node {
+	node := p.power()
+
+	if p.isPercentSign() {
+		end := p.currentToken.Span.End
+		p.eat(token.MODULO)
+		node = &ast.PercentNode{Expr: node, Loc: token.Span{Start: node.Span().Start, End: end}}
+
+		if p.currentToken.Type == token.OF {
+			currTok := p.currentToken
+			p.eat(token.OF)
+			right := p.factor()
 			node = &ast.BinaryOpNode{
 				Left:  node,
 				Op:    currTok,
-				Right: p.factor(),
+				Right: right,
+				Loc:   spanBetween(node, right),
 			}
 		}
 	}
@@ -82,49 +528,427 @@ func (p *Parser) term() ast.Node {
 	return node
 }
 
-// factor → NUMBER | LPAREN expr RPAREN | (PLUS | MINUS) factor
-func (p *Parser) factor() ast.Node {
+// isPercentSign reports whether the current token is a '%' used as a
+// percent sign: one that is not followed by anything that could start its
+// right operand as modulo
+func (p *Parser) isPercentSign() bool {
+	if p.currentToken.Type != token.MODULO {
+		return false
+	}
+	switch p.peekToken.Type {
+	case token.NUMBER, token.IMAGINARY, token.DATETIME, token.DURATION, token.IDENT,
+		token.TRUE, token.FALSE, token.IF, token.LPAREN, token.LBRACKET, token.PLUS, token.MINUS, token.NOT, token.ILLEGAL:
+		return false
+	}
+	return true
+}
+
+// power → postfix (POW factor)?
+//
+// The right operand is a factor, which makes ^ right-associative
+// (2^3^2 == 2^(3^2)) and lets it take a signed exponent (2^-1), while a
+// leading sign applies to the whole power (-2^2 == -(2^2)).
+func (p *Parser) power() ast.Node {
+	node := p.postfix()
+
+	if p.currentToken.Type == token.POWER {
+		currTok := p.currentToken
+		p.eat(token.POWER)
+		right := p.factor()
+		node = &ast.BinaryOpNode{
+			Left:  node,
+			Op:    currTok,
+			Right: right,
+			Loc:   spanBetween(node, right),
+		}
+	}
+
+	return node
+}
+
+// postfix → primary (index | LPAREN args RPAREN)*
+//
+// Indexing and calls bind tighter than any operator, so -v[0]^2 is
+// -((v[0])^2), and they chain, so adder(3)(4) calls the function adder(3)
+// returns.
+func (p *Parser) postfix() ast.Node {
+	node := p.primary()
+	for {
+		switch p.currentToken.Type {
+		case token.LBRACKET:
+			node = p.index(node)
+		case token.LPAREN:
+			args, end := p.args()
+			node = &ast.ApplyNode{Func: node, Args: args, Loc: token.Span{Start: node.Span().Start, End: end}}
+		default:
+			return node
+		}
+	}
+}
+
+// primary → NUMBER unit? | IMAGINARY | DATETIME | DURATION | TRUE | FALSE | IDENT | call | list | ifExpr
+//
+//	| IDENT lambda | LPAREN (IDENT (COMMA IDENT)*)? RPAREN lambda | LPAREN conversion RPAREN
+func (p *Parser) primary() ast.Node {
 	currTok := p.currentToken
 
 	switch currTok.Type {
+	case token.IDENT:
+		if p.peekToken.Type == token.LPAREN {
+			return p.call()
+		}
+		p.eat(token.IDENT)
+		node := &ast.IdentNode{Name: currTok.Value, Loc: currTok.Span}
+		if p.currentToken.Type == token.ARROW {
+			return p.lambda(currTok.Span.Start, []ast.Node{node})
+		}
+		return node
 	case token.NUMBER:
 		p.eat(token.NUMBER)
 		val, err := strconv.ParseFloat(currTok.Value, 64)
 		if err != nil {
-			panic(err)
+			p.errorAt(currTok.Span, fmt.Sprintf("invalid number: %s", currTok.Value), "")
+		}
+		number := &ast.NumberNode{Value: val, Literal: currTok.Value, Loc: currTok.Span}
+		if p.currentToken.Type == token.IDENT && !p.units {
+			p.syntaxError(fmt.Sprintf("missing operator between %s and %s", currTok.Value, p.currentToken.Value), "expected an operator")
+			return number
+		}
+		// a name straight after a number is its unit, unless it is called
+		if p.currentToken.Type == token.IDENT && p.peekToken.Type != token.LPAREN {
+			unit := p.unit()
+			return &ast.QuantityNode{
+				Value: number,
+				Unit:  unit,
+				Loc:   token.Span{Start: currTok.Span.Start, End: unit.Loc.End},
+			}
+		}
+		return number
+	case token.IMAGINARY:
+		p.eat(token.IMAGINARY)
+		// drop the i or j suffix; the node records it
+		literal := currTok.Value[:len(currTok.Value)-1]
+		val, err := strconv.ParseFloat(literal, 64)
+		if err != nil {
+			p.errorAt(currTok.Span, fmt.Sprintf("invalid number: %s", currTok.Value), "")
+		}
+		return &ast.NumberNode{Value: val, Literal: literal, Imaginary: true, Loc: currTok.Span}
+	case token.DATETIME:
+		p.eat(token.DATETIME)
+		val, err := parseDateTime(currTok.Value)
+		if err != nil {
+			p.errorAt(currTok.Span, fmt.Sprintf("invalid date: %s", currTok.Value), "")
 		}
-		return &ast.NumberNode{Value: val}
+		return &ast.DateNode{Value: val, Literal: currTok.Value, Loc: currTok.Span}
+	case token.DURATION:
+		p.eat(token.DURATION)
+		val, err := parseDuration(currTok.Value)
+		if err != nil {
+			p.errorAt(currTok.Span, fmt.Sprintf("invalid duration: %s", currTok.Value), err.Error())
+		}
+		return &ast.DurationNode{Value: val, Literal: currTok.Value, Loc: currTok.Span}
+	case token.TRUE, token.FALSE:
+		p.eat(currTok.Type)
+		return &ast.BoolNode{Value: currTok.Type == token.TRUE, Loc: currTok.Span}
 	case token.LPAREN:
 		p.eat(token.LPAREN)
-		node := p.expr()
-		p.eat(token.RPAREN)
-		return node
-	case token.MINUS:
-		p.eat(token.MINUS)
-		return &ast.UnaryOpNode{
-			Op:   token.Token{Type: token.MINUS, Value: "-"},
-			Expr: p.factor(),
+		if p.currentToken.Type == token.RPAREN {
+			// () can only be the parameters of a lambda
+			p.eat(token.RPAREN)
+			return p.lambda(currTok.Span.Start, nil)
 		}
-	case token.PLUS:
-		p.eat(token.PLUS)
-		return &ast.UnaryOpNode{
-			Op:   token.Token{Type: token.PLUS, Value: "+"},
-			Expr: p.factor(),
+		node := p.conversion()
+		if p.currentToken.Type == token.COMMA {
+			// (a, b) can only be the parameters of a lambda
+			exprs := []ast.Node{node}
+			for p.currentToken.Type == token.COMMA {
+				p.eat(token.COMMA)
+				exprs = append(exprs, p.conversion())
+			}
+			p.eat(token.RPAREN)
+			return p.lambda(currTok.Span.Start, exprs)
 		}
+		if p.currentToken.Type == token.RPAREN && p.peekToken.Type == token.ARROW {
+			p.eat(token.RPAREN)
+			return p.lambda(currTok.Span.Start, []ast.Node{node})
+		}
+		if p.currentToken.Type == token.RPAREN {
+			// the parentheses are part of the sub-expression's span
+			setSpan(node, token.Span{Start: currTok.Span.Start, End: p.currentToken.Span.End})
+			p.eat(token.RPAREN)
+		} else {
+			p.eat(token.RPAREN)
+			// resynchronise after the junk inside the parentheses
+			p.skipToClosingParen()
+		}
+		return node
+	case token.LBRACKET:
+		return p.list()
+	case token.IF:
+		return p.ifExpr()
 	default:
-		// TODO as below, collect errors
-		panic(fmt.Sprintf("Syntax error: unexpected token %v", currTok.Type))
+		p.syntaxError(fmt.Sprintf("expected expression, found %v", currTok.Type), "expected expression")
+		node := &ast.ErrorNode{Loc: token.Span{Start: currTok.Span.Start, End: currTok.Span.Start}}
+		// leave operators and ')' for the enclosing rule to pick up, but
+		// skip anything else so parsing makes progress
+		if !p.isSyncToken() {
+			p.nextToken()
+			node.Loc = currTok.Span
+		}
+		return node
 	}
 }
 
-// Parse the input and return the AST
-func (p *Parser) Parse() ast.Node {
-	node := p.expr()
-	// Check for trailing tokens--after a valid expression, we should only have EOF
-	if p.currentToken.Type != token.EOF {
-		// for now, failures result in a panic
-		// TODO collect errors
-		panic(fmt.Sprintf("Syntax error: unexpected token %v", p.currentToken.Type))
+// ifExpr → IF conditional THEN conditional ELSE conditional
+//
+// This is another way of writing cond ? a : b. The else branch extends as
+// far right as it can, so if a then b else c + 1 is if a then b else (c + 1).
+func (p *Parser) ifExpr() ast.Node {
+	start := p.currentToken.Span.Start
+	p.eat(token.IF)
+	cond := p.conditional()
+	p.eat(token.THEN)
+	then := p.conditional()
+	p.eat(token.ELSE)
+	els := p.conditional()
+	return &ast.ConditionalNode{Cond: cond, Then: then, Else: els, Loc: spanFrom(start, els)}
+}
+
+// dateTimeLayouts are the forms a DATETIME token can take
+var dateTimeLayouts = []string{
+	"2006-01-02",
+	"2006-01-02T15:04",
+	"2006-01-02T15:04Z07:00",
+	"2006-01-02T15:04:05",
+	"2006-01-02T15:04:05Z07:00",
+}
+
+// parseDateTime reads a DATETIME token. Times without a UTC offset are
+// taken to be in UTC.
+func parseDateTime(literal string) (time.Time, error) {
+	var err error
+	for _, layout := range dateTimeLayouts {
+		var t time.Time
+		if t, err = time.Parse(layout, literal); err == nil {
+			return t, nil
+		}
 	}
+	return time.Time{}, err
+}
+
+// durationUnits are the lengths of the units a DURATION token is made of
+var durationUnits = map[byte]time.Duration{
+	'w': 7 * 24 * time.Hour,
+	'd': 24 * time.Hour,
+	'h': time.Hour,
+	'm': time.Minute,
+	's': time.Second,
+}
+
+var durationPart = regexp.MustCompile(`(\d+(?:\.\d+)?)([wdhms])`)
+
+// parseDuration reads a DURATION token such as 4h30m, adding up its parts
+func parseDuration(literal string) (time.Duration, error) {
+	total := 0.0
+	for _, part := range durationPart.FindAllStringSubmatch(literal, -1) {
+		amount, err := strconv.ParseFloat(part[1], 64)
+		if err != nil {
+			return 0, err
+		}
+		total += amount * float64(durationUnits[part[2][0]])
+	}
+	if total >= math.MaxInt64 {
+		return 0, fmt.Errorf("longer than %v", time.Duration(math.MaxInt64).Truncate(time.Hour))
+	}
+	return time.Duration(math.Round(total)), nil
+}
+
+// call → IDENT LPAREN args RPAREN
+func (p *Parser) call() ast.Node {
+	name := &ast.IdentNode{Name: p.currentToken.Value, Loc: p.currentToken.Span}
+	if !slices.Contains(p.locals, name.Name) {
+		p.calls = append(p.calls, name)
+	}
+	p.eat(token.IDENT)
+	args, end := p.args()
+	return &ast.CallNode{Name: name, Args: args, Loc: token.Span{Start: name.Loc.Start, End: end}}
+}
+
+// args → (conditional (COMMA conditional)*)?
+//
+// args parses the parenthesised arguments of a call, and returns them with
+// the position where the call ends
+func (p *Parser) args() ([]ast.Node, token.Position) {
+	end := p.currentToken.Span.End
+	p.eat(token.LPAREN)
+
+	var args []ast.Node
+	if p.currentToken.Type != token.RPAREN {
+		args = append(args, p.conditional())
+		for p.currentToken.Type == token.COMMA {
+			p.eat(token.COMMA)
+			args = append(args, p.conditional())
+		}
+		end = args[len(args)-1].Span().End
+	}
+
+	if p.currentToken.Type == token.RPAREN {
+		end = p.currentToken.Span.End
+		p.eat(token.RPAREN)
+	} else {
+		p.eat(token.RPAREN)
+		p.skipToClosingParen()
+	}
+	return args, end
+}
+
+// unit → unitFactor ((MUL | DIV) unitFactor)*
+//
+// A * or / only continues the unit when a name follows it, so 6 m / s is a
+// speed but 6 m / 2 is a length.
+func (p *Parser) unit() *ast.Unit {
+	unit := &ast.Unit{}
+	unit.Factors = append(unit.Factors, p.unitFactor(1))
+	for (p.currentToken.Type == token.MULTIPLY || p.currentToken.Type == token.DIVIDE) &&
+		p.peekToken.Type == token.IDENT {
+		sign := 1
+		if p.currentToken.Type == token.DIVIDE {
+			sign = -1
+		}
+		p.eat(p.currentToken.Type)
+		unit.Factors = append(unit.Factors, p.unitFactor(sign))
+	}
+	unit.Loc = token.Span{Start: unit.Factors[0].Loc.Start, End: unit.Factors[len(unit.Factors)-1].Loc.End}
+	return unit
+}
+
+// unitFactor → IDENT (POW MINUS? NUMBER)?
+//
+// sign is -1 for a factor after /, which divides by the unit
+func (p *Parser) unitFactor(sign int) ast.UnitFactor {
+	currTok := p.currentToken
+	factor := ast.UnitFactor{Name: currTok.Value, Power: sign, Loc: currTok.Span}
+	if currTok.Type != token.IDENT {
+		p.syntaxError(fmt.Sprintf("expected unit, found %v", currTok.Type), "expected a unit")
+		// like ast.ErrorNode, stand in for the missing unit
+		factor.Name = "<error>"
+		return factor
+	}
+	p.eat(token.IDENT)
+
+	if p.currentToken.Type == token.POWER {
+		p.eat(token.POWER)
+		if p.currentToken.Type == token.MINUS {
+			factor.Power = -factor.Power
+			p.eat(token.MINUS)
+		}
+		exponent := p.currentToken
+		p.eat(token.NUMBER)
+		if exponent.Type != token.NUMBER {
+			return factor
+		}
+		power, err := strconv.Atoi(exponent.Value)
+		if err != nil {
+			p.errorAt(exponent.Span, "a unit can only be raised to a whole number", "")
+			return factor
+		}
+		factor.Power *= power
+		factor.Loc.End = exponent.Span.End
+	}
+	return factor
+}
+
+// list → LBRACKET (conditional (COMMA conditional)*)? RBRACKET
+func (p *Parser) list() ast.Node {
+	start := p.currentToken.Span.Start
+	end := p.currentToken.Span.End
+	p.eat(token.LBRACKET)
+
+	node := &ast.ListNode{}
+	if p.currentToken.Type != token.RBRACKET {
+		node.Elems = append(node.Elems, p.conditional())
+		for p.currentToken.Type == token.COMMA {
+			p.eat(token.COMMA)
+			node.Elems = append(node.Elems, p.conditional())
+		}
+		end = node.Elems[len(node.Elems)-1].Span().End
+	}
+
+	if p.currentToken.Type == token.RBRACKET {
+		end = p.currentToken.Span.End
+	}
+	p.eat(token.RBRACKET)
+	node.Loc = token.Span{Start: start, End: end}
 	return node
 }
+
+// index → LBRACKET (conditional | conditional? COLON conditional?) RBRACKET
+//
+// node is the list being indexed or sliced
+func (p *Parser) index(node ast.Node) ast.Node {
+	p.eat(token.LBRACKET)
+
+	var lo ast.Node
+	if p.currentToken.Type != token.COLON {
+		lo = p.conditional()
+	}
+	var result ast.Node
+	if p.currentToken.Type == token.COLON {
+		p.eat(token.COLON)
+		var hi ast.Node
+		if p.currentToken.Type != token.RBRACKET {
+			hi = p.conditional()
+		}
+		result = &ast.SliceNode{Expr: node, Lo: lo, Hi: hi}
+	} else {
+		result = &ast.IndexNode{Expr: node, Index: lo}
+	}
+
+	end := p.currentToken.Span.Start
+	if p.currentToken.Type == token.RBRACKET {
+		end = p.currentToken.Span.End
+	}
+	p.eat(token.RBRACKET)
+	setSpan(result, token.Span{Start: node.Span().Start, End: end})
+	return result
+}
+
+// Parse the input and return the AST. If the input contains errors, the
+// returned error is a *ParseErrors holding all of them, and the AST is a
+// partial one with ast.ErrorNode in place of the parts that could not be
+// parsed.
+func (p *Parser) Parse() (ast.Node, error) {
+	node := p.statement()
+	// Check for trailing tokens--after a valid expression, we should only have EOF
+	for p.currentToken.Type != token.EOF {
+		if p.currentToken.Type == token.RPAREN {
+			p.syntaxError("unmatched ')'", "no matching '('")
+		} else if p.currentToken.Type == token.RBRACKET {
+			p.syntaxError("unmatched ']'", "no matching '['")
+		} else if p.currentToken.Type == token.ASSIGN {
+			p.syntaxError("cannot assign to an expression", "only a variable can be assigned to")
+		} else {
+			p.syntaxError(fmt.Sprintf("unexpected %v after expression", p.currentToken.Type), "expected an operator")
+		}
+		// skip the stray token and parse whatever follows, so that errors
+		// further along are reported too
+		p.nextToken()
+		if p.currentToken.Type != token.EOF {
+			p.conversion()
+		}
+	}
+
+	p.checkCalls(node)
+
+	var diagnostics []diagnostic.Diagnostic
+	diagnostics = append(diagnostics, p.lexer.Errors()...)
+	diagnostics = append(diagnostics, p.errors...)
+	// report in the order the problems appear in the input
+	sort.SliceStable(diagnostics, func(i, j int) bool {
+		return diagnostics[i].Span.Start.Offset < diagnostics[j].Span.Start.Offset
+	})
+	if len(diagnostics) > 0 {
+		return node, &ParseErrors{Diagnostics: diagnostics}
+	}
+	return node, nil
+}
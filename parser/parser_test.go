@@ -5,6 +5,7 @@ import (
 	"basic-arithmetic-parser/lexer"
 	"basic-arithmetic-parser/token"
 	"testing"
+	"time"
 )
 
 func checkNumberNode(t *testing.T, node ast.Node, expected float64) bool {
@@ -53,7 +54,10 @@ func TestSimpleAddition(t *testing.T) {
 	input := "3 + 5"
 	l := lexer.New(input)
 	p := New(l)
-	rootNode := p.Parse()
+	rootNode, err := p.Parse()
+	if err != nil {
+		t.Fatalf("Parse() returned an error: %v", err)
+	}
 
 	binOp, ok := checkBinaryOpNode(t, rootNode, token.PLUS)
 	if !ok {
@@ -73,7 +77,10 @@ func TestOperatorPrecedence(t *testing.T) {
 	input := "3 + 5 * 2"
 	l := lexer.New(input)
 	p := New(l)
-	rootNode := p.Parse()
+	rootNode, err := p.Parse()
+	if err != nil {
+		t.Fatalf("Parse() returned an error: %v", err)
+	}
 
 	// Root should be PLUS
 	rootBinOp, ok := checkBinaryOpNode(t, rootNode, token.PLUS)
@@ -107,7 +114,10 @@ func TestParentheses(t *testing.T) {
 	input := "(3 + 5) * 2"
 	l := lexer.New(input)
 	p := New(l)
-	rootNode := p.Parse()
+	rootNode, err := p.Parse()
+	if err != nil {
+		t.Fatalf("Parse() returned an error: %v", err)
+	}
 
 	// Root should be MULTIPLY
 	rootBinOp, ok := checkBinaryOpNode(t, rootNode, token.MULTIPLY)
@@ -140,7 +150,10 @@ func TestUnaryMinus(t *testing.T) {
 	input := "-5"
 	l := lexer.New(input)
 	p := New(l)
-	rootNode := p.Parse()
+	rootNode, err := p.Parse()
+	if err != nil {
+		t.Fatalf("Parse() returned an error: %v", err)
+	}
 
 	unOp, ok := checkUnaryOpNode(t, rootNode, token.MINUS)
 	if !ok {
@@ -156,7 +169,10 @@ func TestUnaryPlus(t *testing.T) {
 	input := "+5"
 	l := lexer.New(input)
 	p := New(l)
-	rootNode := p.Parse()
+	rootNode, err := p.Parse()
+	if err != nil {
+		t.Fatalf("Parse() returned an error: %v", err)
+	}
 
 	unOp, ok := checkUnaryOpNode(t, rootNode, token.PLUS)
 	if !ok {
@@ -167,3 +183,841 @@ func TestUnaryPlus(t *testing.T) {
 		t.Errorf("Unary operand check failed")
 	}
 }
+
+func TestPowerPrecedence(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"2 ^ 3 ^ 2", "(2 ^ (3 ^ 2))"},
+		{"-2 ^ 2", "-(2 ^ 2)"},
+		{"2 ^ -1", "(2 ^ -1)"},
+		{"2 * 3 ^ 2", "(2 * (3 ^ 2))"},
+		{"(2 ^ 3) ^ 2", "((2 ^ 3) ^ 2)"},
+		{"2 ** 3 ^ 2", "(2 ** (3 ^ 2))"},
+		{"-2 ^ -3 ^ 2", "-(2 ^ -(3 ^ 2))"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		rootNode, err := p.Parse()
+		if err != nil {
+			t.Fatalf("Parse(%q) returned an error: %v", tt.input, err)
+		}
+		if rootNode.String() != tt.expected {
+			t.Errorf("Parse(%q) wrong. expected=%q, got=%q", tt.input, tt.expected, rootNode.String())
+		}
+	}
+}
+
+func TestModuloPrecedence(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"1 + 7 % 3", "(1 + (7 % 3))"},
+		{"7 // 2 * 3", "((7 // 2) * 3)"},
+		{"8 rem 3 rem 2", "((8 rem 3) rem 2)"},
+		{"-7 % 3", "(-7 % 3)"},
+		{"2 ^ 3 % 5", "((2 ^ 3) % 5)"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		rootNode, err := p.Parse()
+		if err != nil {
+			t.Fatalf("Parse(%q) returned an error: %v", tt.input, err)
+		}
+		if rootNode.String() != tt.expected {
+			t.Errorf("Parse(%q) wrong. expected=%q, got=%q", tt.input, tt.expected, rootNode.String())
+		}
+	}
+}
+
+func TestPercent(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"10%", "10%"},
+		{"200 + 10%", "(200 + 10%)"},
+		{"50% * 80", "(50% * 80)"},
+		{"x% of y", "(x% of y)"},
+		{"15% of 80 + 1", "((15% of 80) + 1)"},
+		{"10% of -200", "(10% of -200)"},
+		{"-10%", "-10%"},
+		{"(1 + 2)%", "(1 + 2)%"},
+		{"max(5%, 1)", "max(5%, 1)"},
+		// followed by an operand, % is still modulo
+		{"7 % 3", "(7 % 3)"},
+		{"7 % -3", "(7 % -3)"},
+		{"10% + 1", "(10 % +1)"},
+		{"x % -2", "(x % -2)"},
+		{"(10%) - 5", "(10% - 5)"},
+		{"7 % (1 + 2)", "(7 % (1 + 2))"},
+	}
+
+	for _, tt := range tests {
+		rootNode, err := New(lexer.New(tt.input)).Parse()
+		if err != nil {
+			t.Fatalf("Parse(%q) returned an error: %v", tt.input, err)
+		}
+		if rootNode.String() != tt.expected {
+			t.Errorf("Parse(%q) wrong. expected=%q, got=%q", tt.input, tt.expected, rootNode.String())
+		}
+	}
+}
+
+func TestComparisonAndLogicalOperators(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"1 + 2 == 3", "((1 + 2) == 3)"},
+		{"x * 2 != y - 1", "((x * 2) != (y - 1))"},
+		{"a < b && b <= c", "((a < b) && (b <= c))"},
+		{"a > 1 || b >= 2 && c", "((a > 1) || ((b >= 2) && c))"},
+		{"a || b || c", "((a || b) || c)"},
+		{"!a && b", "(!a && b)"},
+		{"!(a && b)", "!(a && b)"},
+		{"!x == y", "(!x == y)"},
+		{"true == !false", "(true == !false)"},
+		{"-2^2 < 0", "(-(2 ^ 2) < 0)"},
+		{"x = 1 < 2", "x = (1 < 2)"},
+		{"max(1, 2) >= 2", "(max(1, 2) >= 2)"},
+		{"f(a < b, c)", "f((a < b), c)"},
+		{"10% == 0.1", "(10% == 0.1)"},
+	}
+
+	for _, tt := range tests {
+		rootNode, err := New(lexer.New(tt.input)).Parse()
+		if err != nil {
+			t.Fatalf("Parse(%q) returned an error: %v", tt.input, err)
+		}
+		if rootNode.String() != tt.expected {
+			t.Errorf("Parse(%q) wrong. expected=%q, got=%q", tt.input, tt.expected, rootNode.String())
+		}
+	}
+}
+
+func TestConditional(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"x != 0 ? 1 / x : 0", "((x != 0) ? (1 / x) : 0)"},
+		{"if x != 0 then 1 / x else 0", "((x != 0) ? (1 / x) : 0)"},
+		{"a ? b : c ? d : e", "(a ? b : (c ? d : e))"},
+		{"a ? b ? c : d : e", "(a ? (b ? c : d) : e)"},
+		{"if a then b else if c then d else e", "(a ? b : (c ? d : e))"},
+		{"if a then b else c + 1", "(a ? b : (c + 1))"},
+		{"(a ? b : c) + 1", "((a ? b : c) + 1)"},
+		{"1 + if a then 2 else 3 * 4", "(1 + (a ? 2 : (3 * 4)))"},
+		{"a || b ? 1 : 2", "((a || b) ? 1 : 2)"},
+		{"x = a ? 1 : 2", "x = (a ? 1 : 2)"},
+		{"max(a ? 1 : 2, 3)", "max((a ? 1 : 2), 3)"},
+		{"a ? x : y to cm", "((a ? x : y) to cm)"},
+	}
+
+	for _, tt := range tests {
+		rootNode, err := New(lexer.New(tt.input)).Parse()
+		if err != nil {
+			t.Fatalf("Parse(%q) returned an error: %v", tt.input, err)
+		}
+		if rootNode.String() != tt.expected {
+			t.Errorf("Parse(%q) wrong. expected=%q, got=%q", tt.input, tt.expected, rootNode.String())
+		}
+	}
+}
+
+func TestFunctionDefinition(t *testing.T) {
+	tests := []struct {
+		input     string
+		expected  string
+		signature string
+	}{
+		{"f(x, y) = x^2 + y", "f(x, y) = ((x ^ 2) + y)", "f(x, y)"},
+		{"answer() = 42", "answer() = 42", "answer()"},
+		{"fact(n) = n <= 1 ? 1 : n * fact(n - 1)", "fact(n) = ((n <= 1) ? 1 : (n * fact((n - 1))))", "fact(n)"},
+	}
+
+	for _, tt := range tests {
+		rootNode, err := New(lexer.New(tt.input)).Parse()
+		if err != nil {
+			t.Fatalf("Parse(%q) returned an error: %v", tt.input, err)
+		}
+		def, ok := rootNode.(*ast.FunctionDefNode)
+		if !ok {
+			t.Fatalf("Parse(%q) is not *ast.FunctionDefNode. got=%T", tt.input, rootNode)
+		}
+		if def.String() != tt.expected {
+			t.Errorf("Parse(%q) wrong. expected=%q, got=%q", tt.input, tt.expected, def.String())
+		}
+		if def.Signature() != tt.signature {
+			t.Errorf("Signature() wrong. expected=%q, got=%q", tt.signature, def.Signature())
+		}
+	}
+
+	// a call that is not followed by '=' is still a call
+	rootNode, err := New(lexer.New("f(x, y) + 1")).Parse()
+	if err != nil {
+		t.Fatalf("Parse() returned an error: %v", err)
+	}
+	if rootNode.String() != "(f(x, y) + 1)" {
+		t.Errorf("expected a call, got %q", rootNode.String())
+	}
+}
+
+func TestLambda(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"x -> x * 2", "(x -> (x * 2))"},
+		{"(x) -> x * 2", "(x -> (x * 2))"},
+		{"(a, b) -> a + b", "((a, b) -> (a + b))"},
+		{"() -> 42", "(() -> 42)"},
+		{"x -> y -> x + y", "(x -> (y -> (x + y)))"},
+		{"x -> x > 0 ? x : -x", "(x -> ((x > 0) ? x : -x))"},
+		{"double = x -> x * 2", "double = (x -> (x * 2))"},
+		{"map(x -> x * 2, 1, 2)", "map((x -> (x * 2)), 1, 2)"},
+		{"reduce((a, b) -> a * b, 1, 2)", "reduce(((a, b) -> (a * b)), 1, 2)"},
+		{"adder(n) = x -> x + n", "adder(n) = (x -> (x + n))"},
+		{"(x -> x) + 1", "((x -> x) + 1)"},
+		{"(x -> x)(5)", "(x -> x)(5)"},
+		{"((a, b) -> a * b)(2, 3) + 1", "(((a, b) -> (a * b))(2, 3) + 1)"},
+		// a parenthesised expression is not a lambda without an arrow
+		{"(x) * 2", "(x * 2)"},
+	}
+
+	for _, tt := range tests {
+		rootNode, err := New(lexer.New(tt.input)).Parse()
+		if err != nil {
+			t.Fatalf("Parse(%q) returned an error: %v", tt.input, err)
+		}
+		if rootNode.String() != tt.expected {
+			t.Errorf("Parse(%q) wrong. expected=%q, got=%q", tt.input, tt.expected, rootNode.String())
+		}
+	}
+}
+
+func TestListsAndIndexing(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"[1, 2, 3]", "[1, 2, 3]"},
+		{"[]", "[]"},
+		{"[1]", "[1]"},
+		{"[[1, 2], [3]]", "[[1, 2], [3]]"},
+		{"[x > 0, x -> x]", "[(x > 0), (x -> x)]"},
+		{"v[0]", "v[0]"},
+		{"v[i + 1]", "v[(i + 1)]"},
+		{"v[1:3]", "v[1:3]"},
+		{"v[:2]", "v[:2]"},
+		{"v[1:]", "v[1:]"},
+		{"v[:]", "v[:]"},
+		{"v[a ? 1 : 2]", "v[(a ? 1 : 2)]"},
+		{"m[0][1]", "m[0][1]"},
+		{"[1, 2][0]", "[1, 2][0]"},
+		{"f(x)[0]", "f(x)[0]"},
+		{"(v)[0]", "v[0]"},
+		// indexing binds tighter than any operator
+		{"-v[0]^2", "-(v[0] ^ 2)"},
+		{"2 * v[0]", "(2 * v[0])"},
+		{"v[0]%", "v[0]%"},
+		{"[1, 2] * 2", "([1, 2] * 2)"},
+		{"A @ x + b", "((A @ x) + b)"},
+		{"2 * A @ B[0]", "((2 * A) @ B[0])"},
+	}
+
+	for _, tt := range tests {
+		rootNode, err := New(lexer.New(tt.input)).Parse()
+		if err != nil {
+			t.Fatalf("Parse(%q) returned an error: %v", tt.input, err)
+		}
+		if rootNode.String() != tt.expected {
+			t.Errorf("Parse(%q) wrong. expected=%q, got=%q", tt.input, tt.expected, rootNode.String())
+		}
+	}
+
+	input := "1 + v[1:2]"
+	rootNode, err := New(lexer.New(input)).Parse()
+	if err != nil {
+		t.Fatalf("Parse(%q) returned an error: %v", input, err)
+	}
+	span := rootNode.(*ast.BinaryOpNode).Right.Span()
+	if got := input[span.Start.Offset:span.End.Offset]; got != "v[1:2]" {
+		t.Errorf("slice span wrong. expected=%q, got=%q", "v[1:2]", got)
+	}
+}
+
+func TestAssignment(t *testing.T) {
+	input := "rate = x * 4"
+	l := lexer.New(input)
+	p := New(l)
+	rootNode, err := p.Parse()
+	if err != nil {
+		t.Fatalf("Parse() returned an error: %v", err)
+	}
+
+	assign, ok := rootNode.(*ast.AssignNode)
+	if !ok {
+		t.Fatalf("Root node is not *ast.AssignNode. got=%T", rootNode)
+	}
+	if assign.Name.Name != "rate" {
+		t.Errorf("assignment name wrong. expected=%q, got=%q", "rate", assign.Name.Name)
+	}
+
+	binOp, ok := checkBinaryOpNode(t, assign.Value, token.MULTIPLY)
+	if !ok {
+		t.Fatalf("Assigned value is not a BinaryOpNode with MULTIPLY operator")
+	}
+	ident, ok := binOp.Left.(*ast.IdentNode)
+	if !ok || ident.Name != "x" {
+		t.Errorf("Left operand of MULTIPLY is not identifier x. got=%v", binOp.Left)
+	}
+	if !checkNumberNode(t, binOp.Right, 4) {
+		t.Errorf("Right operand of MULTIPLY check failed")
+	}
+}
+
+func TestCall(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+		args     int
+	}{
+		{"max()", "max()", 0},
+		{"sqrt(2)", "sqrt(2)", 1},
+		{"atan2(y, x + 1)", "atan2(y, (x + 1))", 2},
+		{"-min(1, 2, 3) ^ 2", "-(min(1, 2, 3) ^ 2)", 3},
+		{"f(g(1), (2))", "f(g(1), 2)", 2},
+		{"adder(3)(4)", "adder(3)(4)", 1},
+		{"f(1)(2, 3)(4)", "f(1)(2, 3)(4)", 1},
+		{"fs[0](1)", "fs[0](1)", 1},
+		{"-f(1)(2) ^ 2", "-(f(1)(2) ^ 2)", 1},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		rootNode, err := p.Parse()
+		if err != nil {
+			t.Fatalf("Parse(%q) returned an error: %v", tt.input, err)
+		}
+		if rootNode.String() != tt.expected {
+			t.Errorf("Parse(%q) wrong. expected=%q, got=%q", tt.input, tt.expected, rootNode.String())
+		}
+	}
+
+	input := "1 + hypot(3, 4)"
+	rootNode, err := New(lexer.New(input)).Parse()
+	if err != nil {
+		t.Fatalf("Parse(%q) returned an error: %v", input, err)
+	}
+	binOp, ok := checkBinaryOpNode(t, rootNode, token.PLUS)
+	if !ok {
+		t.Fatalf("Root node is not a BinaryOpNode with PLUS operator")
+	}
+	call, ok := binOp.Right.(*ast.CallNode)
+	if !ok {
+		t.Fatalf("Right operand of PLUS is not *ast.CallNode. got=%T", binOp.Right)
+	}
+	if call.Name.Name != "hypot" || len(call.Args) != 2 {
+		t.Fatalf("call wrong. expected hypot with 2 args, got %s", call)
+	}
+	span := call.Span()
+	if got := input[span.Start.Offset:span.End.Offset]; got != "hypot(3, 4)" {
+		t.Errorf("call span wrong. expected=%q, got=%q", "hypot(3, 4)", got)
+	}
+
+	input = "1 + adder(3)(4)"
+	rootNode, err = New(lexer.New(input)).Parse()
+	if err != nil {
+		t.Fatalf("Parse(%q) returned an error: %v", input, err)
+	}
+	binOp, ok = checkBinaryOpNode(t, rootNode, token.PLUS)
+	if !ok {
+		t.Fatalf("Root node is not a BinaryOpNode with PLUS operator")
+	}
+	apply, ok := binOp.Right.(*ast.ApplyNode)
+	if !ok {
+		t.Fatalf("Right operand of PLUS is not *ast.ApplyNode. got=%T", binOp.Right)
+	}
+	if _, ok := apply.Func.(*ast.CallNode); !ok || len(apply.Args) != 1 {
+		t.Fatalf("call wrong. expected a call of adder(3) with 1 arg, got %s", apply)
+	}
+	span = apply.Span()
+	if got := input[span.Start.Offset:span.End.Offset]; got != "adder(3)(4)" {
+		t.Errorf("call span wrong. expected=%q, got=%q", "adder(3)(4)", got)
+	}
+}
+
+func TestImaginaryLiteral(t *testing.T) {
+	input := "3 - 2.5j"
+	rootNode, err := New(lexer.New(input)).Parse()
+	if err != nil {
+		t.Fatalf("Parse(%q) returned an error: %v", input, err)
+	}
+	binOp, ok := checkBinaryOpNode(t, rootNode, token.MINUS)
+	if !ok {
+		t.Fatalf("Root node is not a BinaryOpNode with MINUS operator")
+	}
+	if !checkNumberNode(t, binOp.Right, 2.5) {
+		return
+	}
+	imag := binOp.Right.(*ast.NumberNode)
+	if !imag.Imaginary || imag.Literal != "2.5" {
+		t.Errorf("imaginary literal wrong. expected Imaginary with literal %q, got %+v", "2.5", imag)
+	}
+	if rootNode.String() != "(3 - 2.5i)" {
+		t.Errorf("Parse(%q) wrong. expected=%q, got=%q", input, "(3 - 2.5i)", rootNode.String())
+	}
+	span := imag.Span()
+	if got := input[span.Start.Offset:span.End.Offset]; got != "2.5j" {
+		t.Errorf("imaginary span wrong. expected=%q, got=%q", "2.5j", got)
+	}
+}
+
+func TestInterval(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"[1.9, 2.1]", "[1.9, 2.1]"},
+		{"[-1, 1 + x] * 2", "([-1, (1 + x)] * 2)"},
+		{"2±0.1", "(2 ± 0.1)"},
+		{"2±0.1 * 3", "((2 ± 0.1) * 3)"},
+		{"1 + 2±0.1", "(1 + (2 ± 0.1))"},
+		{"2^2±0.1", "((2 ^ 2) ± 0.1)"},
+		{"-2±0.1", "(-2 ± 0.1)"},
+	}
+
+	for _, tt := range tests {
+		rootNode, err := New(lexer.New(tt.input)).Parse()
+		if err != nil {
+			t.Fatalf("Parse(%q) returned an error: %v", tt.input, err)
+		}
+		if rootNode.String() != tt.expected {
+			t.Errorf("Parse(%q) wrong. expected=%q, got=%q", tt.input, tt.expected, rootNode.String())
+		}
+	}
+
+	input := "1 + [0, 2]"
+	rootNode, err := New(lexer.New(input)).Parse()
+	if err != nil {
+		t.Fatalf("Parse(%q) returned an error: %v", input, err)
+	}
+	span := rootNode.(*ast.BinaryOpNode).Right.Span()
+	if got := input[span.Start.Offset:span.End.Offset]; got != "[0, 2]" {
+		t.Errorf("interval span wrong. expected=%q, got=%q", "[0, 2]", got)
+	}
+}
+
+// parseUnits parses input with units enabled, as in units mode
+func parseUnits(input string) (ast.Node, error) {
+	p := New(lexer.New(input))
+	p.EnableUnits()
+	return p.Parse()
+}
+
+func TestQuantity(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"3 m", "3 m"},
+		{"2.5 km/h", "2.5 km/h"},
+		{"9.81 m/s^2", "9.81 m/s^2"},
+		{"1 m^-1", "1 1/m"},
+		{"3 N*m", "3 N*m"},
+		{"6 m / 2", "(6 m / 2)"},
+		{"6 m * 2 s", "(6 m * 2 s)"},
+		{"-3 m ^ 2", "-3 m^2"},
+		{"2 kg * 9.81 m/s^2", "(2 kg * 9.81 m/s^2)"},
+		{"60 mph to km/h", "(60 mph to km/h)"},
+		{"1 m + 2 ft in cm", "((1 m + 2 ft) to cm)"},
+		{"(1 h to min) * 2", "((1 h to min) * 2)"},
+		{"d = 5 km to m", "d = (5 km to m)"},
+	}
+
+	for _, tt := range tests {
+		rootNode, err := parseUnits(tt.input)
+		if err != nil {
+			t.Fatalf("Parse(%q) returned an error: %v", tt.input, err)
+		}
+		if rootNode.String() != tt.expected {
+			t.Errorf("Parse(%q) wrong. expected=%q, got=%q", tt.input, tt.expected, rootNode.String())
+		}
+	}
+
+	input := "1 + 9.81 m/s^2"
+	rootNode, err := parseUnits(input)
+	if err != nil {
+		t.Fatalf("Parse(%q) returned an error: %v", input, err)
+	}
+	span := rootNode.(*ast.BinaryOpNode).Right.Span()
+	if got := input[span.Start.Offset:span.End.Offset]; got != "9.81 m/s^2" {
+		t.Errorf("quantity span wrong. expected=%q, got=%q", "9.81 m/s^2", got)
+	}
+
+	// without units, a name cannot follow a number
+	if _, err := New(lexer.New("3 m")).Parse(); err == nil {
+		t.Errorf("expected an error for a unit outside units mode")
+	}
+}
+
+func TestUnitErrors(t *testing.T) {
+	tests := []struct {
+		name        string
+		input       string
+		expected    string
+		expectedAST string
+	}{
+		{"Missing Unit", "1 m to", "expected unit, found end of input", "(1 m to <error>)"},
+		{"Fractional Unit Power", "1 m^1.5", "a unit can only be raised to a whole number", "1 m"},
+		{"Chained Conversion", "1 m to cm to mm", "unexpected 'to' after expression", "(1 m to cm)"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			node, err := parseUnits(tt.input)
+			if node.String() != tt.expectedAST {
+				t.Errorf("partial AST wrong. expected=%q, got=%q", tt.expectedAST, node.String())
+			}
+			parseErrs, ok := err.(*ParseErrors)
+			if !ok || len(parseErrs.Diagnostics) != 1 {
+				t.Fatalf("expected one diagnostic, got %v", err)
+			}
+			if msg := parseErrs.Diagnostics[0].Message; msg != tt.expected {
+				t.Errorf("diagnostic wrong. expected=%q, got=%q", tt.expected, msg)
+			}
+		})
+	}
+}
+
+// parseDates parses input with dates and durations enabled, as in date mode
+func parseDates(input string) (ast.Node, error) {
+	l := lexer.New(input)
+	l.EnableDates()
+	return New(l).Parse()
+}
+
+func TestDateAndDuration(t *testing.T) {
+	input := "2026-10-17T09:00+02:00 + 4h30m - 1.5d"
+	rootNode, err := parseDates(input)
+	if err != nil {
+		t.Fatalf("Parse(%q) returned an error: %v", input, err)
+	}
+	if expected := "((2026-10-17T09:00+02:00 + 4h30m) - 1.5d)"; rootNode.String() != expected {
+		t.Errorf("Parse(%q) wrong. expected=%q, got=%q", input, expected, rootNode.String())
+	}
+
+	outer := rootNode.(*ast.BinaryOpNode)
+	inner := outer.Left.(*ast.BinaryOpNode)
+	date, ok := inner.Left.(*ast.DateNode)
+	if !ok {
+		t.Fatalf("expected a DateNode, got %T", inner.Left)
+	}
+	expectedDate := time.Date(2026, 10, 17, 7, 0, 0, 0, time.UTC)
+	if !date.Value.Equal(expectedDate) {
+		t.Errorf("date wrong. expected=%v, got=%v", expectedDate, date.Value)
+	}
+
+	durations := []struct {
+		node     ast.Node
+		expected time.Duration
+	}{
+		{inner.Right, 4*time.Hour + 30*time.Minute},
+		{outer.Right, 36 * time.Hour},
+	}
+	for _, tt := range durations {
+		d, ok := tt.node.(*ast.DurationNode)
+		if !ok {
+			t.Fatalf("expected a DurationNode, got %T", tt.node)
+		}
+		if d.Value != tt.expected {
+			t.Errorf("duration %s wrong. expected=%v, got=%v", d, tt.expected, d.Value)
+		}
+	}
+
+	// a date without an offset is in UTC
+	rootNode, err = parseDates("2026-10-17")
+	if err != nil {
+		t.Fatalf("Parse returned an error: %v", err)
+	}
+	if got := rootNode.(*ast.DateNode).Value; !got.Equal(time.Date(2026, 10, 17, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("date wrong. got=%v", got)
+	}
+
+	// without dates enabled, the same input is arithmetic
+	rootNode, err = New(lexer.New("2026-10-17")).Parse()
+	if err != nil {
+		t.Fatalf("Parse returned an error: %v", err)
+	}
+	if expected := "((2026 - 10) - 17)"; rootNode.String() != expected {
+		t.Errorf("Parse wrong. expected=%q, got=%q", expected, rootNode.String())
+	}
+}
+
+func TestDateErrors(t *testing.T) {
+	tests := []struct {
+		name        string
+		input       string
+		expected    string
+		expectedAST string
+	}{
+		{"Invalid Date", "2026-02-30 + 1d", "invalid date: 2026-02-30", "(2026-02-30 + 1d)"},
+		{"Duration Too Long", "999999w", "invalid duration: 999999w", "999999w"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			node, err := parseDates(tt.input)
+			if node.String() != tt.expectedAST {
+				t.Errorf("partial AST wrong. expected=%q, got=%q", tt.expectedAST, node.String())
+			}
+			parseErrs, ok := err.(*ParseErrors)
+			if !ok || len(parseErrs.Diagnostics) != 1 {
+				t.Fatalf("expected one diagnostic, got %v", err)
+			}
+			if msg := parseErrs.Diagnostics[0].Message; msg != tt.expected {
+				t.Errorf("diagnostic wrong. expected=%q, got=%q", tt.expected, msg)
+			}
+		})
+	}
+}
+
+// knownFunctions is a Resolver that knows a fixed set of names
+type knownFunctions map[string]bool
+
+func (k knownFunctions) IsFunction(name string) bool {
+	return k[name]
+}
+
+func TestResolver(t *testing.T) {
+	input := "sqrt(2) + nope(1, 2) * sqrt(undefined(3))"
+	l := lexer.New(input)
+	p := New(l)
+	p.SetResolver(knownFunctions{"sqrt": true})
+	_, err := p.Parse()
+
+	parseErrs, ok := err.(*ParseErrors)
+	if !ok {
+		t.Fatalf("error is not *ParseErrors. got=%T (%v)", err, err)
+	}
+	expected := []string{"nope", "undefined"}
+	if len(parseErrs.Diagnostics) != len(expected) {
+		t.Fatalf("wrong number of diagnostics. expected=%d, got=%d (%v)", len(expected), len(parseErrs.Diagnostics), parseErrs)
+	}
+	for i, name := range expected {
+		d := parseErrs.Diagnostics[i]
+		if d.Message != "unknown function: "+name {
+			t.Errorf("diagnostics[%d] wrong. expected=%q, got=%q", i, "unknown function: "+name, d.Message)
+		}
+		if got := input[d.Span.Start.Offset:d.Span.End.Offset]; got != name {
+			t.Errorf("diagnostics[%d] span wrong. expected=%q, got=%q", i, name, got)
+		}
+	}
+}
+
+func TestResolverInDefinition(t *testing.T) {
+	// the function being defined can call itself, and sqrt is known
+	input := "fact(n) = n <= 1 ? 1 : n * fact(n - 1) + sqrt(nope(n))"
+	p := New(lexer.New(input))
+	p.SetResolver(knownFunctions{"sqrt": true})
+	_, err := p.Parse()
+
+	parseErrs, ok := err.(*ParseErrors)
+	if !ok {
+		t.Fatalf("error is not *ParseErrors. got=%T (%v)", err, err)
+	}
+	if len(parseErrs.Diagnostics) != 1 || parseErrs.Diagnostics[0].Message != "unknown function: nope" {
+		t.Errorf("expected only %q, got %v", "unknown function: nope", parseErrs)
+	}
+
+	// the parameters of a lambda can be called in its body, but not outside
+	p = New(lexer.New("(f, x) -> f(x) + g(x)"))
+	p.SetResolver(knownFunctions{})
+	_, err = p.Parse()
+	parseErrs, ok = err.(*ParseErrors)
+	if !ok || len(parseErrs.Diagnostics) != 1 || parseErrs.Diagnostics[0].Message != "unknown function: g" {
+		t.Errorf("expected only %q, got %v", "unknown function: g", err)
+	}
+
+	// a lambda being assigned to a name can call itself through it
+	p = New(lexer.New("f = n -> n <= 1 ? 1 : n * f(n - 1) + g(n)"))
+	p.SetResolver(knownFunctions{})
+	_, err = p.Parse()
+	parseErrs, ok = err.(*ParseErrors)
+	if !ok || len(parseErrs.Diagnostics) != 1 || parseErrs.Diagnostics[0].Message != "unknown function: g" {
+		t.Errorf("expected only %q, got %v", "unknown function: g", err)
+	}
+
+	// but assigning anything else does not make the name callable
+	p = New(lexer.New("f = f(1)"))
+	p.SetResolver(knownFunctions{})
+	if _, err := p.Parse(); err == nil {
+		t.Errorf("expected an error for calling f in a value that is not a lambda")
+	}
+
+	// outside a definition, calling an unknown function is still an error
+	p = New(lexer.New("fact(3)"))
+	p.SetResolver(knownFunctions{"sqrt": true})
+	if _, err := p.Parse(); err == nil {
+		t.Errorf("expected an error for calling fact outside its definition")
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name        string
+		input       string
+		expected    []string
+		expectedAST string
+	}{
+		{"Missing Operand", "2 + * 3", []string{"expected expression, found '*'"}, "(2 + (<error> * 3))"},
+		{"Unmatched Parenthesis", "1 + 2 )", []string{"unmatched ')'"}, "(1 + 2)"},
+		{"Missing Closing Parenthesis", "( 1 + 2", []string{"expected ')', found end of input"}, "(1 + 2)"},
+		{"Empty Input", "", []string{"expected expression, found end of input"}, "<error>"},
+		{"Invalid Character", "1 $ 2", []string{"invalid character '$'"}, "1"},
+		{"Invalid Number", "1.2.3 + 4", []string{"invalid number format: 1.2.3"}, "(<error> + 4)"},
+		{"Trailing Number", "1 2", []string{"unexpected number after expression"}, "1"},
+		{"Lexical Errors After Syntax Error", "1 + * 2 $ 3 #", []string{
+			"expected expression, found '*'",
+			"invalid character '$'",
+			"invalid character '#'",
+		}, "(1 + (<error> * 2))"},
+		{"Errors In Both Operands", "(1 + ) * (2 / )", []string{
+			"expected expression, found ')'",
+			"expected expression, found ')'",
+		}, "((1 + <error>) * (2 / <error>))"},
+		{"Junk Inside Parentheses", "(1 2 (3)) * 4 +", []string{
+			"expected ')', found number",
+			"expected expression, found end of input",
+		}, "((1 * 4) + <error>)"},
+		{"Assign To Expression", "x + 1 = 2", []string{"cannot assign to an expression"}, "(x + 1)"},
+		{"Chained Assignment", "x = y = 2", []string{"cannot assign to an expression"}, "x = y"},
+		{"Missing Assigned Value", "x =", []string{"expected expression, found end of input"}, "x = <error>"},
+		{"Missing Argument", "max(1, , 2)", []string{"expected expression, found ','"}, "max(1, <error>, 2)"},
+		{"Unclosed Call", "sqrt(4 + 1", []string{"expected ')', found end of input"}, "sqrt((4 + 1))"},
+		{"Junk In Call", "sqrt(4 5) + 1", []string{"expected ')', found number"}, "(sqrt(4) + 1)"},
+		{"Errors After Stray Token", "1 ) + 2 * / 3", []string{
+			"unmatched ')'",
+			"expected expression, found '/'",
+		}, "1"},
+		{"Unmatched Bracket", "[1, 2]]", []string{"unmatched ']'"}, "[1, 2]"},
+		{"Missing List Element", "[1, ] + 2", []string{"expected expression, found ']'"}, "([1, <error>] + 2)"},
+		{"Unclosed List", "[1, 2", []string{"expected ']', found end of input"}, "[1, 2]"},
+		{"Missing Index", "v[] + 1", []string{"expected expression, found ']'"}, "(v[<error>] + 1)"},
+		{"Unclosed Index", "v[0", []string{"expected ']', found end of input"}, "v[0]"},
+		{"Slice Step", "v[1:2:3]", []string{"expected ']', found ':'", "unmatched ']'"}, "v[1:2]"},
+		{"Of Without Percentage", "10 of 200", []string{"unexpected 'of' after expression"}, "10"},
+		{"Name After Number", "2 pi", []string{"missing operator between 2 and pi"}, "2"},
+		{"Exponent Notation", "1e9", []string{"missing operator between 1 and e9"}, "1"},
+		{"Name After Number In Call", "sqrt(1e3) + 1", []string{"missing operator between 1 and e3"}, "(sqrt(1) + 1)"},
+		{"Call After Number", "2 sqrt(4)", []string{"missing operator between 2 and sqrt"}, "2"},
+		{"Chained Comparison", "1 < x < 3", []string{"comparisons cannot be chained"}, "(1 < x)"},
+		{"Missing Logical Operand", "true && ", []string{"expected expression, found end of input"}, "(true && <error>)"},
+		{"Single Ampersand", "a & b", []string{"invalid character '&'"}, "a"},
+		{"Parameter Not A Name", "f(x, 2) = x", []string{"function parameters must be names"}, "f(x) = x"},
+		{"Duplicate Parameter", "f(x, x) = x", []string{"duplicate parameter x"}, "f(x, x) = x"},
+		{"Missing Function Body", "f(x) =", []string{"expected expression, found end of input"}, "f(x) = <error>"},
+		{"Define Expression", "f(x) + 1 = 2", []string{"cannot assign to an expression"}, "(f(x) + 1)"},
+		{"Lambda Parameter Not A Name", "(x, 1) -> x", []string{"function parameters must be names"}, "(x -> x)"},
+		{"Duplicate Lambda Parameter", "(x, x) -> x", []string{"duplicate parameter x"}, "((x, x) -> x)"},
+		{"Tuple Without Arrow", "(a, b)", []string{"expected '->', found end of input"}, "((a, b) -> <error>)"},
+		{"Missing Lambda Body", "x ->", []string{"expected expression, found end of input"}, "(x -> <error>)"},
+		{"Missing Colon", "a ? 1", []string{"expected ':', found end of input"}, "(a ? 1 : <error>)"},
+		{"Missing Else", "if a then 1", []string{"expected 'else', found end of input"}, "(a ? 1 : <error>)"},
+		{"Missing Then", "if a 1 else 2", []string{"expected 'then', found number"}, "(a ? 1 : 2)"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := lexer.New(tt.input)
+			p := New(l)
+			node, err := p.Parse()
+			if node == nil {
+				t.Fatalf("expected a partial AST on error, got nil")
+			}
+			if node.String() != tt.expectedAST {
+				t.Errorf("partial AST wrong. expected=%q, got=%q", tt.expectedAST, node.String())
+			}
+
+			parseErrs, ok := err.(*ParseErrors)
+			if !ok {
+				t.Fatalf("error is not *ParseErrors. got=%T (%v)", err, err)
+			}
+			if len(parseErrs.Diagnostics) != len(tt.expected) {
+				t.Fatalf("wrong number of diagnostics. expected=%d, got=%d (%v)",
+					len(tt.expected), len(parseErrs.Diagnostics), parseErrs)
+			}
+			for i, msg := range tt.expected {
+				if parseErrs.Diagnostics[i].Message != msg {
+					t.Errorf("diagnostics[%d] wrong. expected=%q, got=%q", i, msg, parseErrs.Diagnostics[i].Message)
+				}
+			}
+		})
+	}
+}
+
+func TestNodeSpans(t *testing.T) {
+	input := "1 + -(2 * 30)"
+	l := lexer.New(input)
+	p := New(l)
+	rootNode, err := p.Parse()
+	if err != nil {
+		t.Fatalf("Parse() returned an error: %v", err)
+	}
+
+	spanText := func(node ast.Node) string {
+		span := node.Span()
+		return input[span.Start.Offset:span.End.Offset]
+	}
+
+	rootBinOp, ok := checkBinaryOpNode(t, rootNode, token.PLUS)
+	if !ok {
+		t.Fatalf("Root node is not a BinaryOpNode with PLUS operator")
+	}
+	if got := spanText(rootBinOp); got != input {
+		t.Errorf("root span wrong. expected=%q, got=%q", input, got)
+	}
+	if got := spanText(rootBinOp.Left); got != "1" {
+		t.Errorf("left span wrong. expected=%q, got=%q", "1", got)
+	}
+
+	unOp, ok := checkUnaryOpNode(t, rootBinOp.Right, token.MINUS)
+	if !ok {
+		t.Fatalf("Right operand of PLUS is not a UnaryOpNode with MINUS operator")
+	}
+	if got := spanText(unOp); got != "-(2 * 30)" {
+		t.Errorf("unary span wrong. expected=%q, got=%q", "-(2 * 30)", got)
+	}
+	// parentheses are included in the span of the grouped expression
+	if got := spanText(unOp.Expr); got != "(2 * 30)" {
+		t.Errorf("inner span wrong. expected=%q, got=%q", "(2 * 30)", got)
+	}
+}
+
+func TestErrorSpans(t *testing.T) {
+	input := "1 +\n  * 2"
+	l := lexer.New(input)
+	p := New(l)
+	_, err := p.Parse()
+
+	parseErrs, ok := err.(*ParseErrors)
+	if !ok || len(parseErrs.Diagnostics) != 1 {
+		t.Fatalf("expected a single diagnostic. got=%v", err)
+	}
+	start := parseErrs.Diagnostics[0].Span.Start
+	if start.Line != 2 || start.Column != 3 {
+		t.Errorf("diagnostic position wrong. expected=2:3, got=%d:%d", start.Line, start.Column)
+	}
+	if err.Error() != "2:3: expected expression, found '*'" {
+		t.Errorf("Error() wrong. got=%q", err.Error())
+	}
+}
@@ -1,19 +1,143 @@
 package token
 
+import "fmt"
+
 type TokenType int
 
 const (
-	NUMBER TokenType = iota
+	NUMBER    TokenType = iota
+	IMAGINARY           // a number with an i or j suffix, e.g. 2.5i
+	DATETIME            // an ISO 8601 date or time, e.g. 2026-10-17T09:00Z
+	DURATION            // e.g. 3d or 4h30m
+	IDENT
+	ASSIGN
 	PLUS
 	MINUS
 	MULTIPLY
 	DIVIDE
+	MODULO       // % (floored), or a percent sign when no operand follows
+	FLOOR_DIVIDE // //
+	REM          // rem (truncated remainder)
+	MATMUL       // @, matrix multiplication
+	POWER        // ^ or **
+	PLUSMINUS    // ±
+	TO           // to or in, for unit conversion
+	OF           // of, as in 10% of 200
+	EQ           // ==
+	NOT_EQ       // !=
+	LT           // <
+	LT_EQ        // <=
+	GT           // >
+	GT_EQ        // >=
+	AND          // &&
+	OR           // ||
+	NOT          // !
+	TRUE
+	FALSE
+	QUESTION // ?, as in c ? a : b
+	COLON
+	IF
+	THEN
+	ELSE
+	ARROW // ->, as in x -> x * 2
 	LPAREN
 	RPAREN
+	LBRACKET
+	RBRACKET
+	COMMA
+	ILLEGAL // invalid input; the lexer records a diagnostic for it
 	EOF
 )
 
+var names = map[TokenType]string{
+	NUMBER:       "number",
+	IMAGINARY:    "imaginary number",
+	DATETIME:     "date",
+	DURATION:     "duration",
+	IDENT:        "identifier",
+	ASSIGN:       "'='",
+	PLUS:         "'+'",
+	MINUS:        "'-'",
+	MULTIPLY:     "'*'",
+	DIVIDE:       "'/'",
+	MODULO:       "'%'",
+	FLOOR_DIVIDE: "'//'",
+	REM:          "'rem'",
+	MATMUL:       "'@'",
+	POWER:        "'^'",
+	PLUSMINUS:    "'±'",
+	TO:           "'to'",
+	OF:           "'of'",
+	EQ:           "'=='",
+	NOT_EQ:       "'!='",
+	LT:           "'<'",
+	LT_EQ:        "'<='",
+	GT:           "'>'",
+	GT_EQ:        "'>='",
+	AND:          "'&&'",
+	OR:           "'||'",
+	NOT:          "'!'",
+	TRUE:         "'true'",
+	FALSE:        "'false'",
+	QUESTION:     "'?'",
+	COLON:        "':'",
+	IF:           "'if'",
+	THEN:         "'then'",
+	ELSE:         "'else'",
+	ARROW:        "'->'",
+	LPAREN:       "'('",
+	RPAREN:       "')'",
+	LBRACKET:     "'['",
+	RBRACKET:     "']'",
+	COMMA:        "','",
+	ILLEGAL:      "invalid token",
+	EOF:          "end of input",
+}
+
+// keywords are words that lex as operators rather than identifiers
+var keywords = map[string]TokenType{
+	"rem":   REM,
+	"to":    TO,
+	"in":    TO,
+	"of":    OF,
+	"true":  TRUE,
+	"false": FALSE,
+	"if":    IF,
+	"then":  THEN,
+	"else":  ELSE,
+}
+
+// LookupKeyword returns the token type for a keyword, and whether word is one
+func LookupKeyword(word string) (TokenType, bool) {
+	tokenType, ok := keywords[word]
+	return tokenType, ok
+}
+
+// String returns a human-readable name for the token type, as used in error
+// messages (e.g. "expected ')', found end of input")
+func (t TokenType) String() string {
+	if name, ok := names[t]; ok {
+		return name
+	}
+	return fmt.Sprintf("TokenType(%d)", int(t))
+}
+
+// Position is a location in the input. Offset is a 0-based byte offset;
+// Line and Column are 1-based, with Column counted in bytes.
+type Position struct {
+	Offset int
+	Line   int
+	Column int
+}
+
+// Span is the region of the input from Start up to, but not including, End.
+type Span struct {
+	Start Position
+	End   Position
+}
+
 type Token struct {
 	Type  TokenType
 	Value string
+	Span  Span
 }